@@ -5,6 +5,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/dadav/helm-schema/pkg/report"
+	"github.com/dadav/helm-schema/pkg/schema"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -40,6 +42,10 @@ func newCommand(run func(cmd *cobra.Command, args []string) error) (*cobra.Comma
 		RunE:          run,
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		// The config file is located in the chart search root, which is a flag
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			return loadConfigFile(cmd)
+		},
 	}
 
 	logLevelUsage := fmt.Sprintf(
@@ -81,15 +87,54 @@ func newCommand(run func(cmd *cobra.Command, args []string) error) (*cobra.Comma
 		BoolP("allow-circular-dependencies", "w", false, "allow circular dependencies between charts (will log a warning instead of failing)")
 	cmd.PersistentFlags().
 		BoolP("annotate", "A", false, "write inferred @schema annotations into values.yaml files for unannotated keys")
+	cmd.PersistentFlags().
+		StringSlice("annotate-keywords", []string{schema.AnnotatePresetMinimal}, "keywords --annotate writes, a preset (minimal, rich) or a list of (type, format, description, additionalProperties, required, items)")
+	cmd.PersistentFlags().
+		Bool("migrate-helm-docs", false, "rewrite helm-docs comments in values.yaml files into @schema annotations")
 	cmd.PersistentFlags().
 		BoolP("keep-existing-dep-schemas", "K", false, "use dependency charts' pre-existing values.schema.json instead of regenerating from values.yaml")
 	cmd.PersistentFlags().
 		BoolP("check", "C", false, "check that existing schema files are up-to-date; exit nonzero if any are missing or stale, without writing files")
+	cmd.PersistentFlags().
+		BoolP("show-diff", "D", false, "with --check, print a structural diff for every stale schema")
+	cmd.PersistentFlags().
+		String("draft", schema.Draft7, fmt.Sprintf("JSON Schema draft of the generated schemas, one of (%s)", strings.Join(schema.Drafts, ", ")))
+	cmd.PersistentFlags().
+		String("k8s-catalog", "", "directory of kubernetes OpenAPI definitions per version (e.g. v1.29.0/swagger.json) to resolve k8s:// refs")
+	cmd.PersistentFlags().
+		String("k8s-version", "", "kubernetes version of the catalog used for k8s:// refs (default: latest version satisfying the kubeVersion of the chart)")
+	cmd.PersistentFlags().
+		Bool("remote-refs", false, "resolve http(s) refs, so they are checked and used to compile the schemas")
+	cmd.PersistentFlags().
+		Bool("offline", false, "resolve http(s) refs only from the cache (implies --remote-refs)")
+	cmd.PersistentFlags().
+		Bool("bundle", false, "inline http(s) refs into the definitions, so the schemas are self-contained (implies --remote-refs)")
+	cmd.PersistentFlags().
+		String("ref-cache-dir", "", "directory the documents of http(s) refs are cached in (default: <user cache dir>/helm-schema/refs)")
+	cmd.PersistentFlags().
+		Bool("watch", false, "keep running and regenerate the schemas of charts whose Chart.yaml, values or referenced files change")
+
+	cmd.PersistentFlags().
+		String("report-format", "", fmt.Sprintf("write a machine-readable report of the run, one of (%s)", strings.Join(report.Formats, ", ")))
+	cmd.PersistentFlags().
+		String("report-file", "", "file the report is written to (default: stdout)")
 
 	viper.AutomaticEnv()
 	viper.SetEnvPrefix("HELM_SCHEMA")
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	err := viper.BindPFlags(cmd.PersistentFlags())
 
+	cmd.AddCommand(newValidateCommand())
+	cmd.AddCommand(newDiffCommand())
+	cmd.AddCommand(newDocsCommand())
+	cmd.AddCommand(newLSPCommand())
+	cmd.AddCommand(newImportCommand())
+	cmd.AddCommand(newExplainCommand())
+	cmd.AddCommand(newLintCommand())
+	cmd.AddCommand(newCRDCommand())
+	cmd.AddCommand(newCodegenCommand())
+	cmd.AddCommand(newExampleCommand())
+	cmd.AddCommand(newFuzzCommand())
+
 	return cmd, err
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/codegen"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newCodegenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "codegen <chart>",
+		Short: "generate types for the values of a chart",
+		Long: `Generate types for the values of a chart, so programs which render or
+validate the values of the chart can work with typed values.
+
+The types are generated from the final schema of the chart (including merged
+dependencies). The values become a type named by --type-name, objects become
+nested types named after their keys and definitions become named types which
+are used for their references.
+
+Go: keys which are not required become pointers with omitempty, enums become
+types with constants and descriptions become doc comments.
+
+TypeScript: declarations (.d.ts) with interfaces, keys which are not required
+are optional, enums become union types and additionalProperties and
+patternProperties become index signatures. Descriptions, defaults, examples
+and deprecations become JSDoc comments.
+
+CUE: the values and definitions become CUE definitions (#Values), defaults
+become default disjunctions (*value | type), validations become constraints
+and objects are closed unless they allow additional properties.
+
+The chart is given by its name or its directory. The code is printed to
+stdout unless --file is set.`,
+		Args: cobra.ExactArgs(1),
+		RunE: generateCode,
+	}
+
+	cmd.Flags().
+		String("language", codegen.LanguageGo, fmt.Sprintf("language of the generated code, one of (%s)", strings.Join(codegen.Languages, ", ")))
+	cmd.Flags().
+		String("package", "values", "package of the generated code (go, cue)")
+	cmd.Flags().
+		String("type-name", "Values", "name of the type of the values")
+	cmd.Flags().
+		String("file", "", "file the code is written to")
+
+	return cmd
+}
+
+func generateCode(cmd *cobra.Command, args []string) error {
+	configureLogging()
+
+	var language, file string
+	var options codegen.Options
+	for flag, value := range map[string]*string{
+		"language":  &language,
+		"package":   &options.Package,
+		"type-name": &options.TypeName,
+		"file":      &file,
+	} {
+		var err error
+		if *value, err = cmd.Flags().GetString(flag); err != nil {
+			return err
+		}
+	}
+	if err := codegen.ValidateLanguage(language); err != nil {
+		return err
+	}
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+	dryRun := opts.dryRun
+	opts.disableWrites()
+
+	result, err := findChartResult(opts, args[0])
+	if err != nil {
+		return err
+	}
+
+	code, err := codegen.Generate(language, &result.Schema, options)
+	if err != nil {
+		return err
+	}
+
+	if file == "" || dryRun {
+		log.Infof("Printing %s types of chart %s (%s)", language, result.Chart.Name, result.ChartPath)
+		_, err = cmd.OutOrStdout().Write(code)
+		return err
+	}
+	if err := os.WriteFile(file, code, 0o644); err != nil {
+		return err
+	}
+	log.Infof("Wrote %s types of chart %s to %s", language, result.Chart.Name, file)
+	return nil
+}
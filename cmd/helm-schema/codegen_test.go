@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodegen(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("my-app/Chart.yaml", `
+apiVersion: v2
+name: my-app
+version: 1.0.0
+`)
+	writeFile("my-app/values.yaml", `
+# @schema
+# enum: [Always, IfNotPresent]
+# @schema
+pullPolicy: Always
+image:
+  # -- The image repository
+  repository: nginx
+`)
+
+	codegen := func(args ...string) (string, error) {
+		setStandardViper(tmpDir)
+		var out bytes.Buffer
+		cmd := newCodegenCommand()
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := codegen("my-app")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "package values\n")
+	assert.Contains(t, out, "\ntype Values struct {\n")
+	assert.Contains(t, out, "\n\t// The image repository\n")
+	assert.Contains(t, out, "\tPullPolicyIfNotPresent PullPolicy = \"IfNotPresent\"\n")
+
+	// The code is written to the file
+	file := filepath.Join(tmpDir, "values.go")
+	out, err = codegen(filepath.Join(tmpDir, "my-app"), "--package", "myapp", "--type-name", "MyAppValues", "--file", file)
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+	written, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(written), "package myapp\n")
+	assert.Contains(t, string(written), "\ntype MyAppValues struct {\n")
+
+	out, err = codegen("my-app", "--language", "typescript")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "\nexport interface Values {\n")
+	assert.Contains(t, out, "  pullPolicy?: \"Always\" | \"IfNotPresent\";\n")
+	assert.Contains(t, out, "  /**\n   * The image repository\n")
+
+	out, err = codegen("my-app", "--language", "cue", "--package", "myapp")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "package myapp\n")
+	assert.Contains(t, out, "\n#Values: {\n")
+	assert.Contains(t, out, "\tpullPolicy?: *\"Always\" | \"IfNotPresent\"\n")
+
+	_, err = codegen("my-app", "--language", "rust")
+	assert.EqualError(t, err, "unsupported language rust, must be one of (go, typescript, cue)")
+
+	_, err = codegen("other")
+	assert.EqualError(t, err, "chart other not found below "+tmpDir)
+}
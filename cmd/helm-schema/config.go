@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configFileName is the name of the config file in the chart search root and
+// the chart directories
+const configFileName = ".helm-schema.yaml"
+
+// chartConfigKeys are the options a config file in a chart directory can
+// override
+var chartConfigKeys = []string{
+	"value-files",
+	"output-file",
+	"skip-auto-generation",
+	"dont-add-global",
+	"keep-full-comment",
+	"helm-docs-compatibility-mode",
+	"dont-strip-helm-docs-prefix",
+	"uncomment",
+}
+
+// loadConfigFile reads the config file of the chart search root into viper.
+// Its keys are the names of the flags, flags and environment variables take
+// precedence. A missing file is ignored.
+func loadConfigFile(cmd *cobra.Command) error {
+	path := filepath.Join(viper.GetString("chart-search-root"), configFileName)
+	viper.SetConfigFile(path)
+	viper.SetConfigType("yaml")
+	if err := viper.ReadInConfig(); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	for _, key := range viper.AllKeys() {
+		if cmd.Root().PersistentFlags().Lookup(key) == nil && viper.InConfig(key) {
+			log.Warnf("Unknown option %s in %s", key, path)
+		}
+	}
+	log.Debugf("Using config file %s", path)
+	return nil
+}
+
+// forChart returns the options of a chart, the options of its config file
+// override the global ones. The chart search root uses the global options.
+func (opts *generatorOptions) forChart(chartPath string) (*generatorOptions, error) {
+	chartDir := filepath.Dir(chartPath)
+	path := filepath.Join(chartDir, configFileName)
+
+	rootPath, err := filepath.Abs(filepath.Join(opts.chartSearchRoot, configFileName))
+	if err != nil {
+		return nil, err
+	}
+	if absPath, err := filepath.Abs(path); err != nil || absPath == rootPath {
+		return opts, err
+	}
+
+	config := viper.New()
+	config.SetConfigFile(path)
+	config.SetConfigType("yaml")
+	if err := config.ReadInConfig(); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return opts, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	chartOpts := *opts
+	for _, key := range config.AllKeys() {
+		if !slices.Contains(chartConfigKeys, key) {
+			log.Warnf("Option %s in %s can't be set per chart, it is ignored", key, path)
+		}
+	}
+	if config.IsSet("value-files") {
+		chartOpts.valueFileNames = config.GetStringSlice("value-files")
+	}
+	if config.IsSet("output-file") {
+		chartOpts.outFile = config.GetString("output-file")
+	}
+	if config.IsSet("skip-auto-generation") {
+		skipConfig, err := schema.NewSkipAutoGenerationConfig(config.GetStringSlice("skip-auto-generation"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid option skip-auto-generation in %s: %w", path, err)
+		}
+		chartOpts.skipConfig = skipConfig
+	}
+	if config.IsSet("dont-add-global") {
+		chartOpts.dontAddGlobal = config.GetBool("dont-add-global")
+	}
+	if config.IsSet("keep-full-comment") {
+		chartOpts.keepFullComment = config.GetBool("keep-full-comment")
+	}
+	if config.IsSet("helm-docs-compatibility-mode") {
+		chartOpts.helmDocsCompatibilityMode = config.GetBool("helm-docs-compatibility-mode")
+	}
+	if config.IsSet("dont-strip-helm-docs-prefix") {
+		chartOpts.dontRemoveHelmDocsPrefix = config.GetBool("dont-strip-helm-docs-prefix")
+	}
+	if config.IsSet("uncomment") {
+		chartOpts.uncomment = config.GetBool("uncomment")
+	}
+
+	log.Debugf("Using config file %s for chart %s", path, chartPath)
+	return &chartOpts, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExec_ConfigFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile(".helm-schema.yaml", "dont-add-global: true\noutput-file: schema.json\n")
+	writeFile("a/Chart.yaml", "apiVersion: v2\nname: a\nversion: 1.0.0\n")
+	writeFile("a/values.yaml", "name: a\n")
+	writeFile("b/Chart.yaml", "apiVersion: v2\nname: b\nversion: 1.0.0\n")
+	writeFile("b/custom.yaml", "name: b\n")
+	writeFile("b/.helm-schema.yaml", "value-files: [custom.yaml]\noutput-file: b.schema.json\ndont-add-global: false\nskip-auto-generation: [title]\n")
+
+	readSchema := func(relPath string) map[string]any {
+		content, err := os.ReadFile(filepath.Join(tmpDir, relPath))
+		assert.NoError(t, err)
+		var result map[string]any
+		assert.NoError(t, json.Unmarshal(content, &result))
+		return result
+	}
+
+	viper.Reset()
+	cmd, err := newCommand(exec)
+	assert.NoError(t, err)
+	cmd.SetArgs([]string{"--chart-search-root", tmpDir})
+	assert.NoError(t, cmd.Execute())
+
+	// The root config applies to every chart
+	a := readSchema("a/schema.json")
+	assert.NotContains(t, a["properties"], "global")
+	assert.Equal(t, "name", a["properties"].(map[string]any)["name"].(map[string]any)["title"])
+
+	// The chart config overrides it
+	b := readSchema("b/b.schema.json")
+	assert.Contains(t, b["properties"], "global")
+	assert.NotContains(t, b["properties"].(map[string]any)["name"], "title")
+	_, err = os.Stat(filepath.Join(tmpDir, "b", "schema.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Flags take precedence over the root config
+	viper.Reset()
+	cmd, err = newCommand(exec)
+	assert.NoError(t, err)
+	cmd.SetArgs([]string{"--chart-search-root", tmpDir, "--output-file", "flag.json"})
+	assert.NoError(t, cmd.Execute())
+	_, err = os.Stat(filepath.Join(tmpDir, "a", "flag.json"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(tmpDir, "b", "flag.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestForChart_InvalidConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	setStandardViper(tmpDir)
+	opts, err := newGeneratorOptions()
+	assert.NoError(t, err)
+
+	chartPath := filepath.Join(tmpDir, "chart", "Chart.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(chartPath), 0o755))
+
+	// Charts without config file use the global options
+	chartOpts, err := opts.forChart(chartPath)
+	assert.NoError(t, err)
+	assert.Same(t, opts, chartOpts)
+
+	configPath := filepath.Join(tmpDir, "chart", ".helm-schema.yaml")
+	assert.NoError(t, os.WriteFile(configPath, []byte("skip-auto-generation: [unknown]\n"), 0o644))
+	_, err = opts.forChart(chartPath)
+	assert.ErrorContains(t, err, "invalid option skip-auto-generation")
+
+	assert.NoError(t, os.WriteFile(configPath, []byte("value-files: [\n"), 0o644))
+	_, err = opts.forChart(chartPath)
+	assert.ErrorContains(t, err, "failed to read")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dadav/helm-schema/pkg/crd"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newCRDCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "crd <chart>",
+		Short: "generate a CustomResourceDefinition whose spec is the values of a chart",
+		Long: `Generate a CustomResourceDefinition for helm based operators (e.g. the helm
+operator of the Operator SDK), whose custom resource spec holds the values of
+the chart.
+
+The final schema of the chart (including merged dependencies) is converted
+into a structural OpenAPI v3 schema: definitions are inlined, objects which
+allow additional properties preserve unknown fields, null types become
+nullable and allOf, anyOf, oneOf and not only keep value validations.
+Keywords which can't be expressed are dropped with a warning.
+
+The chart is given by its name or its directory. The kind defaults to the
+chart name in CamelCase. The manifest is printed to stdout unless --file is
+set.`,
+		Args: cobra.ExactArgs(1),
+		RunE: generateCRD,
+	}
+
+	cmd.Flags().
+		String("group", "", "API group of the custom resource, e.g. example.com")
+	cmd.Flags().
+		String("kind", "", "kind of the custom resource (default: the chart name in CamelCase)")
+	cmd.Flags().
+		String("version", "v1alpha1", "API version of the custom resource")
+	cmd.Flags().
+		String("plural", "", "plural name of the custom resource (default: the lowercase kind + s)")
+	cmd.Flags().
+		String("scope", crd.ScopeNamespaced, fmt.Sprintf("scope of the custom resource, one of (%s, %s)", crd.ScopeNamespaced, crd.ScopeCluster))
+	cmd.Flags().
+		String("file", "", "file the manifest is written to")
+	_ = cmd.MarkFlagRequired("group")
+
+	return cmd
+}
+
+func generateCRD(cmd *cobra.Command, args []string) error {
+	configureLogging()
+
+	var options crd.Options
+	var file string
+	for flag, value := range map[string]*string{
+		"group":   &options.Group,
+		"kind":    &options.Kind,
+		"version": &options.Version,
+		"plural":  &options.Plural,
+		"scope":   &options.Scope,
+		"file":    &file,
+	} {
+		var err error
+		if *value, err = cmd.Flags().GetString(flag); err != nil {
+			return err
+		}
+	}
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+	dryRun := opts.dryRun
+	opts.disableWrites()
+
+	result, err := findChartResult(opts, args[0])
+	if err != nil {
+		return err
+	}
+
+	if options.Kind == "" {
+		options.Kind = crd.KindFromName(result.Chart.Name)
+	}
+	definition, warnings, err := crd.New(&result.Schema, options)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		log.Warnf("Chart %s: %s", result.Chart.Name, warning)
+	}
+	manifest, err := definition.ToYaml()
+	if err != nil {
+		return err
+	}
+
+	if file == "" || dryRun {
+		log.Infof("Printing CustomResourceDefinition of chart %s (%s)", result.Chart.Name, result.ChartPath)
+		_, err = cmd.OutOrStdout().Write(manifest)
+		return err
+	}
+	if err := os.WriteFile(file, manifest, 0o644); err != nil {
+		return err
+	}
+	log.Infof("Wrote CustomResourceDefinition of chart %s to %s", result.Chart.Name, file)
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCRD(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("my-app/Chart.yaml", `
+apiVersion: v2
+name: my-app
+version: 1.0.0
+`)
+	writeFile("my-app/values.yaml", `
+# @schema
+# type: [string, null]
+# @schema
+tag: null
+# @schema
+# additionalProperties: true
+# @schema
+podAnnotations: {}
+`)
+
+	crd := func(args ...string) (string, error) {
+		setStandardViper(tmpDir)
+		var out bytes.Buffer
+		cmd := newCRDCommand()
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := crd("my-app", "--group", "example.com")
+	assert.NoError(t, err)
+
+	var manifest struct {
+		Metadata struct {
+			Name string `yaml:"name"`
+		} `yaml:"metadata"`
+		Spec struct {
+			Names struct {
+				Kind string `yaml:"kind"`
+			} `yaml:"names"`
+			Versions []struct {
+				Name   string `yaml:"name"`
+				Schema struct {
+					OpenAPIV3Schema struct {
+						Properties map[string]map[string]interface{} `yaml:"properties"`
+					} `yaml:"openAPIV3Schema"`
+				} `yaml:"schema"`
+			} `yaml:"versions"`
+		} `yaml:"spec"`
+	}
+	assert.NoError(t, yaml.Unmarshal([]byte(out), &manifest))
+	assert.Equal(t, "myapps.example.com", manifest.Metadata.Name)
+	assert.Equal(t, "MyApp", manifest.Spec.Names.Kind)
+	if assert.Len(t, manifest.Spec.Versions, 1) {
+		assert.Equal(t, "v1alpha1", manifest.Spec.Versions[0].Name)
+		spec := manifest.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+		properties := spec["properties"].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"type": "string", "title": "tag", "nullable": true, "default": "null"}, properties["tag"])
+		assert.Equal(t, true, properties["podAnnotations"].(map[string]interface{})["x-kubernetes-preserve-unknown-fields"])
+	}
+
+	// The manifest is written to the file
+	file := filepath.Join(tmpDir, "crd.yaml")
+	out, err = crd(filepath.Join(tmpDir, "my-app"), "--group", "example.com", "--kind", "Application", "--version", "v1", "--file", file)
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+	written, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(written), "name: applications.example.com\n")
+
+	_, err = crd("my-app", "--group", "example")
+	assert.EqualError(t, err, "invalid group example, must be a lowercase domain with at least one dot")
+
+	_, err = crd("other", "--group", "example.com")
+	assert.EqualError(t, err, "chart other not found below "+tmpDir)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newDiffCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff",
+		Short: "print a structural diff between the existing schema files and freshly generated ones",
+		Long: `Regenerate the schema of every chart in memory and compare it with the existing
+schema file (see --output-file). Changes are printed per json pointer:
+
+  + added keyword, property or required entry
+  - removed keyword, property or required entry
+  ~ changed value (e.g. type or default)
+
+Exits nonzero if any schema differs or is missing.`,
+		Args: cobra.NoArgs,
+		RunE: diff,
+	}
+}
+
+func diff(cmd *cobra.Command, _ []string) error {
+	configureLogging()
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+	opts.disableWrites()
+
+	results, cleanup := collectResults(opts)
+	defer cleanup()
+
+	differs := false
+	foundErrors, err := finalizeSchemas(opts, results, func(result *schema.Result) bool {
+		if result.PreExistingSchema {
+			return true
+		}
+
+		schemaPath := opts.schemaPath(result)
+		generated, err := result.Schema.ToJsonForDraft(opts.draft)
+		if err != nil {
+			log.Errorf("Failed to serialize schema for chart %s: %s", result.Chart.Name, err)
+			return false
+		}
+		changes, err := diffWithExistingSchema(generated, schemaPath)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("chart %s (%s): schema file is missing\n", result.Chart.Name, schemaPath)
+			differs = true
+			return true
+		}
+		if err != nil {
+			log.Errorf("Failed to diff schema of chart %s: %s", result.Chart.Name, err)
+			return false
+		}
+		if len(changes) > 0 {
+			differs = true
+			printSchemaDiff(os.Stdout, result.Chart.Name, schemaPath, changes)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	if differs {
+		return errors.New("schema files differ from the generated schemas")
+	}
+	return nil
+}
+
+// diffWithExistingSchema compares the schema file at schemaPath with the
+// generated schema, both in the json of the configured draft.
+func diffWithExistingSchema(generated []byte, schemaPath string) ([]schema.Change, error) {
+	content, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := schema.DiffJSON(content, generated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", schemaPath, err)
+	}
+	return changes, nil
+}
+
+func printSchemaDiff(w io.Writer, chartName, schemaPath string, changes []schema.Change) {
+	fmt.Fprintf(w, "chart %s (%s):\n", chartName, schemaPath)
+	for _, change := range changes {
+		fmt.Fprintf(w, "  %s\n", change)
+	}
+}
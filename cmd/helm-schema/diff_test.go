@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffWithExistingSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "values.schema.json")
+
+	_, err := diffWithExistingSchema([]byte(`{}`), schemaPath)
+	assert.True(t, errors.Is(err, os.ErrNotExist), "a missing schema file must be reported as such")
+
+	err = os.WriteFile(schemaPath, []byte(`{
+  "type": "object",
+  "properties": {
+    "replicas": {"type": "integer", "default": 1}
+  }
+}`), 0o644)
+	assert.NoError(t, err)
+
+	generated, err := (&schema.Schema{
+		Type: []string{"object"},
+		Properties: map[string]*schema.Schema{
+			"replicas": {Type: []string{"integer"}, Default: 3},
+			"name":     {Type: []string{"string"}},
+		},
+	}).ToJson()
+	assert.NoError(t, err)
+
+	changes, err := diffWithExistingSchema(generated, schemaPath)
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	printSchemaDiff(&out, "chart", schemaPath, changes)
+	assert.Equal(t, "chart chart ("+schemaPath+"):\n"+
+		"  + /properties/name: schema of type string\n"+
+		"  ~ /properties/replicas/default: 1 -> 3\n", out.String())
+}
+
+// captureStdout returns what run prints to stdout
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	assert.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		content, _ := io.ReadAll(reader)
+		output <- string(content)
+	}()
+	run()
+	writer.Close()
+	os.Stdout = stdout
+	return <-output
+}
+
+func TestDiff_Draft(t *testing.T) {
+	for _, draft := range []string{"2019-09", "2020-12"} {
+		t.Run(draft, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			setStandardViper(tmpDir)
+			viper.Set("draft", draft)
+
+			chartDir := filepath.Join(tmpDir, "chart")
+			assert.NoError(t, os.MkdirAll(chartDir, 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: chart\nversion: 1.0.0\n"), 0o644))
+			values := `# @schema
+# definitions:
+#   port:
+#     type: integer
+# $ref: "#/definitions/port"
+# @schema
+port: 80
+# @schema
+# type: array
+# prefixItems:
+#   - type: string
+# items:
+#   type: integer
+# @schema
+args: []
+`
+			assert.NoError(t, os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte(values), 0o644))
+			assert.NoError(t, exec(nil, nil))
+
+			// The written schema doesn't differ from the generated one
+			var err error
+			output := captureStdout(t, func() { err = diff(newDiffCommand(), nil) })
+			assert.NoError(t, err)
+			assert.Empty(t, output)
+
+			assert.NoError(t, os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte(values+"name: web\n"), 0o644))
+			output = captureStdout(t, func() { err = diff(newDiffCommand(), nil) })
+			assert.EqualError(t, err, "schema files differ from the generated schemas")
+			assert.Contains(t, output, "  + /properties/name: schema of type string\n")
+			assert.NotContains(t, output, "$schema")
+
+			viper.Set("check", true)
+			viper.Set("show-diff", true)
+			output = captureStdout(t, func() { err = exec(nil, nil) })
+			assert.Error(t, err)
+			assert.Equal(t, "chart chart ("+filepath.Join(chartDir, "values.schema.json")+"):\n"+
+				"  + /required: \"name\"\n"+
+				"  + /properties/name: schema of type string\n", output)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/docs"
+	"github.com/dadav/helm-schema/pkg/schema"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newDocsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "docs",
+		Short: "generate a values reference from the jsonschema of every chart",
+		Long: `Render the final schema of every chart (including merged dependencies and
+definitions) as a table with key, type, default, description, enum,
+constraints and deprecation.
+
+By default the documentation is printed to stdout. With --inject it replaces
+the content between the lines containing ` + docs.MarkerStart + ` and
+` + docs.MarkerEnd + ` in the given file of each chart, e.g.
+
+  <!-- ` + docs.MarkerStart + ` -->
+  <!-- ` + docs.MarkerEnd + ` -->
+
+Custom text/template files get the chart (.Chart) and the rows (.Rows) and
+can use the helpers cell, code, codeList, constraintList and join.`,
+		Args: cobra.NoArgs,
+		RunE: generateDocs,
+	}
+
+	cmd.Flags().
+		String("format", docs.FormatMarkdown, fmt.Sprintf("output format, one of (%s)", strings.Join(docs.Formats, ", ")))
+	cmd.Flags().
+		String("template", "", "path to a custom text/template file used instead of the builtin table")
+	cmd.Flags().
+		String("inject", "", "file relative to each chart directory to inject the documentation into (e.g. README.md)")
+
+	return cmd
+}
+
+func generateDocs(cmd *cobra.Command, _ []string) error {
+	configureLogging()
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return err
+	}
+	templateFile, err := cmd.Flags().GetString("template")
+	if err != nil {
+		return err
+	}
+	injectFile, err := cmd.Flags().GetString("inject")
+	if err != nil {
+		return err
+	}
+
+	if err := docs.ValidateFormat(format); err != nil {
+		return err
+	}
+	renderer, err := docs.NewRenderer(format, templateFile)
+	if err != nil {
+		return err
+	}
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+	dryRun := opts.dryRun
+	opts.disableWrites()
+
+	results, cleanup := collectResults(opts)
+	defer cleanup()
+
+	printed := false
+	foundErrors, err := finalizeSchemas(opts, results, func(result *schema.Result) bool {
+		if result.PreExistingSchema {
+			return true
+		}
+
+		content, err := renderer.Render(result.Chart, &result.Schema)
+		if err != nil {
+			log.Errorf("Failed to render docs of chart %s: %s", result.Chart.Name, err)
+			return false
+		}
+
+		if injectFile == "" {
+			log.Infof("Printing docs of chart %s (%s)", result.Chart.Name, result.ChartPath)
+			if printed {
+				fmt.Println()
+			}
+			fmt.Print(string(content))
+			printed = true
+			return true
+		}
+
+		if err := injectDocs(filepath.Join(filepath.Dir(result.ChartPath), injectFile), content, dryRun); err != nil {
+			log.Errorf("Failed to inject docs of chart %s: %s", result.Chart.Name, err)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// injectDocs replaces the docs between the markers of the given file. Files
+// which don't exist or don't contain markers are skipped.
+func injectDocs(path string, content []byte, dryRun bool) error {
+	existing, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Debugf("Skipping %s, file does not exist", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	injected, err := docs.Inject(existing, content)
+	if errors.Is(err, docs.ErrNoMarkers) {
+		log.Warnf("Skipping %s, it contains no %s marker", path, docs.MarkerStart)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if dryRun {
+		log.Infof("Printing injected docs for %s", path)
+		fmt.Print(string(injected))
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, injected, info.Mode().Perm()); err != nil {
+		return err
+	}
+	log.Infof("Updated docs in %s", path)
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateDocs_Inject(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("parent/Chart.yaml", `
+apiVersion: v2
+name: parent
+version: 1.0.0
+dependencies:
+  - name: dep
+    version: 1.0.0
+`)
+	writeFile("parent/values.yaml", `
+# Number of replicas
+replicas: 1
+`)
+	writeFile("parent/README.md", "# parent\n\n<!-- helm-schema-docs-start -->\n<!-- helm-schema-docs-end -->\n")
+	writeFile("parent/charts/dep/Chart.yaml", `
+apiVersion: v2
+name: dep
+version: 1.0.0
+description: The dependency
+`)
+	writeFile("parent/charts/dep/values.yaml", `
+# Enable the feature
+enabled: true
+`)
+
+	setStandardViper(tmpDir)
+	cmd := newDocsCommand()
+	cmd.SetArgs([]string{"--inject", "README.md"})
+	assert.NoError(t, cmd.Execute())
+
+	readme, err := os.ReadFile(filepath.Join(tmpDir, "parent", "README.md"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(readme), "| `replicas` | `integer` | `1` | Number of replicas |")
+	assert.Contains(t, string(readme), "| `dep` | `object` |  | The dependency |")
+	assert.Contains(t, string(readme), "| `dep.enabled` | `boolean` | `true` | Enable the feature |")
+	assert.Contains(t, string(readme), "<!-- helm-schema-docs-end -->\n")
+
+	// The dependency has no README and is skipped
+	_, err = os.Stat(filepath.Join(tmpDir, "parent", "charts", "dep", "README.md"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGenerateDocs_InvalidFormat(t *testing.T) {
+	setStandardViper(t.TempDir())
+	cmd := newDocsCommand()
+	cmd.SetArgs([]string{"--format", "html"})
+	assert.ErrorContains(t, cmd.Execute(), "unsupported docs format html")
+}
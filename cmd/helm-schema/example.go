@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newExampleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "example <chart directory|schema file>",
+		Short: "generate a commented values file from a schema",
+		Long: `Generate a values file from an existing schema, e.g. a hand-written schema or
+one which mostly consists of $refs, to get started with a chart.
+
+Every key gets its default, or its const, first enum value or first example,
+or else a placeholder of its type. Descriptions are written as comments and
+the keywords which can't be inferred from the values as @schema blocks, so
+generating the schema of the values file yields an equivalent schema.
+
+With --minimal only the required keys are written.
+
+The argument is a schema file or a chart directory, whose schema file is
+read (see --schema-file). The values are printed to stdout unless --file is
+set.`,
+		Args: cobra.ExactArgs(1),
+		RunE: generateExample,
+	}
+
+	cmd.Flags().
+		Bool("minimal", false, "only write the required keys")
+	cmd.Flags().
+		String("schema-file", "", "schema file relative to the chart directory (default: --output-file)")
+	cmd.Flags().
+		String("file", "", "file the values are written to")
+
+	return cmd
+}
+
+func generateExample(cmd *cobra.Command, args []string) error {
+	configureLogging()
+
+	minimal, err := cmd.Flags().GetBool("minimal")
+	if err != nil {
+		return err
+	}
+	schemaFile, err := cmd.Flags().GetString("schema-file")
+	if err != nil {
+		return err
+	}
+	file, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return err
+	}
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+
+	schemaPath := args[0]
+	if info, err := os.Stat(schemaPath); err == nil && info.IsDir() {
+		if schemaFile == "" {
+			chartOpts, err := opts.forChart(filepath.Join(schemaPath, "Chart.yaml"))
+			if err != nil {
+				return err
+			}
+			schemaFile = chartOpts.outFile
+		}
+		schemaPath = filepath.Join(schemaPath, schemaFile)
+	}
+
+	existing, err := schema.ReadSchemaFile(schemaPath)
+	if err != nil {
+		return err
+	}
+	values, err := schema.ExampleValues(existing, minimal)
+	if err != nil {
+		return err
+	}
+
+	if file == "" || opts.dryRun {
+		log.Infof("Printing example values of %s", schemaPath)
+		_, err = cmd.OutOrStdout().Write(values)
+		return err
+	}
+	if err := os.WriteFile(file, values, 0o644); err != nil {
+		return err
+	}
+	log.Infof("Wrote example values of %s to %s", schemaPath, file)
+	return nil
+}
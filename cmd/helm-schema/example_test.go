@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExample(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	chartDir := filepath.Join(tmpDir, "my-app")
+	assert.NoError(t, os.MkdirAll(chartDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(chartDir, "values.schema.json"), []byte(`{
+  "type": "object",
+  "required": ["name"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "description": "Name of the app", "minLength": 1},
+    "replicas": {"type": "integer", "default": 2}
+  }
+}`), 0o644))
+
+	example := func(args ...string) (string, error) {
+		setStandardViper(tmpDir)
+		var out bytes.Buffer
+		cmd := newExampleCommand()
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := example(chartDir)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "# @schema\n# type: string\n# minLength: 1\n# @schema\n# Name of the app\nname: \"\"\n")
+	assert.Contains(t, out, "\nreplicas: 2\n")
+
+	out, err = example(filepath.Join(chartDir, "values.schema.json"), "--minimal")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "\nname: \"\"\n")
+	assert.NotContains(t, out, "replicas")
+
+	// The values are written to the file
+	file := filepath.Join(tmpDir, "values.yaml")
+	out, err = example(chartDir, "--file", file)
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+	written, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(written), "\nreplicas: 2\n")
+
+	// The schema file of a chart is taken from its config file
+	assert.NoError(t, os.Rename(filepath.Join(chartDir, "values.schema.json"), filepath.Join(chartDir, "chart.schema.json")))
+	assert.NoError(t, os.WriteFile(filepath.Join(chartDir, ".helm-schema.yaml"), []byte("output-file: chart.schema.json\n"), 0o644))
+	out, err = example(chartDir)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "\nreplicas: 2\n")
+
+	_, err = example(chartDir, "--schema-file", "missing.json")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/dadav/helm-schema/pkg/docs"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newExplainCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain <chart> <path>",
+		Short: "print the schema of a values path",
+		Long: `Print the documentation of a dot-separated values path from the final schema
+of a chart (including merged dependencies and resolved definitions): type,
+default, description, enum, constraints and the child keys.
+
+The chart is given by its name or its directory. Array items are entered
+implicitly or with [], e.g. ingress.tls.hosts or ingress.tls[].hosts. An
+empty path explains the root of the values.
+
+With --recursive the whole subtree of keys is printed.`,
+		Args: cobra.ExactArgs(2),
+		RunE: explain,
+	}
+
+	cmd.Flags().
+		Bool("recursive", false, "print all keys below the path instead of the direct child keys")
+
+	return cmd
+}
+
+func explain(cmd *cobra.Command, args []string) error {
+	configureLogging()
+
+	recursive, err := cmd.Flags().GetBool("recursive")
+	if err != nil {
+		return err
+	}
+	chartName, path := args[0], args[1]
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+	opts.disableWrites()
+
+	result, err := findChartResult(opts, chartName)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Explaining %q of chart %s (%s)", path, result.Chart.Name, result.ChartPath)
+	return docs.Explain(cmd.OutOrStdout(), &result.Schema, path, recursive)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("parent/Chart.yaml", `
+apiVersion: v2
+name: parent
+version: 1.0.0
+dependencies:
+  - name: dep
+    version: 1.0.0
+`)
+	writeFile("parent/values.yaml", `
+# Number of replicas
+replicas: 1
+`)
+	writeFile("parent/charts/dep/Chart.yaml", `
+apiVersion: v2
+name: dep
+version: 1.0.0
+description: The dependency
+`)
+	writeFile("parent/charts/dep/values.yaml", `
+# @schema
+# enum: [a, b]
+# @schema
+# The mode
+mode: a
+`)
+
+	explain := func(args ...string) (string, error) {
+		setStandardViper(tmpDir)
+		var out bytes.Buffer
+		cmd := newExplainCommand()
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	// Dependencies are merged into the parent
+	out, err := explain("parent", "dep.mode")
+	assert.NoError(t, err)
+	assert.Equal(t, "KEY:     dep.mode\nTYPE:    any\nDEFAULT: \"a\"\nENUM:    \"a\", \"b\"\n\nDESCRIPTION:\n  The mode\n", out)
+
+	// Charts can be given by directory
+	out, err = explain(filepath.Join(tmpDir, "parent"), "", "--recursive")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "  dep       <object>\n    global  <object>\n    mode    <any>\n")
+	assert.Contains(t, out, "  replicas  <integer>  required\n")
+
+	_, err = explain("parent", "missing")
+	assert.ErrorContains(t, err, "key not found: missing")
+
+	_, err = explain("unknown", "replicas")
+	assert.ErrorContains(t, err, "chart unknown not found")
+}
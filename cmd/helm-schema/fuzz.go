@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dadav/helm-schema/pkg/fuzz"
+	"github.com/dadav/helm-schema/pkg/schema"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newFuzzCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fuzz <chart>",
+		Short: "generate random values files for template testing",
+		Long: `Generate random values files from the schema of a chart, e.g. to render the
+templates with unusual but valid values.
+
+The values files are generated from the final schema of the chart (including
+merged dependencies) and satisfy its types, enums, patterns, numeric ranges
+and array sizes. The same --seed always yields the same files.
+
+With --invalid, values files which violate exactly one constraint each are
+generated as well. Each starts with a comment naming the violated keyword,
+the JSON pointer of the violating value and the location of the keyword in
+the schema, e.g.
+
+  # violates maximum at "/replicas" (#/properties/replicas/maximum)
+
+The chart is given by its name or its directory. The values files are printed
+to stdout as YAML documents unless --output-dir is set, where they are written
+as valid-001.yaml, ... and invalid-001.yaml, ...`,
+		Args: cobra.ExactArgs(1),
+		RunE: generateFuzzValues,
+	}
+
+	cmd.Flags().
+		Int("count", 10, "number of valid values files")
+	cmd.Flags().
+		Int64("seed", 1, "seed of the random values")
+	cmd.Flags().
+		Bool("invalid", false, "also generate values files which violate one constraint each")
+	cmd.Flags().
+		String("output-dir", "", "directory the values files are written to")
+
+	return cmd
+}
+
+// fuzzFile is a generated values file
+type fuzzFile struct {
+	name    string
+	comment string
+	values  interface{}
+}
+
+func generateFuzzValues(cmd *cobra.Command, args []string) error {
+	configureLogging()
+
+	count, err := cmd.Flags().GetInt("count")
+	if err != nil {
+		return err
+	}
+	if count < 0 {
+		return fmt.Errorf("invalid count %d, must not be negative", count)
+	}
+	seed, err := cmd.Flags().GetInt64("seed")
+	if err != nil {
+		return err
+	}
+	invalid, err := cmd.Flags().GetBool("invalid")
+	if err != nil {
+		return err
+	}
+	outputDir, err := cmd.Flags().GetString("output-dir")
+	if err != nil {
+		return err
+	}
+
+	chartName := args[0]
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+	dryRun := opts.dryRun
+	opts.disableWrites()
+
+	result, err := findChartResult(opts, chartName)
+	if err != nil {
+		return err
+	}
+	files, err := fuzzValues(opts, result, count, seed, invalid)
+	if err != nil {
+		return err
+	}
+
+	if outputDir == "" || dryRun {
+		for _, file := range files {
+			content, err := fuzzFileContent(file)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "---\n# %s\n%s", file.name, content); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		content, err := fuzzFileContent(file)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(outputDir, file.name), content, 0o644); err != nil {
+			return err
+		}
+	}
+	log.Infof("Wrote %d values files of chart %s to %s", len(files), chartName, outputDir)
+	return nil
+}
+
+// fuzzValues generates the values files of a chart
+func fuzzValues(opts *generatorOptions, result *schema.Result, count int, seed int64, invalid bool) ([]fuzzFile, error) {
+	jsonStr, err := result.Schema.ToJsonForDraft(opts.draft)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := compileSchema(jsonStr, opts.urlLoader())
+	if err != nil {
+		return nil, err
+	}
+
+	generator := fuzz.New(&result.Schema, compiled, seed)
+	docs, err := generator.Valid(count)
+	if err != nil {
+		return nil, err
+	}
+	var files []fuzzFile
+	for i, doc := range docs {
+		files = append(files, fuzzFile{name: fmt.Sprintf("valid-%03d.yaml", i+1), values: doc})
+	}
+	if !invalid {
+		return files, nil
+	}
+
+	cases, err := generator.Invalid()
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range cases {
+		files = append(files, fuzzFile{
+			name:    fmt.Sprintf("invalid-%03d.yaml", i+1),
+			comment: fmt.Sprintf("# violates %s at %q (%s)\n", c.Keyword, c.Pointer, c.SchemaPointer),
+			values:  c.Values,
+		})
+	}
+	return files, nil
+}
+
+func fuzzFileContent(file fuzzFile) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(file.comment)
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(file.values); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
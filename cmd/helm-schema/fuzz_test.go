@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestFuzz(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("my-app/Chart.yaml", `
+apiVersion: v2
+name: my-app
+version: 1.0.0
+`)
+	writeFile("my-app/values.yaml", `
+# @schema
+# type: integer
+# minimum: 1
+# maximum: 5
+# @schema
+replicas: 1
+# @schema
+# enum: [Always, IfNotPresent]
+# @schema
+pullPolicy: Always
+image:
+  # @schema
+  # type: string
+  # pattern: ^[a-z]+$
+  # @schema
+  repository: nginx
+`)
+
+	fuzzValues := func(args ...string) (string, error) {
+		setStandardViper(tmpDir)
+		var out bytes.Buffer
+		cmd := newFuzzCommand()
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := fuzzValues("my-app", "--count", "3", "--seed", "5")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "---\n# valid-001.yaml\n")
+	assert.Contains(t, out, "---\n# valid-003.yaml\n")
+	assert.NotContains(t, out, "invalid-001.yaml")
+
+	// The same seed yields the same values
+	again, err := fuzzValues("my-app", "--count", "3", "--seed", "5")
+	assert.NoError(t, err)
+	assert.Equal(t, out, again)
+
+	// The values files are written to the directory
+	outputDir := filepath.Join(tmpDir, "fuzz")
+	out, err = fuzzValues(filepath.Join(tmpDir, "my-app"), "--count", "2", "--invalid", "--output-dir", outputDir)
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+
+	valid, err := os.ReadFile(filepath.Join(outputDir, "valid-002.yaml"))
+	assert.NoError(t, err)
+	var values map[string]interface{}
+	assert.NoError(t, yaml.Unmarshal(valid, &values))
+	if assert.Contains(t, values, "image") {
+		assert.Regexp(t, "^[a-z]+$", values["image"].(map[string]interface{})["repository"])
+	}
+
+	var comments []string
+	invalidFiles, err := filepath.Glob(filepath.Join(outputDir, "invalid-*.yaml"))
+	assert.NoError(t, err)
+	for _, file := range invalidFiles {
+		content, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		comment, _, _ := bytes.Cut(content, []byte("\n"))
+		comments = append(comments, string(comment))
+	}
+	assert.Contains(t, comments, `# violates maximum at "/replicas" (#/properties/replicas/maximum)`)
+	assert.Contains(t, comments, `# violates enum at "/pullPolicy" (#/properties/pullPolicy/enum)`)
+	assert.Contains(t, comments, `# violates pattern at "/image/repository" (#/properties/image/properties/repository/pattern)`)
+
+	_, err = fuzzValues("my-app", "--count", "-1")
+	assert.EqualError(t, err, "invalid count -1, must not be negative")
+
+	_, err = fuzzValues("other")
+	assert.EqualError(t, err, "chart other not found below "+tmpDir)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/dadav/helm-schema/pkg/chart"
+	"github.com/dadav/helm-schema/pkg/chart/searching"
+	"github.com/dadav/helm-schema/pkg/schema"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "turn existing schema files into @schema annotations of the values files",
+		Long: `Read the existing schema file of every chart and write the parts of each
+property which can't be inferred from the values (e.g. enum, pattern, minimum,
+oneOf or descriptions) as @schema blocks into the first matching values file,
+so that generating the schema afterwards yields an equivalent schema.
+
+Keys which already have a @schema block are left unchanged. Root keywords like
+definitions or the required keys are written as @schema.root block. The
+properties of dependencies are skipped, they are merged when generating.
+
+With --dry-run the annotated values files are printed instead.`,
+		Args: cobra.NoArgs,
+		RunE: importSchemas,
+	}
+
+	cmd.Flags().
+		String("schema-file", "", "schema file relative to each chart directory to import (default: --output-file)")
+
+	return cmd
+}
+
+func importSchemas(cmd *cobra.Command, _ []string) error {
+	configureLogging()
+
+	schemaFile, err := cmd.Flags().GetString("schema-file")
+	if err != nil {
+		return err
+	}
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+
+	queue := make(chan string)
+	errs := make(chan error, 100)
+	go searching.SearchFiles(opts.chartSearchRoot, opts.chartSearchRoot, "Chart.yaml", opts.dependenciesFilterMap, queue, errs)
+
+	foundErrors := false
+	for queueOpen := true; queueOpen; {
+		select {
+		case err := <-errs:
+			log.Error(err)
+			foundErrors = true
+		case chartPath, ok := <-queue:
+			if !ok {
+				queueOpen = false
+				continue
+			}
+			if err := importSchema(chartPath, schemaFile, opts); err != nil {
+				log.Errorf("Failed to import schema of chart %s: %s", chartPath, err)
+				foundErrors = true
+			}
+		}
+	}
+	if len(errs) > 0 {
+		foundErrors = true
+	}
+	logErrors(errs)
+
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// importSchema annotates the values file of a chart with its existing schema.
+// Charts without schema file are skipped.
+func importSchema(chartPath, schemaFile string, opts *generatorOptions) error {
+	chartDir := filepath.Dir(chartPath)
+	opts, err := opts.forChart(chartPath)
+	if err != nil {
+		return err
+	}
+	if schemaFile == "" {
+		schemaFile = opts.outFile
+	}
+
+	schemaPath := filepath.Join(chartDir, schemaFile)
+	existing, err := schema.ReadSchemaFile(schemaPath)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Debugf("Skipping %s, it has no %s", chartPath, schemaFile)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	chartFile := readChartFile(chartPath)
+	if chartFile == nil {
+		return errors.New("failed to read Chart.yaml")
+	}
+	removeDependencyProperties(existing, chartFile)
+
+	for _, valueFileName := range opts.valueFileNames {
+		valuesPath := filepath.Join(chartDir, valueFileName)
+		if _, err := os.Stat(valuesPath); err != nil {
+			continue
+		}
+		return schema.RewriteValuesFile(valuesPath, opts.dryRun, schema.AnnotateFromSchemaRewrite(valuesPath, existing))
+	}
+
+	log.Warnf("Skipping %s, it has no values file", chartPath)
+	return nil
+}
+
+// removeDependencyProperties removes the merged schemas of the dependencies,
+// the generator merges them again.
+func removeDependencyProperties(existing *schema.Schema, chartFile *chart.ChartFile) {
+	for _, dep := range chartFile.Dependencies {
+		name := dep.Name
+		if dep.Alias != "" {
+			name = dep.Alias
+		}
+		delete(existing.Properties, name)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportSchemas(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("parent/Chart.yaml", `
+apiVersion: v2
+name: parent
+version: 1.0.0
+dependencies:
+  - name: dep
+    version: 1.0.0
+`)
+	writeFile("parent/values.yaml", "pullPolicy: Always\ndep:\n  enabled: true\n")
+	writeFile("parent/values.schema.json", `{
+  "type": "object",
+  "required": ["pullPolicy"],
+  "properties": {
+    "pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent"]},
+    "dep": {"type": "object", "properties": {"enabled": {"type": "boolean"}}}
+  }
+}`)
+	writeFile("parent/charts/dep/Chart.yaml", `
+apiVersion: v2
+name: dep
+version: 1.0.0
+`)
+	writeFile("parent/charts/dep/values.yaml", "enabled: true\n")
+	writeFile("other/Chart.yaml", `
+apiVersion: v2
+name: other
+version: 1.0.0
+`)
+	writeFile("other/values.yaml", "name: x\n")
+
+	setStandardViper(tmpDir)
+	cmd := newImportCommand()
+	cmd.SetArgs([]string{})
+	assert.NoError(t, cmd.Execute())
+
+	values, err := os.ReadFile(filepath.Join(tmpDir, "parent", "values.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# @schema.root\n# additionalProperties: true\n# required:\n#   - pullPolicy\n# @schema.root\n"+
+		"# @schema\n# type: string\n# enum:\n#   - Always\n#   - IfNotPresent\n# @schema\npullPolicy: Always\ndep:\n  enabled: true\n", string(values))
+
+	// Charts without schema file are skipped
+	otherValues, err := os.ReadFile(filepath.Join(tmpDir, "other", "values.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "name: x\n", string(otherValues))
+
+	// Regenerating keeps the imported constraints
+	setStandardViper(tmpDir)
+	assert.NoError(t, exec(nil, nil))
+	generated, err := os.ReadFile(filepath.Join(tmpDir, "parent", "values.schema.json"))
+	assert.NoError(t, err)
+	var result map[string]any
+	assert.NoError(t, json.Unmarshal(generated, &result))
+	properties := result["properties"].(map[string]any)
+	assert.Equal(t, []any{"Always", "IfNotPresent"}, properties["pullPolicy"].(map[string]any)["enum"])
+	assert.Equal(t, []any{"pullPolicy"}, result["required"])
+	assert.Contains(t, properties, "dep")
+}
+
+func TestImportSchemas_InvalidSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(tmpDir, "Chart.yaml"), []byte("apiVersion: v2\nname: chart\nversion: 1.0.0\n"), 0o644)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(tmpDir, "values.yaml"), []byte("name: x\n"), 0o644)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(tmpDir, "custom.json"), []byte("{"), 0o644)
+	assert.NoError(t, err)
+
+	setStandardViper(tmpDir)
+	cmd := newImportCommand()
+	cmd.SetArgs([]string{"--schema-file", "custom.json"})
+	assert.ErrorContains(t, cmd.Execute(), "some errors were found")
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/chart/searching"
+	"github.com/dadav/helm-schema/pkg/lint"
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/dadav/helm-schema/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newLintCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "check the annotations of the values files against authoring rules",
+		Long: `Generate the schema of every chart and check it together with the keys of the
+values file against these rules:
+
+  ` + lint.RuleDocumented + `                  every leaf key has a description (warning)
+  ` + lint.RuleRootAdditionalProperties + `  the root doesn't allow additional properties (error)
+  ` + lint.RulePlaintextPassword + `          password, token and key values have no default (error)
+  ` + lint.RuleDeprecatedReplacement + `      deprecated keys explain their replacement (warning)
+  ` + lint.RuleRemoteRef + `                  no $ref points to a http(s) URL (error)
+
+The severity of every rule can be changed to error, warning or off with a
+rules file or --rule, e.g. --rule documented=off. The command fails if an
+error was found.`,
+		Args: cobra.NoArgs,
+		RunE: lintCharts,
+	}
+
+	cmd.Flags().
+		String("rules-file", "", "yaml file with the severities of the rules, e.g. 'rules: {documented: error}'")
+	cmd.Flags().
+		StringToString("rule", map[string]string{}, "severity of a rule (error, warning or off), overrides the rules file")
+
+	return cmd
+}
+
+func lintCharts(cmd *cobra.Command, _ []string) error {
+	configureLogging()
+
+	config, err := newLintConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+	opts.disableWrites()
+
+	queue := make(chan string)
+	errs := make(chan error, 100)
+	go searching.SearchFiles(opts.chartSearchRoot, opts.chartSearchRoot, "Chart.yaml", opts.dependenciesFilterMap, queue, errs)
+	results := runWorkers(opts, queue, errs)
+	sort.Slice(results, func(i, j int) bool { return results[i].ChartPath < results[j].ChartPath })
+
+	foundErrors := false
+	errorCount, warningCount := 0, 0
+	for _, result := range results {
+		if len(result.Errors) > 0 {
+			for _, err := range result.Errors {
+				log.Errorf("Failed to lint chart %s: %s", result.ChartPath, err)
+			}
+			foundErrors = true
+			continue
+		}
+
+		// The values file is read with the options of its chart, like it was
+		// read to generate the schema
+		chartOpts, err := opts.forChart(result.ChartPath)
+		if err != nil {
+			log.Errorf("Failed to lint chart %s: %s", result.ChartPath, err)
+			foundErrors = true
+			continue
+		}
+		findings, err := lintValuesFile(result.ValuesPath, &result.Schema, config, chartOpts.uncomment)
+		if err != nil {
+			log.Errorf("Failed to lint chart %s: %s", result.ChartPath, err)
+			foundErrors = true
+			continue
+		}
+		for _, finding := range findings {
+			if finding.Severity == lint.SeverityError {
+				log.Errorf("%s:%s", result.ValuesPath, finding)
+				errorCount++
+			} else {
+				log.Warnf("%s:%s", result.ValuesPath, finding)
+				warningCount++
+			}
+		}
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("found %d lint errors and %d warnings", errorCount, warningCount)
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	log.Infof("Linted %d charts, found %d warnings", len(results), warningCount)
+	return nil
+}
+
+// newLintConfig reads the rules file and applies the --rule flags
+func newLintConfig(cmd *cobra.Command) (*lint.Config, error) {
+	rulesFile, err := cmd.Flags().GetString("rules-file")
+	if err != nil {
+		return nil, err
+	}
+	rules, err := cmd.Flags().GetStringToString("rule")
+	if err != nil {
+		return nil, err
+	}
+
+	config := &lint.Config{}
+	if rulesFile != "" {
+		config, err = lint.ReadConfig(rulesFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.Rules == nil {
+		config.Rules = make(map[string]lint.Severity)
+	}
+	for rule, severity := range rules {
+		config.Rules[rule] = lint.Severity(strings.ToLower(severity))
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// lintValuesFile lints the schema generated from the values file, the
+// findings point to the keys of the file. With uncomment, commented out keys
+// are linted as well.
+func lintValuesFile(valuesPath string, s *schema.Schema, config *lint.Config, uncomment bool) ([]lint.Finding, error) {
+	file, err := os.Open(valuesPath)
+	if err != nil {
+		return nil, err
+	}
+	content, err := util.ReadFileAndFixNewline(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	if uncomment {
+		content, err = util.RemoveCommentsFromYaml(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", valuesPath, err)
+	}
+	return lint.Lint(&doc, s, config), nil
+}
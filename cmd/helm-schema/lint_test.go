@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintCharts(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("chart/Chart.yaml", "apiVersion: v2\nname: chart\nversion: 1.0.0\n")
+	writeFile("chart/values.yaml", "# The admin password\npassword: changeme\nundocumented: 1\n")
+	writeFile("rules.yaml", "rules:\n  plaintext-password: warning\n")
+
+	lintWith := func(args ...string) error {
+		setStandardViper(tmpDir)
+		cmd := newLintCommand()
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	assert.ErrorContains(t, lintWith(), "found 1 lint errors and 1 warnings")
+	assert.NoError(t, lintWith("--rules-file", filepath.Join(tmpDir, "rules.yaml")))
+	assert.NoError(t, lintWith("--rule", "plaintext-password=off"))
+	assert.ErrorContains(t, lintWith("--rule", "documented=error", "--rule", "plaintext-password=off"), "found 1 lint errors")
+	assert.ErrorContains(t, lintWith("--rule", "unknown=off"), "unknown rule unknown")
+}
+
+func TestLintCharts_ChartConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("chart/Chart.yaml", "apiVersion: v2\nname: chart\nversion: 1.0.0\n")
+	writeFile("chart/.helm-schema.yaml", "value-files:\n  - custom.yaml\nuncomment: true\n")
+	writeFile("chart/custom.yaml", "# The replicas\nreplicas: 1\n# undocumented: 1\n")
+
+	setStandardViper(tmpDir)
+	cmd := newLintCommand()
+	cmd.SetArgs([]string{"--rule", "documented=error"})
+	// The commented out key is linted, like it is part of the schema
+	assert.ErrorContains(t, cmd.Execute(), "found 1 lint errors and 0 warnings")
+}
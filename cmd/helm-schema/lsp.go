@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/dadav/helm-schema/pkg/lsp"
+	"github.com/spf13/cobra"
+)
+
+func newLSPCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lsp",
+		Short: "start a language server for values files on stdio",
+		Long: `Start a language server (LSP) for values files which communicates over stdin/stdout.
+
+It reports invalid @schema annotations while typing, completes JSON Schema
+keywords inside @schema blocks and shows the generated schema of the key under
+the cursor on hover. The generator flags (e.g. --helm-docs-compatibility-mode)
+are respected. Logs are written to stderr.`,
+		Args: cobra.NoArgs,
+		RunE: runLSP,
+	}
+}
+
+func runLSP(cmd *cobra.Command, _ []string) error {
+	configureLogging()
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+
+	server := lsp.NewServer(version, lsp.Options{
+		KeepFullComment:           opts.keepFullComment,
+		HelmDocsCompatibilityMode: opts.helmDocsCompatibilityMode,
+		DontRemoveHelmDocsPrefix:  opts.dontRemoveHelmDocsPrefix,
+		DontAddGlobal:             opts.dontAddGlobal,
+		SkipAutoGeneration:        opts.skipConfig,
+	})
+	return server.Run(cmd.InOrStdin(), cmd.OutOrStdout())
+}
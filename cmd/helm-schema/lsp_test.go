@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunLSP(t *testing.T) {
+	setStandardViper(t.TempDir())
+
+	var in bytes.Buffer
+	for _, msg := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	var out bytes.Buffer
+
+	cmd := newLSPCommand()
+	cmd.SetArgs([]string{})
+	cmd.SetIn(&in)
+	cmd.SetOut(&out)
+	assert.NoError(t, cmd.Execute())
+
+	assert.Contains(t, out.String(), `"serverInfo":{"name":"helm-schema"`)
+	assert.Contains(t, out.String(), `{"jsonrpc":"2.0","id":2,"result":null}`)
+}
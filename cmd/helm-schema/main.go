@@ -2,18 +2,23 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"slices"
 	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/dadav/helm-schema/pkg/chart"
 	"github.com/dadav/helm-schema/pkg/chart/searching"
+	"github.com/dadav/helm-schema/pkg/report"
 	"github.com/dadav/helm-schema/pkg/schema"
 	"github.com/santhosh-tekuri/jsonschema/v6"
 	log "github.com/sirupsen/logrus"
@@ -177,6 +182,18 @@ func processImportValues(
 	return importedProps
 }
 
+// addDependencyDefinitions adds the namespaced definitions of a dependency
+// to the root of the parent schema, the imported properties refer to them
+func addDependencyDefinitions(parentSchema *schema.Schema, depSchema *schema.Schema) {
+	if len(depSchema.Definitions) == 0 {
+		return
+	}
+	if parentSchema.Definitions == nil {
+		parentSchema.Definitions = make(map[string]*schema.Schema)
+	}
+	maps.Copy(parentSchema.Definitions, depSchema.Definitions)
+}
+
 // parseConditionPaths parses a Helm dependency condition string into one or more
 // property paths that should receive a boolean marker in the target schema.
 //
@@ -210,10 +227,10 @@ func parseConditionPaths(condition, depName, depAlias string) [][]string {
 	return paths
 }
 
-// stubURLLoader resolves any external ($ref) URL to a permissive schema so that
-// final-schema compilation stays hermetic: no network access and no dependency
-// on external schema files existing. It still lets the compiler catch
-// structurally invalid output and broken internal refs.
+// stubURLLoader resolves any external ($ref) URL to a permissive schema, so
+// compilation needs no network access and no external schema files while
+// remote refs aren't resolved. It still lets the compiler catch structurally
+// invalid output and broken internal refs.
 type stubURLLoader struct{}
 
 func (stubURLLoader) Load(_ string) (any, error) {
@@ -221,87 +238,212 @@ func (stubURLLoader) Load(_ string) (any, error) {
 	return true, nil
 }
 
-// compileFinalSchema compiles the serialized final schema against Draft 7 to
-// verify it is structurally valid and that all internal $refs resolve. External
-// refs are stubbed via stubURLLoader.
-func compileFinalSchema(jsonStr []byte) error {
+// urlLoader returns the loader of external refs, the fetched documents if
+// remote refs are resolved or else the stub
+func (opts *generatorOptions) urlLoader() jsonschema.URLLoader {
+	if opts.remoteRefs != nil {
+		return opts.remoteRefs
+	}
+	return stubURLLoader{}
+}
+
+// compileFinalSchema compiles the serialized final schema against the
+// metaschema of the draft named by its $schema to verify it is
+// structurally valid and that all internal $refs resolve. External refs are
+// loaded by the loader, e.g. stubbed via stubURLLoader.
+func compileFinalSchema(jsonStr []byte, loader jsonschema.URLLoader) error {
+	_, err := compileSchema(jsonStr, loader)
+	return err
+}
+
+// compileSchema compiles the serialized schema like compileFinalSchema and
+// returns the compiled schema, so it can be used to validate values.
+func compileSchema(jsonStr []byte, loader jsonschema.URLLoader) (*jsonschema.Schema, error) {
 	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(jsonStr))
 	if err != nil {
-		return fmt.Errorf("failed to parse generated schema: %w", err)
+		return nil, fmt.Errorf("failed to parse generated schema: %w", err)
 	}
 	c := jsonschema.NewCompiler()
-	c.UseLoader(stubURLLoader{})
+	c.UseLoader(loader)
 	if err := c.AddResource("values.schema.json", doc); err != nil {
-		return fmt.Errorf("failed to add generated schema: %w", err)
+		return nil, fmt.Errorf("failed to add generated schema: %w", err)
 	}
-	if _, err := c.Compile("values.schema.json"); err != nil {
-		return fmt.Errorf("generated schema is not valid: %w", err)
+	compiled, err := c.Compile("values.schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("generated schema is not valid: %w", err)
 	}
-	return nil
+	return compiled, nil
 }
 
-func exec(cmd *cobra.Command, _ []string) error {
-	configureLogging()
+// newReportChart returns the chart identification of a result used in reports.
+func newReportChart(result *schema.Result) report.Chart {
+	chart := report.Chart{ChartPath: result.ChartPath, ValuesPath: result.ValuesPath}
+	if result.Chart != nil {
+		chart.Name = result.Chart.Name
+	}
+	return chart
+}
 
-	var skipAutoGeneration, valueFileNames []string
-
-	chartSearchRoot := viper.GetString("chart-search-root")
-	dryRun := viper.GetBool("dry-run")
-	noDeps := viper.GetBool("no-dependencies")
-	addSchemaReference := viper.GetBool("add-schema-reference")
-	keepFullComment := viper.GetBool("keep-full-comment")
-	helmDocsCompatibilityMode := viper.GetBool("helm-docs-compatibility-mode")
-	uncomment := viper.GetBool("uncomment")
-	outFile := viper.GetString("output-file")
-	dontRemoveHelmDocsPrefix := viper.GetBool("dont-strip-helm-docs-prefix")
-	appendNewline := viper.GetBool("append-newline")
-	dependenciesFilter := viper.GetStringSlice("dependencies-filter")
-	dependenciesFilterMap := make(map[string]bool)
-	dontAddGlobal := viper.GetBool("dont-add-global")
-	skipDepsSchemaValidation := viper.GetBool("skip-dependencies-schema-validation")
-	allowCircularDeps := viper.GetBool("allow-circular-dependencies")
-	annotate := viper.GetBool("annotate")
-	keepExistingDepSchemas := viper.GetBool("keep-existing-dep-schemas")
-	check := viper.GetBool("check")
-	if check {
-		if dryRun {
-			return errors.New("--check cannot be combined with --dry-run")
-		}
-		if annotate {
-			return errors.New("--check cannot be combined with --annotate")
-		}
-		if addSchemaReference {
-			return errors.New("--check cannot be combined with --add-schema-reference")
-		}
+// newReportEntry creates a report entry for a problem of the given result.
+func newReportEntry(result *schema.Result, severity report.Severity, category string, err error) report.Entry {
+	return report.Entry{
+		Chart:    newReportChart(result),
+		Severity: severity,
+		Category: category,
+		Message:  err.Error(),
+		Line:     schema.ErrorLine(err),
+	}
+}
+
+// generatorOptions holds the settings that control how the schemas of all
+// charts below the search root are generated and merged.
+type generatorOptions struct {
+	chartSearchRoot           string
+	dryRun                    bool
+	noDeps                    bool
+	addSchemaReference        bool
+	keepFullComment           bool
+	helmDocsCompatibilityMode bool
+	uncomment                 bool
+	outFile                   string
+	dontRemoveHelmDocsPrefix  bool
+	dependenciesFilterMap     map[string]bool
+	dontAddGlobal             bool
+	skipDepsSchemaValidation  bool
+	allowCircularDeps         bool
+	annotate                  bool
+	migrateHelmDocs           bool
+	keepExistingDepSchemas    bool
+	valueFileNames            []string
+	skipConfig                *schema.SkipAutoGenerationConfig
+	annotateConfig            *schema.AnnotateConfig
+	// k8sCatalog resolves k8s:// refs, nil if no catalog is configured
+	k8sCatalog *schema.K8sCatalog
+	// remoteRefs resolves http(s) refs, nil if they are kept as they are
+	remoteRefs *schema.RemoteRefs
+	// draft is the JSON Schema draft of the written schemas
+	draft string
+	// report collects the outcome of the run, nil if no report was requested
+	report *report.Report
+}
+
+// newGeneratorOptions reads the generator settings from viper.
+func newGeneratorOptions() (*generatorOptions, error) {
+	var skipAutoGeneration []string
+
+	opts := &generatorOptions{
+		chartSearchRoot:           viper.GetString("chart-search-root"),
+		dryRun:                    viper.GetBool("dry-run"),
+		noDeps:                    viper.GetBool("no-dependencies"),
+		addSchemaReference:        viper.GetBool("add-schema-reference"),
+		keepFullComment:           viper.GetBool("keep-full-comment"),
+		helmDocsCompatibilityMode: viper.GetBool("helm-docs-compatibility-mode"),
+		uncomment:                 viper.GetBool("uncomment"),
+		outFile:                   viper.GetString("output-file"),
+		dontRemoveHelmDocsPrefix:  viper.GetBool("dont-strip-helm-docs-prefix"),
+		dependenciesFilterMap:     make(map[string]bool),
+		dontAddGlobal:             viper.GetBool("dont-add-global"),
+		skipDepsSchemaValidation:  viper.GetBool("skip-dependencies-schema-validation"),
+		allowCircularDeps:         viper.GetBool("allow-circular-dependencies"),
+		annotate:                  viper.GetBool("annotate"),
+		migrateHelmDocs:           viper.GetBool("migrate-helm-docs"),
+		keepExistingDepSchemas:    viper.GetBool("keep-existing-dep-schemas"),
+		draft:                     viper.GetString("draft"),
 	}
-	for _, dep := range dependenciesFilter {
-		dependenciesFilterMap[dep] = true
+	for _, dep := range viper.GetStringSlice("dependencies-filter") {
+		opts.dependenciesFilterMap[dep] = true
 	}
-	if err := viper.UnmarshalKey("value-files", &valueFileNames); err != nil {
-		return err
+	if err := viper.UnmarshalKey("value-files", &opts.valueFileNames); err != nil {
+		return nil, err
 	}
 	if err := viper.UnmarshalKey("skip-auto-generation", &skipAutoGeneration); err != nil {
-		return err
+		return nil, err
 	}
-	workersCount := runtime.NumCPU() * 2
 
 	skipConfig, err := schema.NewSkipAutoGenerationConfig(skipAutoGeneration)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	opts.skipConfig = skipConfig
+
+	annotateConfig, err := schema.NewAnnotateConfig(viper.GetStringSlice("annotate-keywords"))
+	if err != nil {
+		return nil, err
+	}
+	opts.annotateConfig = annotateConfig
+
+	if catalogDir := viper.GetString("k8s-catalog"); catalogDir != "" {
+		k8sCatalog, err := schema.NewK8sCatalog(catalogDir, viper.GetString("k8s-version"))
+		if err != nil {
+			return nil, err
+		}
+		opts.k8sCatalog = k8sCatalog
+	}
+
+	offline, bundle := viper.GetBool("offline"), viper.GetBool("bundle")
+	if viper.GetBool("remote-refs") || offline || bundle {
+		remoteRefs, err := schema.NewRemoteRefs(viper.GetString("ref-cache-dir"), offline, bundle)
+		if err != nil {
+			return nil, err
+		}
+		opts.remoteRefs = remoteRefs
+	}
+
+	if opts.draft == "" {
+		opts.draft = schema.Draft7
+	}
+	if err := schema.ValidateDraft(opts.draft); err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+// disableWrites turns off every option which modifies the files of the charts.
+// Used by subcommands which only need the generated schemas in memory.
+func (opts *generatorOptions) disableWrites() {
+	opts.addSchemaReference = false
+	opts.annotate = false
+	opts.migrateHelmDocs = false
+}
+
+// schemaPath returns the path of the schema file of a chart, which may be
+// set by the config file of the chart
+func (opts *generatorOptions) schemaPath(result *schema.Result) string {
+	outFile := result.OutFile
+	if outFile == "" {
+		outFile = opts.outFile
 	}
+	return filepath.Join(filepath.Dir(result.ChartPath), outFile)
+}
 
+// collectResults searches the chart search root for charts and runs the schema
+// workers on them. The returned cleanup function removes the temporary
+// directory used for extracted chart archives and must be called once the
+// results are no longer needed.
+func collectResults(opts *generatorOptions) ([]*schema.Result, func()) {
 	queue := make(chan string)
-	resultsChan := make(chan schema.Result)
-	results := []*schema.Result{}
 	errs := make(chan error, 100) // Buffered to prevent deadlock when errors occur before goroutines start
-	done := make(chan struct{})
 
-	tempDir := searching.SearchArchivesOpenTemp(chartSearchRoot, errs)
+	cleanup := func() {}
+	tempDir := searching.SearchArchivesOpenTemp(opts.chartSearchRoot, errs)
 	if tempDir != "" {
-		defer os.RemoveAll(tempDir)
+		cleanup = func() { os.RemoveAll(tempDir) }
 	}
 
-	go searching.SearchFiles(chartSearchRoot, chartSearchRoot, "Chart.yaml", dependenciesFilterMap, queue, errs)
+	go searching.SearchFiles(opts.chartSearchRoot, opts.chartSearchRoot, "Chart.yaml", opts.dependenciesFilterMap, queue, errs)
+
+	return runWorkers(opts, queue, errs), cleanup
+}
+
+// runWorkers generates the schemas of the charts sent to queue until it is
+// closed. Errors sent to errs are logged.
+func runWorkers(opts *generatorOptions, queue <-chan string, errs <-chan error) []*schema.Result {
+	workersCount := runtime.NumCPU() * 2
+
+	resultsChan := make(chan schema.Result)
+	results := []*schema.Result{}
+	done := make(chan struct{})
 
 	wg := sync.WaitGroup{}
 
@@ -310,21 +452,37 @@ func exec(cmd *cobra.Command, _ []string) error {
 
 		go func() {
 			defer wg.Done()
-			schema.Worker(
-				dryRun,
-				uncomment,
-				addSchemaReference,
-				keepFullComment,
-				helmDocsCompatibilityMode,
-				dontRemoveHelmDocsPrefix,
-				dontAddGlobal,
-				annotate,
-				valueFileNames,
-				skipConfig,
-				outFile,
-				queue,
-				resultsChan,
-			)
+			for chartPath := range queue {
+				chartOpts, err := opts.forChart(chartPath)
+				if err != nil {
+					resultsChan <- schema.Result{ChartPath: chartPath, Errors: []error{err}}
+					continue
+				}
+
+				// Every chart may have its own options
+				chartQueue := make(chan string, 1)
+				chartQueue <- chartPath
+				close(chartQueue)
+				schema.Worker(
+					chartOpts.dryRun,
+					chartOpts.uncomment,
+					chartOpts.addSchemaReference,
+					chartOpts.keepFullComment,
+					chartOpts.helmDocsCompatibilityMode,
+					chartOpts.dontRemoveHelmDocsPrefix,
+					chartOpts.dontAddGlobal,
+					chartOpts.annotate,
+					chartOpts.migrateHelmDocs,
+					chartOpts.valueFileNames,
+					chartOpts.skipConfig,
+					chartOpts.annotateConfig,
+					chartOpts.k8sCatalog,
+					chartOpts.remoteRefs,
+					chartOpts.outFile,
+					chartQueue,
+					resultsChan,
+				)
+			}
 		}()
 	}
 
@@ -342,6 +500,7 @@ func exec(cmd *cobra.Command, _ []string) error {
 		case err, ok := <-errs:
 			if ok {
 				log.Error(err)
+				opts.report.Add(report.Entry{Severity: report.SeverityWarning, Category: report.CategorySearch, Message: err.Error()})
 			}
 		case res, ok := <-resultsChan:
 			if !ok {
@@ -359,42 +518,76 @@ drainErrors:
 		case err, ok := <-errs:
 			if ok {
 				log.Error(err)
+				opts.report.Add(report.Entry{Severity: report.SeverityWarning, Category: report.CategorySearch, Message: err.Error()})
 			}
 		default:
 			break drainErrors
 		}
 	}
 
-	// In annotate mode, just report errors and return (no schema generation)
-	if annotate {
-		foundErrors := false
-		for _, result := range results {
-			if len(result.Errors) > 0 {
-				foundErrors = true
-				if result.Chart != nil {
-					log.Errorf("Found %d errors while annotating chart %s (%s)", len(result.Errors), result.Chart.Name, result.ChartPath)
-				} else {
-					log.Errorf("Found %d errors while annotating chart %s", len(result.Errors), result.ChartPath)
-				}
-				for _, err := range result.Errors {
-					log.Error(err)
-				}
-			}
+	return results
+}
+
+// findChartResult generates the schemas of all charts below the search root
+// and returns the final schema of the chart given by its name or directory.
+// Errors of other charts are logged, but don't fail the lookup.
+func findChartResult(opts *generatorOptions, chartName string) (*schema.Result, error) {
+	chartDir, err := filepath.Abs(chartName)
+	if err != nil {
+		return nil, err
+	}
+	matches := func(result *schema.Result) bool {
+		if result.Chart != nil && result.Chart.Name == chartName {
+			return true
 		}
-		if foundErrors {
-			return errors.New("some errors were found")
+		resultDir, err := filepath.Abs(filepath.Dir(result.ChartPath))
+		return err == nil && resultDir == chartDir
+	}
+
+	results, cleanup := collectResults(opts)
+	defer cleanup()
+
+	var found *schema.Result
+	if _, err := finalizeSchemas(opts, results, func(result *schema.Result) bool {
+		if found == nil && matches(result) {
+			found = result
 		}
-		return nil
+		return true
+	}); err != nil {
+		return nil, err
 	}
+	if found != nil {
+		return found, nil
+	}
+	for _, result := range results {
+		if len(result.Errors) > 0 && matches(result) {
+			return nil, fmt.Errorf("failed to generate the schema of chart %s", chartName)
+		}
+	}
+	return nil, fmt.Errorf("chart %s not found below %s", chartName, opts.chartSearchRoot)
+}
+
+// finalizeSchemas sorts the results topologically, merges every dependency
+// schema into its parents and calls handle with each chart whose final schema
+// is ready. Returning false from handle marks the run as failed. The returned
+// bool reports whether any errors were found; the error is only set for
+// failures that abort the whole run.
+func finalizeSchemas(opts *generatorOptions, results []*schema.Result, handle func(result *schema.Result) bool) (bool, error) {
+	var err error
+
+	noDeps := opts.noDeps
+	dependenciesFilterMap := opts.dependenciesFilterMap
 
 	if !noDeps {
-		results, err = schema.TopoSort(results, allowCircularDeps)
+		results, err = schema.TopoSort(results, opts.allowCircularDeps)
 		if err != nil {
 			if _, ok := err.(*schema.CircularError); ok {
 				log.Errorf("Error while sorting results: %s", err)
-				return err
+				opts.report.Add(report.Entry{Severity: report.SeverityError, Category: report.CategoryDependency, Message: err.Error()})
+				return true, err
 			} else {
 				log.Warnf("Could not sort results: %s", err)
+				opts.report.Add(report.Entry{Severity: report.SeverityWarning, Category: report.CategoryDependency, Message: err.Error()})
 			}
 		}
 	}
@@ -416,7 +609,7 @@ drainErrors:
 	// using the worker-generated schema from values.yaml. Opt-in via
 	// --keep-existing-dep-schemas; default is to regenerate every discovered
 	// chart's schema.
-	if !noDeps && keepExistingDepSchemas {
+	if !noDeps && opts.keepExistingDepSchemas {
 		for _, result := range results {
 			if result.Chart == nil || len(result.Errors) > 0 {
 				continue
@@ -424,14 +617,14 @@ drainErrors:
 			if !isDependencyChart[result.Chart.Name] {
 				continue
 			}
-			schemaPath := filepath.Join(filepath.Dir(result.ChartPath), outFile)
+			schemaPath := opts.schemaPath(result)
 			schemaData, err := os.ReadFile(schemaPath)
 			if err != nil {
 				continue
 			}
 			var existingSchema schema.Schema
 			if err := json.Unmarshal(schemaData, &existingSchema); err != nil {
-				log.Warnf("Found existing %s for dependency %s but failed to parse it: %s", outFile, result.Chart.Name, err)
+				log.Warnf("Found existing %s for dependency %s but failed to parse it: %s", schemaPath, result.Chart.Name, err)
 				continue
 			}
 			log.Debugf("Using pre-existing schema for dependency chart %s", result.Chart.Name)
@@ -463,9 +656,10 @@ drainErrors:
 
 	chartNameToResult := make(map[string]*schema.Result)
 	foundErrors := false
-	staleFound := false
 
 	for _, result := range results {
+		opts.report.AddChart(newReportChart(result))
+
 		if len(result.Errors) > 0 {
 			foundErrors = true
 			if result.Chart != nil {
@@ -480,6 +674,7 @@ drainErrors:
 			}
 			for _, err := range result.Errors {
 				log.Error(err)
+				opts.report.Add(newReportEntry(result, report.SeverityError, report.CategoryValues, err))
 			}
 			continue
 		}
@@ -550,10 +745,18 @@ drainErrors:
 							dependencyResult.ChartPath,
 						)
 
+						depKey := dep.Name
+						if dep.Alias != "" {
+							depKey = dep.Alias
+						}
+						// The copy refers to the definitions of the dependency by their
+						// names in the parent, so they don't collide with the parent's
+						dependencySchema := dependencyResult.Schema.Namespaced(depKey, result.Schema.Definitions)
+
 						// Process import-values first (before regular dependency nesting)
 						importedProps := processImportValues(
 							&result.Schema,
-							&dependencyResult.Schema,
+							dependencySchema,
 							dep,
 							result.Chart.Name,
 						)
@@ -565,22 +768,22 @@ drainErrors:
 							log.Debugf("Merging library chart %s properties into parent chart %s at top level", dep.Name, result.Chart.Name)
 							mergeSchemaProperties(
 								&result.Schema,
-								&dependencyResult.Schema,
+								dependencySchema,
 								importedProps,
 								fmt.Sprintf("library chart %s", dep.Name),
 								fmt.Sprintf("parent chart %s", result.Chart.Name),
 							)
+							addDependencyDefinitions(&result.Schema, dependencySchema)
 						} else if !hasImportValues {
 							// For non-library charts WITHOUT import-values, nest under dependency name
 							// (If import-values is used, user explicitly controls what's imported)
-							// depSchema shares dependencyResult's Properties map; DisableRequiredProperties
-							// mutates it. Safe only because TopoSort writes each dependency's own schema
-							// file before any parent reaches this point, and the mutation is idempotent.
+							// The definitions are hoisted to the root of the parent below
 							depSchema := schema.Schema{
 								Type:        []string{"object"},
 								Title:       dep.Name,
 								Description: dependencyResult.Chart.Description,
-								Properties:  dependencyResult.Schema.Properties,
+								Properties:  dependencySchema.Properties,
+								Definitions: dependencySchema.Definitions,
 							}
 							if dep.Condition != "" && !strings.Contains(dep.Condition, ".") {
 								depSchema.Type = []string{"object", "boolean"}
@@ -590,24 +793,26 @@ drainErrors:
 							if result.Schema.Properties == nil {
 								result.Schema.Properties = make(map[string]*schema.Schema)
 							}
-							if dep.Alias != "" {
-								result.Schema.Properties[dep.Alias] = &depSchema
-							} else {
-								result.Schema.Properties[dep.Name] = &depSchema
-							}
+							result.Schema.Properties[depKey] = &depSchema
+						} else {
+							addDependencyDefinitions(&result.Schema, dependencySchema)
 						}
 
 					} else {
 						log.Warnf("Dependency (%s->%s) specified but no schema found. If you want to create jsonschemas for external dependencies, you need to run helm dep up", result.Chart.Name, dep.Name)
+						opts.report.Add(newReportEntry(result, report.SeverityWarning, report.CategoryDependency,
+							fmt.Errorf("dependency %s specified but no schema found, run helm dep up to include external dependencies", dep.Name)))
 					}
 				} else {
 					log.Warnf("Dependency without name found (checkout %s).", result.ChartPath)
+					opts.report.Add(newReportEntry(result, report.SeverityWarning, report.CategoryDependency,
+						errors.New("dependency without name found")))
 				}
 			}
 		}
 
 		// Handle skip-dependencies-schema-validation flag
-		if skipDepsSchemaValidation && !noDeps {
+		if opts.skipDepsSchemaValidation && !noDeps {
 			// Collect dependency names using helper function
 			depNames := getDependencyNames(result.Chart.Dependencies, dependenciesFilterMap)
 
@@ -634,40 +839,205 @@ drainErrors:
 		// Hoist all nested definitions to the root level so $ref pointers resolve correctly
 		result.Schema.HoistDefinitions()
 
+		if !handle(result) {
+			foundErrors = true
+		}
+	}
+
+	return foundErrors, nil
+}
+
+func exec(cmd *cobra.Command, _ []string) error {
+	configureLogging()
+
+	appendNewline := viper.GetBool("append-newline")
+	check := viper.GetBool("check")
+	showDiff := viper.GetBool("show-diff")
+	watch := viper.GetBool("watch")
+	reportFormat := viper.GetString("report-format")
+	reportFile := viper.GetString("report-file")
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+	if check {
+		if opts.dryRun {
+			return errors.New("--check cannot be combined with --dry-run")
+		}
+		if opts.annotate {
+			return errors.New("--check cannot be combined with --annotate")
+		}
+		if opts.migrateHelmDocs {
+			return errors.New("--check cannot be combined with --migrate-helm-docs")
+		}
+		if opts.addSchemaReference {
+			return errors.New("--check cannot be combined with --add-schema-reference")
+		}
+	}
+	if watch {
+		if check {
+			return errors.New("--watch cannot be combined with --check")
+		}
+		if opts.annotate {
+			return errors.New("--watch cannot be combined with --annotate")
+		}
+		if opts.migrateHelmDocs {
+			return errors.New("--watch cannot be combined with --migrate-helm-docs")
+		}
+		if reportFormat != "" {
+			return errors.New("--watch cannot be combined with --report-format")
+		}
+	}
+	if reportFormat != "" {
+		if err := report.ValidateFormat(reportFormat); err != nil {
+			return err
+		}
+		opts.report = report.New(version)
+	} else if reportFile != "" {
+		return errors.New("--report-file requires --report-format")
+	}
+
+	err = generate(opts, appendNewline, check, showDiff)
+
+	if watch {
+		// Errors are fixed while watching, so they don't end the session
+		if err != nil {
+			log.Error(err)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return watchCharts(ctx, opts, appendNewline)
+	}
+
+	if opts.report != nil {
+		if reportErr := writeReport(opts.report, reportFormat, reportFile); reportErr != nil {
+			log.Errorf("Failed to write report: %s", reportErr)
+			if err == nil {
+				err = reportErr
+			}
+		}
+	}
+
+	return err
+}
+
+// writeReport writes the report to the given file, or to stdout if no file is set.
+func writeReport(r *report.Report, format, file string) error {
+	if file == "" {
+		return r.Write(os.Stdout, format)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := r.Write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// generate generates the schemas of all charts and writes, prints or checks them.
+func generate(opts *generatorOptions, appendNewline, check, showDiff bool) error {
+	results, cleanup := collectResults(opts)
+	defer cleanup()
+
+	// In annotate and migrate modes, just report errors and return (no schema generation)
+	if opts.annotate || opts.migrateHelmDocs {
+		action := "annotating"
+		if !opts.annotate {
+			action = "migrating"
+		}
+		foundErrors := false
+		for _, result := range results {
+			if len(result.Errors) > 0 {
+				foundErrors = true
+				if result.Chart != nil {
+					log.Errorf("Found %d errors while %s chart %s (%s)", len(result.Errors), action, result.Chart.Name, result.ChartPath)
+				} else {
+					log.Errorf("Found %d errors while %s chart %s", len(result.Errors), action, result.ChartPath)
+				}
+				for _, err := range result.Errors {
+					log.Error(err)
+					opts.report.Add(newReportEntry(result, report.SeverityError, report.CategoryValues, err))
+				}
+			}
+		}
+		if foundErrors {
+			return errors.New("some errors were found")
+		}
+		return nil
+	}
+
+	staleFound := false
+	foundErrors, err := finalizeSchemas(opts, results, newOutputHandler(opts, appendNewline, check, showDiff, &staleFound))
+	if err != nil {
+		return err
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	if staleFound {
+		return errors.New("schema files are not up-to-date, run helm-schema to regenerate")
+	}
+	return nil
+}
+
+// newOutputHandler returns the finalizeSchemas callback which writes or prints
+// the schema of a chart. With check, the schema is compared with the existing
+// file instead and staleFound is set if they differ.
+func newOutputHandler(opts *generatorOptions, appendNewline, check, showDiff bool, staleFound *bool) func(result *schema.Result) bool {
+	return func(result *schema.Result) bool {
 		// Skip writing output for dependency charts with pre-existing schema files
 		if result.PreExistingSchema {
 			log.Debugf("Skipping output for dependency chart %s: using pre-existing schema", result.Chart.Name)
-			continue
+			return true
 		}
 
-		jsonStr, err := result.Schema.ToJson()
+		jsonStr, err := result.Schema.ToJsonForDraft(opts.draft)
 		if err != nil {
 			log.Errorf("Failed to serialize schema for chart %s: %s", result.Chart.Name, err)
-			foundErrors = true
-			continue
+			opts.report.Add(newReportEntry(result, report.SeverityError, report.CategoryOutput, err))
+			return false
 		}
 
 		if appendNewline {
 			jsonStr = append(jsonStr, '\n')
 		}
 
-		// Compile the final merged schema against Draft 7 to catch structurally
-		// invalid output and broken internal $refs. External refs are stubbed so
-		// compilation stays hermetic.
-		if err := compileFinalSchema(jsonStr); err != nil {
+		// Compile the final merged schema against its draft to catch structurally
+		// invalid output and broken internal $refs. External refs are loaded by
+		// opts.urlLoader(), so they may be downloaded if remote refs are resolved
+		// and are stubbed otherwise.
+		if err := compileFinalSchema(jsonStr, opts.urlLoader()); err != nil {
 			log.Errorf("Generated schema for chart %s is invalid: %s", result.Chart.Name, err)
-			foundErrors = true
-			continue
+			opts.report.Add(newReportEntry(result, report.SeverityError, report.CategoryInvalidSchema, err))
+			return false
 		}
 
+		schemaPath := opts.schemaPath(result)
 		if check {
-			chartBasePath := filepath.Dir(result.ChartPath)
-			existing, err := os.ReadFile(filepath.Join(chartBasePath, outFile))
+			existing, err := os.ReadFile(schemaPath)
 			if err != nil || !bytes.Equal(existing, jsonStr) {
-				log.Errorf("Schema for chart %s is stale (or missing): %s", result.Chart.Name, filepath.Join(chartBasePath, outFile))
-				staleFound = true
+				log.Errorf("Schema for chart %s is stale (or missing): %s", result.Chart.Name, schemaPath)
+				opts.report.Add(newReportEntry(result, report.SeverityError, report.CategoryStaleSchema,
+					fmt.Errorf("schema file %s is stale (or missing), run helm-schema to regenerate", schemaPath)))
+				*staleFound = true
+
+				if showDiff && err == nil {
+					changes, err := diffWithExistingSchema(jsonStr, schemaPath)
+					if err != nil {
+						log.Errorf("Failed to diff schema of chart %s: %s", result.Chart.Name, err)
+					} else if len(changes) == 0 {
+						log.Errorf("Schema for chart %s only differs in formatting", result.Chart.Name)
+					} else {
+						printSchemaDiff(os.Stdout, result.Chart.Name, schemaPath, changes)
+					}
+				}
 			}
-		} else if dryRun {
+		} else if opts.dryRun {
 			log.Infof("Printing jsonschema for %s chart (%s)", result.Chart.Name, result.ChartPath)
 			if appendNewline {
 				fmt.Printf("%s", jsonStr)
@@ -675,21 +1045,14 @@ drainErrors:
 				fmt.Printf("%s\n", jsonStr)
 			}
 		} else {
-			chartBasePath := filepath.Dir(result.ChartPath)
-			if err := os.WriteFile(filepath.Join(chartBasePath, outFile), jsonStr, 0o644); err != nil {
-				log.Errorf("Failed to write %s for chart %s: %s", outFile, result.Chart.Name, err)
-				foundErrors = true
-				continue
+			if err := os.WriteFile(schemaPath, jsonStr, 0o644); err != nil {
+				log.Errorf("Failed to write %s for chart %s: %s", schemaPath, result.Chart.Name, err)
+				opts.report.Add(newReportEntry(result, report.SeverityError, report.CategoryOutput, err))
+				return false
 			}
 		}
+		return true
 	}
-	if foundErrors {
-		return errors.New("some errors were found")
-	}
-	if staleFound {
-		return errors.New("schema files are not up-to-date, run helm-schema to regenerate")
-	}
-	return nil
 }
 
 func main() {
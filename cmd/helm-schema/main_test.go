@@ -2,10 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/santhosh-tekuri/jsonschema/v6"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
@@ -115,6 +119,8 @@ root: value
 	viper.Set("skip-dependencies-schema-validation", false)
 	viper.Set("allow-circular-dependencies", false)
 	viper.Set("annotate", false)
+	viper.Set("annotate-keywords", []string{"minimal"})
+	viper.Set("migrate-helm-docs", false)
 	viper.Set("keep-existing-dep-schemas", false)
 	viper.Set("value-files", []string{"values.yaml"})
 	viper.Set("skip-auto-generation", []string{})
@@ -216,6 +222,8 @@ root: value
 	viper.Set("skip-dependencies-schema-validation", false)
 	viper.Set("allow-circular-dependencies", false)
 	viper.Set("annotate", false)
+	viper.Set("annotate-keywords", []string{"minimal"})
+	viper.Set("migrate-helm-docs", false)
 	viper.Set("keep-existing-dep-schemas", false)
 	viper.Set("value-files", []string{"values.yaml"})
 	viper.Set("skip-auto-generation", []string{})
@@ -304,6 +312,8 @@ root: value
 	viper.Set("skip-dependencies-schema-validation", false)
 	viper.Set("allow-circular-dependencies", false)
 	viper.Set("annotate", false)
+	viper.Set("annotate-keywords", []string{"minimal"})
+	viper.Set("migrate-helm-docs", false)
 	viper.Set("keep-existing-dep-schemas", false)
 	viper.Set("value-files", []string{"values.yaml"})
 	viper.Set("skip-auto-generation", []string{})
@@ -494,6 +504,8 @@ root: value
 	viper.Set("skip-dependencies-schema-validation", false)
 	viper.Set("allow-circular-dependencies", false)
 	viper.Set("annotate", false)
+	viper.Set("annotate-keywords", []string{"minimal"})
+	viper.Set("migrate-helm-docs", false)
 	viper.Set("keep-existing-dep-schemas", false)
 	viper.Set("value-files", []string{"values.yaml"})
 	viper.Set("skip-auto-generation", []string{})
@@ -557,6 +569,8 @@ inside: 2
 	viper.Set("skip-dependencies-schema-validation", false)
 	viper.Set("allow-circular-dependencies", false)
 	viper.Set("annotate", false)
+	viper.Set("annotate-keywords", []string{"minimal"})
+	viper.Set("migrate-helm-docs", false)
 	viper.Set("keep-existing-dep-schemas", false)
 	viper.Set("value-files", []string{"values.yaml"})
 	viper.Set("skip-auto-generation", []string{})
@@ -593,9 +607,12 @@ func setStandardViper(tmpDir string) {
 	viper.Set("skip-dependencies-schema-validation", false)
 	viper.Set("allow-circular-dependencies", false)
 	viper.Set("annotate", false)
+	viper.Set("annotate-keywords", []string{"minimal"})
+	viper.Set("migrate-helm-docs", false)
 	viper.Set("keep-existing-dep-schemas", false)
 	viper.Set("value-files", []string{"values.yaml"})
 	viper.Set("skip-auto-generation", []string{})
+	viper.Set("draft", "draft-07")
 	viper.Set("log-level", "info")
 }
 
@@ -752,7 +769,7 @@ func TestCompileFinalSchema(t *testing.T) {
 			"ext": {"$ref": "https://example.com/schemas/thing.json"}
 		}
 	}`)
-	assert.NoError(t, compileFinalSchema(valid), "valid schema with external ref must compile")
+	assert.NoError(t, compileFinalSchema(valid, stubURLLoader{}), "valid schema with external ref must compile")
 
 	// Dangling internal $ref must fail compilation.
 	dangling := []byte(`{
@@ -762,5 +779,397 @@ func TestCompileFinalSchema(t *testing.T) {
 			"broken": {"$ref": "#/definitions/doesNotExist"}
 		}
 	}`)
-	assert.Error(t, compileFinalSchema(dangling), "dangling internal $ref must fail compilation")
+	assert.Error(t, compileFinalSchema(dangling, stubURLLoader{}), "dangling internal $ref must fail compilation")
+}
+
+func TestExec_ReportFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("chart/Chart.yaml", `
+apiVersion: v2
+name: chart
+version: 1.0.0
+`)
+	writeFile("chart/values.yaml", `
+key: value
+`)
+
+	reportPath := filepath.Join(tmpDir, "report.json")
+	setStandardViper(tmpDir)
+	viper.Set("check", true)
+	viper.Set("report-format", "json")
+	viper.Set("report-file", reportPath)
+
+	// The schema file doesn't exist yet, so the check fails
+	err := exec(nil, nil)
+	assert.Error(t, err)
+
+	content, err := os.ReadFile(reportPath)
+	assert.NoError(t, err)
+
+	var decoded struct {
+		Success bool `json:"success"`
+		Charts  []struct {
+			Name string `json:"name"`
+		} `json:"charts"`
+		Entries []struct {
+			Name     string `json:"name"`
+			Severity string `json:"severity"`
+			Category string `json:"category"`
+		} `json:"entries"`
+	}
+	assert.NoError(t, json.Unmarshal(content, &decoded))
+	assert.False(t, decoded.Success)
+	if assert.Len(t, decoded.Charts, 1) {
+		assert.Equal(t, "chart", decoded.Charts[0].Name)
+	}
+	if assert.Len(t, decoded.Entries, 1) {
+		assert.Equal(t, "chart", decoded.Entries[0].Name)
+		assert.Equal(t, "error", decoded.Entries[0].Severity)
+		assert.Equal(t, "stale-schema", decoded.Entries[0].Category)
+	}
+}
+
+func TestExec_ReportFileRequiresFormat(t *testing.T) {
+	setStandardViper(t.TempDir())
+	viper.Set("report-file", "report.json")
+
+	err := exec(nil, nil)
+	assert.ErrorContains(t, err, "--report-file requires --report-format")
+}
+
+func TestExec_MigrateHelmDocs(t *testing.T) {
+	tmpDir := t.TempDir()
+	setStandardViper(tmpDir)
+	viper.Set("migrate-helm-docs", true)
+	viper.Set("annotate", true)
+
+	err := os.MkdirAll(filepath.Join(tmpDir, "chart"), 0o755)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(tmpDir, "chart", "Chart.yaml"), []byte("apiVersion: v2\nname: chart\nversion: 1.0.0\n"), 0o644)
+	assert.NoError(t, err)
+	valuesPath := filepath.Join(tmpDir, "chart", "values.yaml")
+	err = os.WriteFile(valuesPath, []byte("# -- (int) Number of replicas\nreplicas: 1\nname: x\n"), 0o644)
+	assert.NoError(t, err)
+
+	err = exec(nil, nil)
+	assert.NoError(t, err)
+
+	migrated, err := os.ReadFile(valuesPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "# @schema\n# type: integer\n# description: Number of replicas\n# @schema\nreplicas: 1\n# @schema\n# type: string\n# @schema\nname: x\n", string(migrated))
+
+	_, err = os.Stat(filepath.Join(tmpDir, "chart", "values.schema.json"))
+	assert.True(t, os.IsNotExist(err), "no schema must be generated")
+}
+
+func TestExec_AnnotateKeywords(t *testing.T) {
+	tmpDir := t.TempDir()
+	setStandardViper(tmpDir)
+	viper.Set("annotate", true)
+	viper.Set("annotate-keywords", []string{"type", "required"})
+
+	err := os.MkdirAll(filepath.Join(tmpDir, "chart"), 0o755)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(tmpDir, "chart", "Chart.yaml"), []byte("apiVersion: v2\nname: chart\nversion: 1.0.0\n"), 0o644)
+	assert.NoError(t, err)
+	valuesPath := filepath.Join(tmpDir, "chart", "values.yaml")
+	err = os.WriteFile(valuesPath, []byte("replicas: 1\n"), 0o644)
+	assert.NoError(t, err)
+
+	err = exec(nil, nil)
+	assert.NoError(t, err)
+
+	annotated, err := os.ReadFile(valuesPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "# @schema\n# type: integer\n# required: true\n# @schema\nreplicas: 1\n", string(annotated))
+
+	viper.Set("annotate-keywords", []string{"pattern"})
+	err = exec(nil, nil)
+	assert.ErrorContains(t, err, "unsupported annotate keywords 'pattern'")
+}
+
+func TestExec_Draft(t *testing.T) {
+	tmpDir := t.TempDir()
+	setStandardViper(tmpDir)
+	viper.Set("draft", "2020-12")
+
+	err := os.MkdirAll(filepath.Join(tmpDir, "chart"), 0o755)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(tmpDir, "chart", "Chart.yaml"), []byte("apiVersion: v2\nname: chart\nversion: 1.0.0\n"), 0o644)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(tmpDir, "chart", "values.yaml"), []byte(`# @schema
+# type: object
+# dependencies:
+#   tls: [cert]
+# definitions:
+#   port:
+#     type: integer
+# @schema
+server:
+  # @schema
+  # $ref: "#/definitions/port"
+  # @schema
+  port: 80
+  tls: false
+  cert: ""
+# @schema
+# type: array
+# prefixItems:
+#   - type: string
+# additionalItems: false
+# @schema
+args: []
+`), 0o644)
+	assert.NoError(t, err)
+
+	err = exec(nil, nil)
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "chart", "values.schema.json"))
+	assert.NoError(t, err)
+	var written map[string]any
+	assert.NoError(t, json.Unmarshal(content, &written))
+	assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", written["$schema"])
+	assert.Contains(t, written["$defs"], "port")
+
+	properties := written["properties"].(map[string]any)
+	server := properties["server"].(map[string]any)
+	assert.Equal(t, map[string]any{"tls": []any{"cert"}}, server["dependentRequired"])
+	assert.NotContains(t, server, "dependencies")
+	port := server["properties"].(map[string]any)["port"].(map[string]any)
+	assert.Equal(t, "#/$defs/port", port["$ref"])
+
+	args := properties["args"].(map[string]any)
+	assert.Equal(t, false, args["items"])
+	assert.NotContains(t, args, "additionalItems")
+
+	viper.Set("draft", "draft-04")
+	err = exec(nil, nil)
+	assert.ErrorContains(t, err, "unsupported draft draft-04")
+}
+
+func TestFindChartResult_IgnoresOtherCharts(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("chart/Chart.yaml", `
+apiVersion: v2
+name: chart
+version: 1.0.0
+`)
+	writeFile("chart/values.yaml", `
+key: value
+`)
+	writeFile("broken/Chart.yaml", `
+apiVersion: v2
+name: broken
+version: 1.0.0
+`)
+	writeFile("broken/values.yaml", `
+# @schema
+# type: [
+# @schema
+key: value
+`)
+
+	setStandardViper(tmpDir)
+	opts, err := newGeneratorOptions()
+	assert.NoError(t, err)
+	opts.disableWrites()
+
+	result, err := findChartResult(opts, "chart")
+	assert.NoError(t, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, "chart", result.Chart.Name)
+		assert.Contains(t, result.Schema.Properties, "key")
+	}
+
+	_, err = findChartResult(opts, filepath.Join(tmpDir, "broken"))
+	assert.EqualError(t, err, "failed to generate the schema of chart "+filepath.Join(tmpDir, "broken"))
+
+	_, err = findChartResult(opts, "other")
+	assert.EqualError(t, err, "chart other not found below "+tmpDir)
+}
+
+// writeDependencyCharts writes a parent chart with the dependency child,
+// the files are written below the directory of the child
+func writeDependencyCharts(t *testing.T, tmpDir string, childFiles map[string]string) {
+	t.Helper()
+	files := map[string]string{
+		"parent/Chart.yaml": `
+apiVersion: v2
+name: parent
+version: 1.0.0
+dependencies:
+  - name: child
+    version: 1.0.0
+`,
+		"parent/values.yaml": "replicas: 1\n",
+		"parent/charts/child/Chart.yaml": `
+apiVersion: v2
+name: child
+version: 1.0.0
+`,
+	}
+	for name, content := range childFiles {
+		files[filepath.Join("parent/charts/child", name)] = content
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+// compileParentSchema compiles the generated schema of the parent chart
+func compileParentSchema(t *testing.T, tmpDir string) (map[string]interface{}, *jsonschema.Schema) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(tmpDir, "parent", "values.schema.json"))
+	assert.NoError(t, err)
+	var document map[string]interface{}
+	assert.NoError(t, json.Unmarshal(content, &document))
+	compiled, err := compileSchema(content, stubURLLoader{})
+	assert.NoError(t, err)
+	return document, compiled
+}
+
+func TestExec_DependencyDefinitions(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeDependencyCharts(t, tmpDir, map[string]string{
+		"values.yaml": `
+# @schema
+# $ref: ./image.json
+# @schema
+image: {}
+# @schema
+# $ref: ./image.json
+# @schema
+sidecar: {}
+`,
+		"image.json": `{"type": "object", "properties": {"tag": {"type": "string"}}}`,
+	})
+
+	setStandardViper(tmpDir)
+	assert.NoError(t, exec(nil, nil))
+
+	// The shared target of the dependency is a definition of the parent
+	document, compiled := compileParentSchema(t, tmpDir)
+	if compiled == nil {
+		return
+	}
+	assert.Contains(t, document["definitions"], "child.image.json")
+	child := document["properties"].(map[string]interface{})["child"].(map[string]interface{})
+	image := child["properties"].(map[string]interface{})["image"].(map[string]interface{})
+	assert.Equal(t, "#/definitions/child.image.json", image["$ref"])
+
+	assert.NoError(t, compiled.Validate(map[string]interface{}{
+		"replicas": 1,
+		"child":    map[string]interface{}{"image": map[string]interface{}{"tag": "1.0"}},
+	}))
+	assert.Error(t, compiled.Validate(map[string]interface{}{
+		"replicas": 1,
+		"child":    map[string]interface{}{"sidecar": map[string]interface{}{"tag": 1}},
+	}))
+
+	// The schema of the dependency itself is unchanged
+	content, err := os.ReadFile(filepath.Join(tmpDir, "parent", "charts", "child", "values.schema.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"$ref": "#/definitions/image.json"`)
+}
+
+func TestExec_DependencyK8sRefs(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeDependencyCharts(t, tmpDir, map[string]string{
+		"values.yaml": `
+# @schema
+# $ref: k8s://io.k8s.api.core.v1.ResourceRequirements
+# @schema
+resources: {}
+`,
+	})
+	catalogDir := filepath.Join(t.TempDir(), "v1.29.0")
+	assert.NoError(t, os.MkdirAll(catalogDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(catalogDir, "swagger.json"), []byte(`{"definitions": {
+		"io.k8s.api.core.v1.ResourceRequirements": {"type": "object", "properties": {
+			"limits": {"type": "object", "additionalProperties": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.api.resource.Quantity"}}
+		}},
+		"io.k8s.apimachinery.pkg.api.resource.Quantity": {"type": "string", "format": "int-or-string"}
+	}}`), 0o644))
+
+	setStandardViper(tmpDir)
+	viper.Set("k8s-catalog", filepath.Dir(catalogDir))
+	assert.NoError(t, exec(nil, nil))
+
+	// The kubernetes definitions of the dependency are definitions of the parent
+	document, compiled := compileParentSchema(t, tmpDir)
+	if compiled == nil {
+		return
+	}
+	assert.Contains(t, document["definitions"], "child.io.k8s.api.core.v1.ResourceRequirements")
+	assert.Contains(t, document["definitions"], "child.io.k8s.apimachinery.pkg.api.resource.Quantity")
+
+	assert.NoError(t, compiled.Validate(map[string]interface{}{
+		"replicas": 1,
+		"child":    map[string]interface{}{"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "100m"}}},
+	}))
+	assert.Error(t, compiled.Validate(map[string]interface{}{
+		"replicas": 1,
+		"child":    map[string]interface{}{"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": true}}},
+	}))
+}
+
+func TestExec_DependencyBundledRefs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"type": "object", "properties": {"port": {"$ref": "#/definitions/port"}}, "definitions": {"port": {"type": "integer"}}}`))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	writeDependencyCharts(t, tmpDir, map[string]string{
+		"values.yaml": `
+# @schema
+# $ref: ` + server.URL + `/service.json
+# @schema
+service: {}
+`,
+	})
+
+	setStandardViper(tmpDir)
+	viper.Set("bundle", true)
+	viper.Set("ref-cache-dir", t.TempDir())
+	assert.NoError(t, exec(nil, nil))
+
+	// The bundled documents of the dependency are definitions of the parent
+	document, compiled := compileParentSchema(t, tmpDir)
+	if compiled == nil {
+		return
+	}
+	name := "child." + strings.ReplaceAll(strings.TrimPrefix(server.URL, "http://"), ":", "_") + "_service.json"
+	assert.Contains(t, document["definitions"], name)
+	assert.Contains(t, document["definitions"], name+".port")
+
+	assert.NoError(t, compiled.Validate(map[string]interface{}{
+		"replicas": 1,
+		"child":    map[string]interface{}{"service": map[string]interface{}{"port": 80}},
+	}))
+	assert.Error(t, compiled.Validate(map[string]interface{}{
+		"replicas": 1,
+		"child":    map[string]interface{}{"service": map[string]interface{}{"port": "http"}},
+	}))
 }
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/dadav/helm-schema/pkg/util"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newValidateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "validate values files against the jsonschema of their chart",
+		Long: `Validate values files (e.g. values-prod.yaml or ci/*.yaml) against the jsonschema of the chart they belong to.
+
+The chart of each file is the closest parent directory containing a Chart.yaml.
+By default its schema is generated in memory (including dependencies), use
+--use-existing-schema to validate against the committed schema file instead.`,
+		Args: cobra.NoArgs,
+		RunE: validate,
+	}
+
+	// Shadows the global --value-files flag: here it names the files to validate,
+	// the schema itself is generated from the configured value files (HELM_SCHEMA_VALUE_FILES).
+	cmd.Flags().
+		StringSliceP("value-files", "f", []string{}, "values files to validate, glob patterns are supported")
+	cmd.Flags().
+		BoolP("use-existing-schema", "e", false, "validate against the existing schema file (see --output-file) instead of a freshly generated one")
+	_ = cmd.MarkFlagRequired("value-files")
+
+	return cmd
+}
+
+// valuesViolation describes a single schema violation found in a values file.
+type valuesViolation struct {
+	File    string
+	Line    int
+	Column  int
+	Path    string // json pointer to the offending value
+	Message string
+}
+
+func (v valuesViolation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", v.File, v.Line, v.Column, path, v.Message)
+}
+
+func validate(cmd *cobra.Command, _ []string) error {
+	configureLogging()
+
+	patterns, err := cmd.Flags().GetStringSlice("value-files")
+	if err != nil {
+		return err
+	}
+	useExistingSchema, err := cmd.Flags().GetBool("use-existing-schema")
+	if err != nil {
+		return err
+	}
+
+	files, err := expandValueFilePatterns(patterns)
+	if err != nil {
+		return err
+	}
+
+	opts, err := newGeneratorOptions()
+	if err != nil {
+		return err
+	}
+
+	violations, err := validateValueFiles(opts, files, useExistingSchema)
+	if err != nil {
+		return err
+	}
+
+	for _, violation := range violations {
+		log.Error(violation)
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("found %d schema violations in %d files", len(violations), countViolatingFiles(violations))
+	}
+
+	log.Infof("All %d values files are valid", len(files))
+	return nil
+}
+
+// expandValueFilePatterns expands glob patterns and returns the matching files.
+// Patterns without glob characters must point to an existing file.
+func expandValueFilePatterns(patterns []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no values file found matching %s", pattern)
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// findChartDir returns the closest directory containing a Chart.yaml, starting
+// at the directory of the given file.
+func findChartDir(file string) (string, error) {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Dir(absFile)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "Chart.yaml")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no Chart.yaml found for values file %s", file)
+		}
+		dir = parent
+	}
+}
+
+// validateValueFiles validates every file against the schema of its chart and
+// returns all violations, sorted by file and position.
+func validateValueFiles(opts *generatorOptions, files []string, useExistingSchema bool) ([]valuesViolation, error) {
+	filesByChart := make(map[string][]string)
+	for _, file := range files {
+		chartDir, err := findChartDir(file)
+		if err != nil {
+			return nil, err
+		}
+		filesByChart[chartDir] = append(filesByChart[chartDir], file)
+	}
+
+	var schemas map[string][]byte
+	var err error
+	if useExistingSchema {
+		schemas, err = readExistingSchemas(opts, filesByChart)
+	} else {
+		schemas, err = generateChartSchemas(opts, filesByChart)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []valuesViolation
+	for chartDir, chartFiles := range filesByChart {
+		compiled, err := compileSchema(schemas[chartDir], opts.urlLoader())
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile schema of chart %s: %w", chartDir, err)
+		}
+		for _, file := range chartFiles {
+			fileViolations, err := validateValuesFile(compiled, file)
+			if err != nil {
+				return nil, err
+			}
+			violations = append(violations, fileViolations...)
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].File != violations[j].File {
+			return violations[i].File < violations[j].File
+		}
+		if violations[i].Line != violations[j].Line {
+			return violations[i].Line < violations[j].Line
+		}
+		return violations[i].Column < violations[j].Column
+	})
+
+	return violations, nil
+}
+
+// readExistingSchemas reads the schema files on disk of the given charts.
+func readExistingSchemas(opts *generatorOptions, charts map[string][]string) (map[string][]byte, error) {
+	schemas := make(map[string][]byte)
+	for chartDir := range charts {
+		chartOpts, err := opts.forChart(filepath.Join(chartDir, "Chart.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		schemaPath := filepath.Join(chartDir, chartOpts.outFile)
+		content, err := os.ReadFile(schemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing schema: %w", err)
+		}
+		schemas[chartDir] = content
+	}
+	return schemas, nil
+}
+
+// generateChartSchemas generates the final schemas of all charts below the
+// search root (so dependencies are merged) and returns the ones of the given
+// chart directories. Nothing is written to disk.
+func generateChartSchemas(opts *generatorOptions, charts map[string][]string) (map[string][]byte, error) {
+	opts.disableWrites()
+
+	results, cleanup := collectResults(opts)
+	defer cleanup()
+
+	schemas := make(map[string][]byte)
+	_, err := finalizeSchemas(opts, results, func(result *schema.Result) bool {
+		chartDir, err := filepath.Abs(filepath.Dir(result.ChartPath))
+		if err != nil {
+			log.Error(err)
+			return false
+		}
+		if _, ok := charts[chartDir]; !ok {
+			return true
+		}
+		jsonStr, err := result.Schema.ToJsonForDraft(opts.draft)
+		if err != nil {
+			log.Errorf("Failed to serialize schema for chart %s: %s", result.Chart.Name, err)
+			return false
+		}
+		schemas[chartDir] = jsonStr
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for chartDir := range charts {
+		if _, ok := schemas[chartDir]; !ok {
+			return nil, fmt.Errorf("could not generate schema for chart %s, is it located below the chart search root?", chartDir)
+		}
+	}
+
+	return schemas, nil
+}
+
+// validateValuesFile validates a single values file and maps each violation
+// back to the line and column of the offending key.
+func validateValuesFile(compiled *jsonschema.Schema, valuesPath string) ([]valuesViolation, error) {
+	file, err := os.Open(valuesPath)
+	if err != nil {
+		return nil, err
+	}
+	content, err := util.ReadFileAndFixNewline(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", valuesPath, err)
+	}
+
+	var values any = map[string]any{}
+	if node.Kind != 0 {
+		values, err = util.YamlNodeToValue(&node)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values of %s: %w", valuesPath, err)
+		}
+		// Helm treats an empty document like an empty map
+		if values == nil {
+			values = map[string]any{}
+		}
+	}
+
+	err = compiled.Validate(values)
+	if err == nil {
+		return nil, nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, fmt.Errorf("failed to validate %s: %w", valuesPath, err)
+	}
+
+	var violations []valuesViolation
+	addViolation := func(path []string, message string) {
+		violation := valuesViolation{
+			File:    valuesPath,
+			Line:    1,
+			Column:  1,
+			Path:    jsonPointer(path),
+			Message: message,
+		}
+		if found := util.FindYamlNode(&node, path); found != nil && found.Line > 0 {
+			violation.Line = found.Line
+			violation.Column = found.Column
+		}
+		violations = append(violations, violation)
+	}
+
+	for _, leaf := range validationLeaves(validationErr) {
+		// Point to every unexpected key instead of their parent
+		if additional, ok := leaf.ErrorKind.(*kind.AdditionalProperties); ok {
+			for _, property := range additional.Properties {
+				path := append(append([]string{}, leaf.InstanceLocation...), property)
+				addViolation(path, fmt.Sprintf("additional property %q is not allowed", property))
+			}
+			continue
+		}
+		addViolation(leaf.InstanceLocation, validationMessage(leaf))
+	}
+
+	return violations, nil
+}
+
+// validationLeaves returns the most specific errors of a validation error tree.
+// anyOf and oneOf errors are kept as they are, because listing the failures of
+// every alternative isn't helpful.
+func validationLeaves(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	switch err.ErrorKind.(type) {
+	case *kind.AnyOf, *kind.OneOf:
+		return []*jsonschema.ValidationError{err}
+	}
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		leaves = append(leaves, validationLeaves(cause)...)
+	}
+	return leaves
+}
+
+// validationMessage returns the message of the error itself, without its causes.
+func validationMessage(err *jsonschema.ValidationError) string {
+	single := *err
+	single.Causes = nil
+	output := single.BasicOutput()
+	if output.Error == nil {
+		return "validation failed"
+	}
+	return output.Error.String()
+}
+
+// jsonPointer joins path tokens into a json pointer.
+func jsonPointer(path []string) string {
+	var sb strings.Builder
+	replacer := strings.NewReplacer("~", "~0", "/", "~1")
+	for _, token := range path {
+		sb.WriteByte('/')
+		sb.WriteString(replacer.Replace(token))
+	}
+	return sb.String()
+}
+
+func countViolatingFiles(violations []valuesViolation) int {
+	files := make(map[string]bool)
+	for _, violation := range violations {
+		files[violation.File] = true
+	}
+	return len(files)
+}
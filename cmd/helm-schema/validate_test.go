@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeValidateChart(t *testing.T, tmpDir string) {
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("chart/Chart.yaml", `
+apiVersion: v2
+name: chart
+version: 1.0.0
+`)
+	writeFile("chart/values.yaml", `# @schema
+# enum: [small, large]
+# @schema
+size: small
+image:
+  tag: latest
+`)
+	writeFile("chart/ci/valid.yaml", `size: large
+image:
+  tag: v1
+`)
+	writeFile("chart/ci/invalid.yaml", `size: medium
+image:
+  tag: 1
+  unknown: true
+`)
+}
+
+func TestValidateValueFiles_GeneratedSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeValidateChart(t, tmpDir)
+
+	setStandardViper(tmpDir)
+	opts, err := newGeneratorOptions()
+	assert.NoError(t, err)
+
+	files, err := expandValueFilePatterns([]string{filepath.Join(tmpDir, "chart", "ci", "*.yaml")})
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	violations, err := validateValueFiles(opts, files, false)
+	assert.NoError(t, err)
+
+	invalidFile := filepath.Join(tmpDir, "chart", "ci", "invalid.yaml")
+	if assert.Len(t, violations, 3) {
+		assert.Equal(t, invalidFile, violations[0].File)
+		assert.Equal(t, "/size", violations[0].Path)
+		assert.Equal(t, 1, violations[0].Line)
+		assert.Equal(t, 1, violations[0].Column)
+
+		assert.Equal(t, "/image/tag", violations[1].Path)
+		assert.Equal(t, 3, violations[1].Line)
+		assert.Equal(t, 3, violations[1].Column)
+
+		assert.Equal(t, "/image/unknown", violations[2].Path)
+		assert.Equal(t, 4, violations[2].Line)
+		assert.Contains(t, violations[2].Message, "unknown")
+	}
+
+	// Nothing must be written in validate mode
+	_, err = os.Stat(filepath.Join(tmpDir, "chart", "values.schema.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestValidateValueFiles_ExistingSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeValidateChart(t, tmpDir)
+
+	// A committed schema which only allows integer tags
+	err := os.WriteFile(filepath.Join(tmpDir, "chart", "values.schema.json"), []byte(`{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "properties": {
+    "image": {
+      "type": "object",
+      "properties": {
+        "tag": {"type": "integer"}
+      }
+    }
+  }
+}`), 0o644)
+	assert.NoError(t, err)
+
+	setStandardViper(tmpDir)
+	opts, err := newGeneratorOptions()
+	assert.NoError(t, err)
+
+	violations, err := validateValueFiles(opts, []string{filepath.Join(tmpDir, "chart", "ci", "valid.yaml")}, true)
+	assert.NoError(t, err)
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, "/image/tag", violations[0].Path)
+		assert.Equal(t, 3, violations[0].Line)
+	}
+}
+
+func TestValidateValueFiles_NoChart(t *testing.T) {
+	tmpDir := t.TempDir()
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	assert.NoError(t, os.WriteFile(valuesPath, []byte("foo: bar\n"), 0o644))
+
+	setStandardViper(tmpDir)
+	opts, err := newGeneratorOptions()
+	assert.NoError(t, err)
+
+	_, err = validateValueFiles(opts, []string{valuesPath}, false)
+	assert.Error(t, err)
+}
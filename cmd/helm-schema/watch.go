@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/dadav/helm-schema/pkg/chart"
+	"github.com/dadav/helm-schema/pkg/chart/searching"
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// watchDebounce is the time to wait for further changes before regenerating.
+// Editors often write a file in several steps.
+const watchDebounce = 200 * time.Millisecond
+
+// chartWatcher regenerates the schemas of charts whose files change.
+type chartWatcher struct {
+	opts          *generatorOptions
+	appendNewline bool
+	watcher       *fsnotify.Watcher
+
+	// charts maps the path of every discovered Chart.yaml to its content, nil
+	// if it couldn't be read
+	charts map[string]*chart.ChartFile
+	// files maps the absolute path of every watched file to the charts using it
+	files map[string][]string
+	// dirs are the watched directories, fsnotify watches directories so files
+	// replaced by editors (write to temp file and rename) are still noticed
+	dirs map[string]bool
+}
+
+// watchCharts regenerates the schemas of changed charts and their dependents
+// until ctx is canceled. Errors are logged and don't stop watching.
+func watchCharts(ctx context.Context, opts *generatorOptions, appendNewline bool) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Keep extracted chart archives for the whole session
+	errs := make(chan error, 100)
+	if tempDir := searching.SearchArchivesOpenTemp(opts.chartSearchRoot, errs); tempDir != "" {
+		defer os.RemoveAll(tempDir)
+	}
+	logErrors(errs)
+
+	w := &chartWatcher{
+		opts:          opts,
+		appendNewline: appendNewline,
+		watcher:       watcher,
+		dirs:          make(map[string]bool),
+	}
+	w.discover()
+	log.Infof("Watching %d charts for changes, press Ctrl+C to stop", len(w.charts))
+
+	pending := make(map[string]bool)
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			path, err := filepath.Abs(event.Name)
+			if err != nil {
+				continue
+			}
+			charts := w.files[path]
+			if len(charts) == 0 {
+				continue
+			}
+			log.Debugf("%s changed (%s)", event.Name, event.Op)
+			for _, chartPath := range charts {
+				pending[chartPath] = true
+			}
+			debounce = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Errorf("Watch error: %s", err)
+		case <-debounce:
+			debounce = nil
+			changed := make([]string, 0, len(pending))
+			for chartPath := range pending {
+				changed = append(changed, chartPath)
+			}
+			clear(pending)
+
+			// Chart.yaml files may have changed the dependencies
+			w.discover()
+			w.regenerate(changed)
+			// The values may reference other files now
+			w.discover()
+		}
+	}
+}
+
+// logErrors logs all errors buffered in errs.
+func logErrors(errs chan error) {
+	for {
+		select {
+		case err := <-errs:
+			log.Error(err)
+		default:
+			return
+		}
+	}
+}
+
+// discover searches the charts and updates the watched files and directories.
+func (w *chartWatcher) discover() {
+	queue := make(chan string)
+	errs := make(chan error, 100)
+	go searching.SearchFiles(w.opts.chartSearchRoot, w.opts.chartSearchRoot, "Chart.yaml", w.opts.dependenciesFilterMap, queue, errs)
+
+	charts := make(map[string]*chart.ChartFile)
+	for queueOpen := true; queueOpen; {
+		select {
+		case err := <-errs:
+			log.Error(err)
+		case chartPath, ok := <-queue:
+			if !ok {
+				queueOpen = false
+				continue
+			}
+			charts[chartPath] = readChartFile(chartPath)
+		}
+	}
+	logErrors(errs)
+	w.charts = charts
+
+	files := make(map[string][]string)
+	addFile := func(path, chartPath string) {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		if !slices.Contains(files[absPath], chartPath) {
+			files[absPath] = append(files[absPath], chartPath)
+		}
+	}
+	for chartPath := range charts {
+		addFile(chartPath, chartPath)
+		addFile(filepath.Join(filepath.Dir(chartPath), configFileName), chartPath)
+		chartOpts, err := w.opts.forChart(chartPath)
+		if err != nil {
+			// Broken config files are reported when generating
+			chartOpts = w.opts
+		}
+		for _, valueFileName := range chartOpts.valueFileNames {
+			valuesPath := filepath.Join(filepath.Dir(chartPath), valueFileName)
+			addFile(valuesPath, chartPath)
+
+			refs, err := schema.ReferencedFiles(valuesPath)
+			if err != nil {
+				// Missing or broken values files are reported when generating
+				continue
+			}
+			for _, ref := range refs {
+				addFile(ref, chartPath)
+			}
+		}
+	}
+	w.files = files
+
+	dirs := make(map[string]bool)
+	for path := range files {
+		dirs[filepath.Dir(path)] = true
+	}
+	for dir := range dirs {
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			log.Warnf("Failed to watch %s: %s", dir, err)
+			delete(dirs, dir)
+		}
+	}
+	for dir := range w.dirs {
+		if !dirs[dir] {
+			_ = w.watcher.Remove(dir)
+		}
+	}
+	w.dirs = dirs
+}
+
+// readChartFile reads a Chart.yaml, errors are reported when generating.
+func readChartFile(chartPath string) *chart.ChartFile {
+	file, err := os.Open(chartPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	chartFile, err := chart.ReadChart(file)
+	if err != nil {
+		return nil
+	}
+	return &chartFile
+}
+
+// relatedCharts returns the given charts and all charts reachable through next,
+// which returns the neighbours of a chart.
+func relatedCharts(chartPaths []string, next func(chartPath string) []string) map[string]bool {
+	result := make(map[string]bool)
+	stack := slices.Clone(chartPaths)
+	for len(stack) > 0 {
+		chartPath := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if result[chartPath] {
+			continue
+		}
+		result[chartPath] = true
+		stack = append(stack, next(chartPath)...)
+	}
+	return result
+}
+
+// dependents returns the charts which have the given chart as a dependency.
+func (w *chartWatcher) dependents(chartPath string) []string {
+	chartFile := w.charts[chartPath]
+	if chartFile == nil {
+		return nil
+	}
+	var result []string
+	for parentPath, parent := range w.charts {
+		if parent == nil {
+			continue
+		}
+		for _, dep := range parent.Dependencies {
+			if dep.Name == chartFile.Name {
+				result = append(result, parentPath)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// dependencies returns the charts the given chart depends on.
+func (w *chartWatcher) dependencies(chartPath string) []string {
+	chartFile := w.charts[chartPath]
+	if chartFile == nil {
+		return nil
+	}
+	var result []string
+	for _, dep := range chartFile.Dependencies {
+		for depPath, depChart := range w.charts {
+			if depChart != nil && depChart.Name == dep.Name {
+				result = append(result, depPath)
+			}
+		}
+	}
+	return result
+}
+
+// regenerate regenerates and writes the schemas of the changed charts and of
+// every chart depending on them. Their dependencies are generated as well,
+// because their schemas are merged into the parents, but not written.
+func (w *chartWatcher) regenerate(changed []string) {
+	changed = slices.DeleteFunc(changed, func(chartPath string) bool {
+		_, ok := w.charts[chartPath]
+		if !ok {
+			log.Infof("Chart %s was removed", chartPath)
+		}
+		return !ok
+	})
+	if len(changed) == 0 {
+		return
+	}
+
+	// Without dependencies nothing is merged, so only the changed charts are affected
+	affected := relatedCharts(changed, func(string) []string { return nil })
+	needed := affected
+	if !w.opts.noDeps {
+		affected = relatedCharts(changed, w.dependents)
+		affectedPaths := make([]string, 0, len(affected))
+		for chartPath := range affected {
+			affectedPaths = append(affectedPaths, chartPath)
+		}
+		needed = relatedCharts(affectedPaths, w.dependencies)
+	}
+
+	queue := make(chan string)
+	go func() {
+		defer close(queue)
+		for chartPath := range needed {
+			queue <- chartPath
+		}
+	}()
+	results := runWorkers(w.opts, queue, make(chan error))
+
+	written := 0
+	output := newOutputHandler(w.opts, w.appendNewline, false, false, nil)
+	foundErrors, err := finalizeSchemas(w.opts, results, func(result *schema.Result) bool {
+		if !affected[result.ChartPath] {
+			return true
+		}
+		if !output(result) {
+			return false
+		}
+		written++
+		return true
+	})
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if foundErrors {
+		log.Errorf("Some errors were found, regenerated %d of %d charts", written, len(affected))
+		return
+	}
+	log.Infof("Regenerated %d charts", written)
+}
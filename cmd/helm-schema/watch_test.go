@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeWatchCharts(t *testing.T, tmpDir string) func(relPath, content string) {
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(tmpDir, relPath)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		assert.NoError(t, err)
+	}
+
+	writeFile("parent/Chart.yaml", `
+apiVersion: v2
+name: parent
+version: 1.0.0
+dependencies:
+  - name: dep
+    version: 1.0.0
+`)
+	writeFile("parent/values.yaml", "replicas: 1\n")
+	writeFile("parent/charts/dep/Chart.yaml", `
+apiVersion: v2
+name: dep
+version: 1.0.0
+`)
+	writeFile("parent/charts/dep/values.yaml", `# @schema
+# $ref: ./image.json
+# @schema
+image: {}
+`)
+	writeFile("parent/charts/dep/image.json", `{"type": "object", "properties": {"tag": {"$ref": "schemas/tag.yaml"}}}`)
+	writeFile("parent/charts/dep/schemas/tag.yaml", "type: string\n")
+	writeFile("other/Chart.yaml", `
+apiVersion: v2
+name: other
+version: 1.0.0
+`)
+	writeFile("other/values.yaml", "enabled: true\n")
+
+	return writeFile
+}
+
+func TestChartWatcher_Discover(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeWatchCharts(t, tmpDir)
+	setStandardViper(tmpDir)
+
+	opts, err := newGeneratorOptions()
+	assert.NoError(t, err)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Skipf("fsnotify is not available: %s", err)
+	}
+	defer watcher.Close()
+
+	w := &chartWatcher{opts: opts, watcher: watcher, dirs: make(map[string]bool)}
+	w.discover()
+
+	parent := filepath.Join(tmpDir, "parent", "Chart.yaml")
+	dep := filepath.Join(tmpDir, "parent", "charts", "dep", "Chart.yaml")
+	other := filepath.Join(tmpDir, "other", "Chart.yaml")
+	assert.Len(t, w.charts, 3)
+
+	assert.Equal(t, []string{dep}, w.files[filepath.Join(tmpDir, "parent", "charts", "dep", "image.json")])
+	assert.Equal(t, []string{dep}, w.files[filepath.Join(tmpDir, "parent", "charts", "dep", "schemas", "tag.yaml")])
+	assert.Equal(t, []string{dep}, w.files[filepath.Join(tmpDir, "parent", "charts", "dep", "values.yaml")])
+	assert.Equal(t, []string{parent}, w.files[parent])
+	assert.True(t, w.dirs[filepath.Join(tmpDir, "other")])
+
+	assert.Equal(t, map[string]bool{dep: true, parent: true}, relatedCharts([]string{dep}, w.dependents))
+	assert.Equal(t, map[string]bool{dep: true, parent: true}, relatedCharts([]string{parent}, w.dependencies))
+	assert.Equal(t, map[string]bool{other: true}, relatedCharts([]string{other}, w.dependents))
+}
+
+func TestWatchCharts_RegeneratesDependents(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile := writeWatchCharts(t, tmpDir)
+	setStandardViper(tmpDir)
+
+	opts, err := newGeneratorOptions()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watchCharts(ctx, opts, false)
+	}()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-done)
+	}()
+
+	parentSchema := filepath.Join(tmpDir, "parent", "values.schema.json")
+	otherSchema := filepath.Join(tmpDir, "other", "values.schema.json")
+
+	// Give the watcher some time to register the directories
+	time.Sleep(300 * time.Millisecond)
+
+	// Files referenced by referenced files are watched as well
+	writeFile("parent/charts/dep/schemas/tag.yaml", "type: string\ndescription: The tag\n")
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(parentSchema)
+		return err == nil && strings.Contains(string(content), "The tag")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	writeFile("parent/charts/dep/image.json", `{"type": "object", "description": "The image"}`)
+
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(parentSchema)
+		return err == nil && strings.Contains(string(content), "The image")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	_, err = os.Stat(otherSchema)
+	assert.True(t, os.IsNotExist(err), "unrelated charts must not be regenerated")
+}
@@ -0,0 +1,163 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+)
+
+// Supported languages
+const (
+	LanguageGo         = "go"
+	LanguageTypeScript = "typescript"
+	LanguageCUE        = "cue"
+)
+
+// Languages lists all supported languages
+var Languages = []string{LanguageGo, LanguageTypeScript, LanguageCUE}
+
+// Options of the generated code
+type Options struct {
+	// Package is the name of the generated package, if the language has one
+	Package string
+	// TypeName is the name of the type of the values
+	TypeName string
+}
+
+// ValidateLanguage returns an error if the language is not supported
+func ValidateLanguage(language string) error {
+	for _, supported := range Languages {
+		if language == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported language %s, must be one of (%s)", language, strings.Join(Languages, ", "))
+}
+
+// Generate returns the source code of the types of the values described by
+// the schema. References to definitions become named types.
+func Generate(language string, s *schema.Schema, options Options) ([]byte, error) {
+	if err := ValidateLanguage(language); err != nil {
+		return nil, err
+	}
+	if options.TypeName == "" {
+		options.TypeName = "Values"
+	}
+
+	switch language {
+	case LanguageGo:
+		if options.Package == "" {
+			options.Package = "values"
+		}
+		return generateGo(s, options)
+	case LanguageTypeScript:
+		return generateTypeScript(s, options)
+	case LanguageCUE:
+		if options.Package == "" {
+			options.Package = "values"
+		}
+		return generateCUE(s, options)
+	}
+	return nil, nil
+}
+
+// definitionName returns the name of the definition a local reference
+// points to
+func definitionName(root *schema.Schema, ref string) (string, bool) {
+	name, ok := strings.CutPrefix(ref, "#/definitions/")
+	if !ok || root.Definitions[name] == nil {
+		return "", false
+	}
+	return name, true
+}
+
+func sortedKeys(m map[string]*schema.Schema) []string {
+	keys := make([]string, 0, len(m))
+	for key, value := range m {
+		if value != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// types returns the types of the schema without null and whether null is
+// allowed
+func types(s *schema.Schema) ([]string, bool) {
+	var result []string
+	nullable := false
+	for _, t := range s.Type {
+		if t == "null" {
+			nullable = true
+		} else {
+			result = append(result, t)
+		}
+	}
+	return result, nullable
+}
+
+// additionalSchema returns the schema of additionalProperties and whether
+// additional properties are allowed at all
+func additionalSchema(value schema.SchemaOrBool) (*schema.Schema, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, true
+	case bool:
+		return nil, v
+	case *bool:
+		return nil, v == nil || *v
+	case *schema.Schema:
+		return v, true
+	case schema.Schema:
+		return &v, true
+	}
+	// When unmarshaled from YAML, a schema object becomes a map
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, true
+	}
+	var s schema.Schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, true
+	}
+	return &s, true
+}
+
+// jsonValue returns the value as compact json
+func jsonValue(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
+
+// commentLines splits a text into trimmed lines
+func commentLines(text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return lines
+}
+
+// definitionComment returns the description of a type or says which values
+// it holds
+func definitionComment(s *schema.Schema, path string) []string {
+	if lines := commentLines(s.Description); len(lines) > 0 {
+		return lines
+	}
+	subject := path
+	if subject == "" {
+		subject = "the chart"
+	}
+	return []string{fmt.Sprintf("The values of %s.", subject)}
+}
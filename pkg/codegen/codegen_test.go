@@ -0,0 +1,32 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLanguage(t *testing.T) {
+	assert.NoError(t, ValidateLanguage(LanguageGo))
+	assert.EqualError(t, ValidateLanguage("rust"), "unsupported language rust, must be one of (go, typescript, cue)")
+
+	_, err := Generate("rust", &schema.Schema{}, Options{})
+	assert.EqualError(t, err, "unsupported language rust, must be one of (go, typescript, cue)")
+}
+
+func TestAdditionalSchema(t *testing.T) {
+	s, allowed := additionalSchema(nil)
+	assert.Nil(t, s)
+	assert.True(t, allowed)
+
+	s, allowed = additionalSchema(false)
+	assert.Nil(t, s)
+	assert.False(t, allowed)
+
+	s, allowed = additionalSchema(map[string]interface{}{"type": "string"})
+	assert.True(t, allowed)
+	if assert.NotNil(t, s) {
+		assert.Equal(t, schema.StringOrArrayOfString{"string"}, s.Type)
+	}
+}
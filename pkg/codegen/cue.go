@@ -0,0 +1,380 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+)
+
+var (
+	cueIdentifierRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	// cueKeywords can't be used as labels without quotes
+	cueKeywords = map[string]bool{
+		"package": true, "import": true, "for": true, "in": true, "if": true, "let": true,
+		"true": true, "false": true, "null": true, "div": true, "mod": true, "quo": true, "rem": true,
+	}
+)
+
+var cuePrimitives = map[string]string{
+	"string":  "string",
+	"integer": "int",
+	"number":  "number",
+	"boolean": "bool",
+	"null":    "null",
+}
+
+type cueGenerator struct {
+	root        *schema.Schema
+	definitions map[string]string
+	imports     map[string]bool
+}
+
+func generateCUE(root *schema.Schema, options Options) ([]byte, error) {
+	if !cueIdentifierRegex.MatchString(options.Package) {
+		return nil, fmt.Errorf("invalid package name %s", options.Package)
+	}
+	if !cueIdentifierRegex.MatchString(options.TypeName) {
+		return nil, fmt.Errorf("invalid type name %s, must be an identifier", options.TypeName)
+	}
+
+	g := &cueGenerator{
+		root:        root,
+		definitions: map[string]string{},
+		imports:     map[string]bool{},
+	}
+	definitionNames := names{options.TypeName: true}
+	for _, name := range sortedKeys(root.Definitions) {
+		typeName := exportedName(name, false)
+		g.definitions[name] = definitionNames.unique(typeName, typeName+"Definition")
+	}
+
+	var decls []string
+	decls = append(decls, fmt.Sprintf("%s#%s: %s\n", cueComment(definitionComment(root, ""), ""), options.TypeName, g.cueType(root, "")))
+	for _, name := range sortedKeys(root.Definitions) {
+		s := root.Definitions[name]
+		decls = append(decls, fmt.Sprintf("%s#%s: %s\n", cueComment(definitionComment(s, "definitions."+name), ""), g.definitions[name], g.cueType(s, "")))
+	}
+
+	var source strings.Builder
+	source.WriteString("// Code generated by helm-schema. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&source, "package %s\n", options.Package)
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for pkg := range g.imports {
+			imports = append(imports, strconv.Quote(pkg))
+		}
+		sort.Strings(imports)
+		if len(imports) == 1 {
+			fmt.Fprintf(&source, "\nimport %s\n", imports[0])
+		} else {
+			fmt.Fprintf(&source, "\nimport (\n\t%s\n)\n", strings.Join(imports, "\n\t"))
+		}
+	}
+	for _, decl := range decls {
+		source.WriteString("\n")
+		source.WriteString(decl)
+	}
+	return []byte(source.String()), nil
+}
+
+// cueType returns the CUE expression of the schema. Nested structs are
+// indented by the indent.
+func (g *cueGenerator) cueType(s *schema.Schema, indent string) string {
+	if s.Ref != "" {
+		if name, ok := definitionName(g.root, s.Ref); ok {
+			return "#" + g.definitions[name]
+		}
+		return "_"
+	}
+
+	// parts are conjuncts, each a disjunction of branches
+	var parts [][]string
+	base, marked := g.baseType(s, indent)
+	if base != "" {
+		parts = append(parts, []string{base})
+	}
+	for _, junctor := range [][]*schema.Schema{s.AnyOf, s.OneOf} {
+		var branches []string
+		for _, branch := range junctor {
+			if branch == nil {
+				continue
+			}
+			if expr := g.cueType(branch, indent); !slices.Contains(branches, expr) {
+				branches = append(branches, expr)
+			}
+		}
+		if len(branches) > 0 {
+			parts = append(parts, branches)
+		}
+	}
+	for _, branch := range s.AllOf {
+		if branch != nil {
+			parts = append(parts, []string{g.cueType(branch, indent)})
+		}
+	}
+
+	var defaultValue string
+	if s.Default != nil && !marked {
+		defaultValue = cueValue(s.Default)
+		// A default which is a branch of the only disjunction is marked there,
+		// a default which is the only value needs no mark
+		if len(parts) == 1 {
+			if i := slices.Index(parts[0], defaultValue); i >= 0 {
+				if len(parts[0]) > 1 {
+					parts[0][i] = "*" + defaultValue
+				}
+				marked = true
+			}
+		}
+	}
+
+	expr := "_"
+	if len(parts) == 1 {
+		expr = strings.Join(parts[0], " | ")
+	} else if len(parts) > 1 {
+		conjuncts := make([]string, len(parts))
+		for i, branches := range parts {
+			conjuncts[i] = strings.Join(branches, " | ")
+			if strings.Contains(conjuncts[i], " | ") {
+				conjuncts[i] = "(" + conjuncts[i] + ")"
+			}
+		}
+		expr = strings.Join(conjuncts, " & ")
+	}
+	if s.Default != nil && !marked {
+		expr = "*" + defaultValue + " | " + expr
+	}
+	return expr
+}
+
+// baseType returns the expression given by const, enum or type with its
+// constraints, and whether the default is already marked in it
+func (g *cueGenerator) baseType(s *schema.Schema, indent string) (string, bool) {
+	if s.Const != nil {
+		return cueValue(s.Const), false
+	}
+	if len(s.Enum) > 0 {
+		marked := false
+		var defaultValue string
+		if s.Default != nil {
+			defaultValue = cueValue(s.Default)
+		}
+		values := make([]string, len(s.Enum))
+		for i, value := range s.Enum {
+			values[i] = cueValue(value)
+			if !marked && s.Default != nil && values[i] == defaultValue {
+				if len(s.Enum) > 1 {
+					values[i] = "*" + values[i]
+				}
+				marked = true
+			}
+		}
+		return strings.Join(values, " | "), marked
+	}
+
+	t := []string(s.Type)
+	if len(t) == 0 && len(s.Properties) > 0 {
+		t = []string{"object"}
+	}
+	var disjuncts []string
+	for _, typeName := range t {
+		var conjuncts []string
+		switch typeName {
+		case "integer", "number":
+			conjuncts = append(conjuncts, cuePrimitives[typeName])
+			for _, bound := range []struct {
+				op    string
+				value *float64
+			}{{">=", s.Minimum}, {">", s.ExclusiveMinimum}, {"<=", s.Maximum}, {"<", s.ExclusiveMaximum}} {
+				if bound.value != nil {
+					conjuncts = append(conjuncts, bound.op+strconv.FormatFloat(*bound.value, 'f', -1, 64))
+				}
+			}
+		case "string":
+			conjuncts = append(conjuncts, "string")
+			if s.Pattern != "" {
+				conjuncts = append(conjuncts, "=~"+cueValue(s.Pattern))
+			}
+			conjuncts = append(conjuncts, g.validators("strings", "MinRunes", s.MinLength, "MaxRunes", s.MaxLength)...)
+		case "array":
+			conjuncts = append(conjuncts, g.listType(s, indent))
+			conjuncts = append(conjuncts, g.validators("list", "MinItems", s.MinItems, "MaxItems", s.MaxItems)...)
+			if s.UniqueItems {
+				g.imports["list"] = true
+				conjuncts = append(conjuncts, "list.UniqueItems()")
+			}
+		case "object":
+			conjuncts = append(conjuncts, g.structType(s, indent))
+			conjuncts = append(conjuncts, g.validators("struct", "MinFields", s.MinProperties, "MaxFields", s.MaxProperties)...)
+		default:
+			if primitive, ok := cuePrimitives[typeName]; ok {
+				conjuncts = append(conjuncts, primitive)
+			}
+		}
+		if len(conjuncts) > 0 {
+			disjuncts = append(disjuncts, strings.Join(conjuncts, " & "))
+		}
+	}
+	return strings.Join(disjuncts, " | "), false
+}
+
+// validators returns the calls of the validators of the package for the
+// given minimum and maximum
+func (g *cueGenerator) validators(pkg, minName string, minimum *int, maxName string, maximum *int) []string {
+	var result []string
+	if minimum != nil {
+		result = append(result, fmt.Sprintf("%s.%s(%d)", pkg, minName, *minimum))
+	}
+	if maximum != nil {
+		result = append(result, fmt.Sprintf("%s.%s(%d)", pkg, maxName, *maximum))
+	}
+	if len(result) > 0 {
+		g.imports[pkg] = true
+	}
+	return result
+}
+
+func (g *cueGenerator) listType(s *schema.Schema, indent string) string {
+	item := "_"
+	if s.Items != nil {
+		item = g.cueType(s.Items, indent)
+	}
+	if len(s.PrefixItems) == 0 {
+		if item == "_" {
+			return "[...]"
+		}
+		return "[..." + item + "]"
+	}
+
+	var elements []string
+	for _, prefixItem := range s.PrefixItems {
+		elements = append(elements, g.cueType(prefixItem, indent))
+	}
+	if s.Items == nil {
+		if additional, allowed := additionalSchema(s.AdditionalItems); additional != nil {
+			item = g.cueType(additional, indent)
+		} else if !allowed {
+			item = ""
+		}
+	}
+	switch item {
+	case "":
+	case "_":
+		elements = append(elements, "...")
+	default:
+		elements = append(elements, "..."+item)
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// structType returns the struct of the object. Structs of definitions are
+// closed in CUE, so they are opened with ... if additional properties are
+// allowed.
+func (g *cueGenerator) structType(s *schema.Schema, indent string) string {
+	inner := indent + "\t"
+	var fields strings.Builder
+	for _, key := range sortedKeys(s.Properties) {
+		property := s.Properties[key]
+		marker := "?"
+		for _, requiredKey := range s.Required.Strings {
+			if requiredKey == key {
+				// Required keys with a default don't have to be set
+				marker = ""
+				if property.Default == nil {
+					marker = "!"
+				}
+			}
+		}
+		fields.WriteString(cueComment(g.comment(property), inner))
+		fmt.Fprintf(&fields, "%s%s%s: %s\n", inner, cueLabel(key), marker, g.cueType(property, inner))
+	}
+
+	var patterns []string
+	for _, pattern := range sortedKeys(s.PatternProperties) {
+		patterns = append(patterns, pattern)
+		fmt.Fprintf(&fields, "%s[=~%s]: %s\n", inner, cueValue(pattern), g.cueType(s.PatternProperties[pattern], inner))
+	}
+
+	additional, allowed := additionalSchema(s.AdditionalProperties)
+	if additional != nil {
+		// Additional properties are the keys which are neither properties nor
+		// match a pattern
+		var exclusions []string
+		if len(s.Properties) > 0 {
+			keys := sortedKeys(s.Properties)
+			for i, key := range keys {
+				keys[i] = regexp.QuoteMeta(key)
+			}
+			exclusions = append(exclusions, "!~"+cueValue("^("+strings.Join(keys, "|")+")$"))
+		}
+		for _, pattern := range patterns {
+			exclusions = append(exclusions, "!~"+cueValue(pattern))
+		}
+		label := "string"
+		if len(exclusions) > 0 {
+			label = strings.Join(exclusions, " & ")
+		}
+		fmt.Fprintf(&fields, "%s[%s]: %s\n", inner, label, g.cueType(additional, inner))
+	} else if allowed {
+		fmt.Fprintf(&fields, "%s...\n", inner)
+	}
+
+	switch fields.String() {
+	case "":
+		return "{}"
+	case inner + "...\n":
+		return "{...}"
+	}
+	return "{\n" + fields.String() + indent + "}"
+}
+
+// comment returns the lines of the comment of a field
+func (g *cueGenerator) comment(s *schema.Schema) []string {
+	description := s.Description
+	if name, ok := definitionName(g.root, s.Ref); ok && description == "" {
+		description = g.root.Definitions[name].Description
+	}
+	lines := commentLines(description)
+	if s.Deprecated {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "Deprecated: this key should no longer be used.")
+	}
+	return lines
+}
+
+func cueComment(lines []string, indent string) string {
+	var comment strings.Builder
+	for _, line := range lines {
+		comment.WriteString(strings.TrimRight(indent+"// "+line, " ") + "\n")
+	}
+	return comment.String()
+}
+
+// cueLabel quotes keys which aren't identifiers
+func cueLabel(key string) string {
+	if cueIdentifierRegex.MatchString(key) && !cueKeywords[key] {
+		return key
+	}
+	return cueValue(key)
+}
+
+// cueValue returns the value as CUE literal. JSON is valid CUE, but HTML
+// characters don't have to be escaped.
+func cueValue(value interface{}) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
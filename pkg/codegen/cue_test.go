@@ -0,0 +1,124 @@
+package codegen
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateCUE(t *testing.T) {
+	var values schema.Schema
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["image", "replicas", "name"],
+		"additionalProperties": false,
+		"properties": {
+			"replicas": {"type": "integer", "description": "Number of replicas", "default": 1, "minimum": 1, "exclusiveMaximum": 10},
+			"name": {"type": "string", "pattern": "^[a-z]+$", "minLength": 1, "maxLength": 63},
+			"image": {
+				"type": "object",
+				"additionalProperties": false,
+				"properties": {
+					"tag": {"type": ["string", "null"], "default": "latest"},
+					"pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent"], "default": "IfNotPresent"}
+				}
+			},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}, "maxProperties": 5},
+			"env": {"type": "object", "additionalProperties": {"type": "string"}, "patternProperties": {"^X_": {"type": "integer"}}, "properties": {"MODE": {"const": "fast"}}},
+			"extra": {"type": "object"},
+			"hosts": {"type": "array", "items": {"anyOf": [{"type": "string"}, {"$ref": "#/definitions/host"}]}, "uniqueItems": true},
+			"pair": {"type": "array", "prefixItems": [{"type": "string"}, {"type": "number"}], "additionalItems": false},
+			"legacy": {"type": "boolean", "deprecated": true},
+			"if": {"type": "boolean"},
+			"pod-annotations": {}
+		},
+		"definitions": {
+			"host": {"type": "object", "required": ["name"], "additionalProperties": false, "properties": {
+				"name": {"type": "string"},
+				"paths": {"type": "array", "items": {"type": "string"}, "minItems": 1}
+			}}
+		}
+	}`), &values))
+
+	code, err := Generate(LanguageCUE, &values, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, `// Code generated by helm-schema. DO NOT EDIT.
+
+package values
+
+import (
+	"list"
+	"strings"
+	"struct"
+)
+
+// The values of the chart.
+#Values: {
+	env?: {
+		MODE?: "fast"
+		[=~"^X_"]: int
+		[!~"^(MODE)$" & !~"^X_"]: string
+	}
+	extra?: {...}
+	hosts?: [...string | #Host] & list.UniqueItems()
+	"if"?: bool
+	image!: {
+		pullPolicy?: "Always" | *"IfNotPresent"
+		tag?: *"latest" | string | null
+	}
+	labels?: {
+		[string]: string
+	} & struct.MaxFields(5)
+	// Deprecated: this key should no longer be used.
+	legacy?: bool
+	name!: string & =~"^[a-z]+$" & strings.MinRunes(1) & strings.MaxRunes(63)
+	pair?: [string, number]
+	"pod-annotations"?: _
+	// Number of replicas
+	replicas: *1 | int & >=1 & <10
+}
+
+// The values of definitions.host.
+#Host: {
+	name!: string
+	paths?: [...string] & list.MinItems(1)
+}
+`, string(code))
+
+	_, err = Generate(LanguageCUE, &values, Options{Package: "my-values"})
+	assert.EqualError(t, err, "invalid package name my-values")
+}
+
+func TestCUELabel(t *testing.T) {
+	assert.Equal(t, "replicas", cueLabel("replicas"))
+	assert.Equal(t, `"if"`, cueLabel("if"))
+	assert.Equal(t, `"_hidden"`, cueLabel("_hidden"))
+	assert.Equal(t, `"#def"`, cueLabel("#def"))
+	assert.Equal(t, `"a<b"`, cueLabel("a<b"))
+}
+
+func TestGenerateCUE_Disjunctions(t *testing.T) {
+	var values schema.Schema
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"name": {"anyOf": [{"$ref": "https://example.com/a.json"}, {"$ref": "https://example.com/b.json"}], "default": ""},
+			"tag": {"oneOf": [{"type": "string"}, {"type": "string"}, {"type": "null"}]},
+			"maintainer": {"const": "x", "default": "x"},
+			"zone": {"enum": ["eu"], "default": "eu"},
+			"mode": {"anyOf": [{"const": "fast"}, {"const": "slow"}], "default": "slow"}
+		}
+	}`), &values))
+
+	code, err := Generate(LanguageCUE, &values, Options{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(code), `
+	maintainer?: "x"
+	mode?: "fast" | *"slow"
+	name?: *"" | _
+	tag?: string | null
+	zone?: "eu"
+`)
+}
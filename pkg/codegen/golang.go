@@ -0,0 +1,420 @@
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+)
+
+// Kinds of Go types a schema maps to
+const (
+	goAny    = "any"
+	goEnum   = "enum"
+	goMap    = "map"
+	goRef    = "ref"
+	goScalar = "scalar"
+	goSlice  = "slice"
+	goStruct = "struct"
+)
+
+var goScalars = map[string]string{
+	"string":  "string",
+	"integer": "int64",
+	"number":  "float64",
+	"boolean": "bool",
+}
+
+// goTypeInfo describes the Go type of a schema
+type goTypeInfo struct {
+	expr string
+	// nilable types like slices, maps and interfaces never become pointers
+	nilable  bool
+	nullable bool
+	// pending is set for references to a definition which is still being
+	// declared, a struct can't contain itself
+	pending bool
+}
+
+type goDefinition struct {
+	name     string
+	nilable  bool
+	nullable bool
+	declared bool
+	pending  bool
+}
+
+type goGenerator struct {
+	root        *schema.Schema
+	names       names
+	decls       []string
+	definitions map[string]*goDefinition
+}
+
+func generateGo(root *schema.Schema, options Options) ([]byte, error) {
+	if !token.IsIdentifier(options.Package) {
+		return nil, fmt.Errorf("invalid package name %s", options.Package)
+	}
+	if !token.IsExported(options.TypeName) || !token.IsIdentifier(options.TypeName) {
+		return nil, fmt.Errorf("invalid type name %s, must be an exported identifier", options.TypeName)
+	}
+
+	g := &goGenerator{
+		root:        root,
+		names:       names{},
+		definitions: map[string]*goDefinition{},
+	}
+	rootName := g.names.unique(options.TypeName)
+	// Definitions keep their names, nested types have to make way
+	for _, name := range sortedKeys(root.Definitions) {
+		definition := g.flatten(root.Definitions[name])
+		_, nullable := types(definition)
+		kind := g.kind(definition)
+		g.definitions[name] = &goDefinition{
+			name:     g.names.unique(exportedName(name, true), exportedName(name, true)+"Definition"),
+			nilable:  kind == goSlice || kind == goMap || kind == goAny,
+			nullable: nullable,
+		}
+	}
+
+	g.declareStruct(rootName, root, "")
+	for _, name := range sortedKeys(root.Definitions) {
+		g.definition(name)
+	}
+
+	var source strings.Builder
+	source.WriteString("// Code generated by helm-schema. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&source, "package %s\n", options.Package)
+	for _, decl := range g.decls {
+		source.WriteString("\n")
+		source.WriteString(decl)
+	}
+
+	formatted, err := format.Source([]byte(source.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format the generated code: %w", err)
+	}
+	return formatted, nil
+}
+
+// flatten returns the only branch of an anyOf, oneOf or allOf without other
+// type information, or the branches merged into one schema if all of them
+// are objects. Otherwise the schema is returned as is.
+func (g *goGenerator) flatten(s *schema.Schema) *schema.Schema {
+	if len(s.Type) > 0 || len(s.Properties) > 0 || s.Ref != "" || len(s.Enum) > 0 {
+		return s
+	}
+	var branches []*schema.Schema
+	for _, junctor := range [][]*schema.Schema{s.AnyOf, s.OneOf, s.AllOf} {
+		for _, branch := range junctor {
+			if branch != nil {
+				branches = append(branches, branch)
+			}
+		}
+	}
+	if len(branches) == 0 {
+		return s
+	}
+	if len(branches) == 1 {
+		return g.flatten(branches[0])
+	}
+
+	merged := &schema.Schema{
+		Type:        schema.StringOrArrayOfString{"object"},
+		Description: s.Description,
+		Properties:  map[string]*schema.Schema{},
+	}
+	// Keys are only required if every branch requires them
+	required := map[string]int{}
+	for _, branch := range branches {
+		branch = g.flatten(branch)
+		if g.kind(branch) != goStruct {
+			return s
+		}
+		for key, property := range branch.Properties {
+			if _, ok := merged.Properties[key]; !ok {
+				merged.Properties[key] = property
+			}
+		}
+		for _, key := range branch.Required.Strings {
+			required[key]++
+		}
+	}
+	for _, key := range sortedKeys(merged.Properties) {
+		if required[key] == len(branches) || (len(s.AllOf) > 0 && required[key] > 0) {
+			merged.Required.Strings = append(merged.Required.Strings, key)
+		}
+	}
+	return merged
+}
+
+// kind returns the kind of Go type the flattened schema maps to
+func (g *goGenerator) kind(s *schema.Schema) string {
+	if s.Ref != "" {
+		if _, ok := definitionName(g.root, s.Ref); ok {
+			return goRef
+		}
+		return goAny
+	}
+	t, _ := types(s)
+	if len(t) == 0 && len(s.Properties) > 0 {
+		t = []string{"object"}
+	}
+	if len(s.Enum) > 0 && enumBase(s.Enum, t) != "" {
+		return goEnum
+	}
+	if len(t) != 1 {
+		return goAny
+	}
+	switch t[0] {
+	case "array":
+		return goSlice
+	case "object":
+		if len(s.Properties) > 0 {
+			return goStruct
+		}
+		return goMap
+	}
+	if _, ok := goScalars[t[0]]; ok {
+		return goScalar
+	}
+	return goAny
+}
+
+// enumBase returns the type shared by all values of the enum, or an empty
+// string if there is none
+func enumBase(values []interface{}, t []string) string {
+	base := ""
+	for _, value := range values {
+		var valueType string
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string:
+			valueType = "string"
+		case int, int64, uint64:
+			valueType = "integer"
+		case float64:
+			valueType = "number"
+			if v == math.Trunc(v) {
+				valueType = "integer"
+			}
+		default:
+			return ""
+		}
+		if base == "" || (base == "integer" && valueType == "number") {
+			base = valueType
+		} else if valueType != base && !(base == "number" && valueType == "integer") {
+			return ""
+		}
+	}
+	if len(t) > 1 || (len(t) == 1 && t[0] != base && !(t[0] == "number" && base == "integer")) {
+		return ""
+	}
+	if len(t) == 1 {
+		return t[0]
+	}
+	return base
+}
+
+// goType returns the Go type of the schema. Nested types are declared with
+// the first free name of the candidates.
+func (g *goGenerator) goType(s *schema.Schema, candidates []string, path string) goTypeInfo {
+	s = g.flatten(s)
+	_, nullable := types(s)
+
+	switch g.kind(s) {
+	case goRef:
+		name, _ := definitionName(g.root, s.Ref)
+		definition := g.definition(name)
+		return goTypeInfo{
+			expr:     definition.name,
+			nilable:  definition.nilable,
+			nullable: nullable || definition.nullable,
+			pending:  definition.pending,
+		}
+	case goEnum:
+		name := g.names.unique(candidates...)
+		g.declareEnum(name, s, path)
+		return goTypeInfo{expr: name, nullable: nullable}
+	case goStruct:
+		name := g.names.unique(candidates...)
+		g.declareStruct(name, s, path)
+		return goTypeInfo{expr: name, nullable: nullable}
+	case goSlice:
+		if s.Items == nil {
+			return goTypeInfo{expr: "[]interface{}", nilable: true, nullable: nullable}
+		}
+		item := g.goType(s.Items, suffixed(candidates, "Item"), path+"[]")
+		return goTypeInfo{expr: "[]" + pointerIfNullable(item), nilable: true, nullable: nullable}
+	case goMap:
+		value, _ := additionalSchema(s.AdditionalProperties)
+		if value == nil && len(s.PatternProperties) == 1 {
+			for _, pattern := range s.PatternProperties {
+				value = pattern
+			}
+		}
+		if value == nil {
+			return goTypeInfo{expr: "map[string]interface{}", nilable: true, nullable: nullable}
+		}
+		item := g.goType(value, suffixed(candidates, "Value"), path+".*")
+		return goTypeInfo{expr: "map[string]" + pointerIfNullable(item), nilable: true, nullable: nullable}
+	case goScalar:
+		t, _ := types(s)
+		return goTypeInfo{expr: goScalars[t[0]], nullable: nullable}
+	}
+	return goTypeInfo{expr: "interface{}", nilable: true, nullable: nullable}
+}
+
+func pointerIfNullable(info goTypeInfo) string {
+	if info.nullable && !info.nilable {
+		return "*" + info.expr
+	}
+	return info.expr
+}
+
+func suffixed(candidates []string, suffix string) []string {
+	result := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		result[i] = candidate + suffix
+	}
+	return result
+}
+
+// definition declares the type of a definition once and returns it
+func (g *goGenerator) definition(name string) *goDefinition {
+	definition := g.definitions[name]
+	if definition.declared {
+		return definition
+	}
+	definition.declared = true
+	definition.pending = true
+	defer func() { definition.pending = false }()
+
+	s := g.flatten(g.root.Definitions[name])
+	path := "definitions." + name
+	switch g.kind(s) {
+	case goStruct:
+		g.declareStruct(definition.name, s, path)
+	case goEnum:
+		g.declareEnum(definition.name, s, path)
+	default:
+		index := g.reserve()
+		info := g.goType(s, []string{definition.name + "Value"}, path)
+		g.decls[index] = fmt.Sprintf("%s\ntype %s %s\n", typeComment(definition.name, s, path), definition.name, info.expr)
+	}
+	return definition
+}
+
+// reserve keeps the place of a declaration, so types are declared before the
+// types nested in them
+func (g *goGenerator) reserve() int {
+	g.decls = append(g.decls, "")
+	return len(g.decls) - 1
+}
+
+func (g *goGenerator) declareStruct(name string, s *schema.Schema, path string) {
+	index := g.reserve()
+
+	var decl strings.Builder
+	decl.WriteString(typeComment(name, s, path))
+	fmt.Fprintf(&decl, "\ntype %s struct {\n", name)
+	fields := names{}
+	for _, key := range sortedKeys(s.Properties) {
+		property := s.Properties[key]
+		keyName := exportedName(key, true)
+		info := g.goType(property, []string{keyName, name + keyName}, joinPath(path, key))
+
+		required := false
+		for _, requiredKey := range s.Required.Strings {
+			required = required || requiredKey == key
+		}
+		expr := info.expr
+		if !info.nilable && (!required || info.nullable || info.pending) {
+			expr = "*" + expr
+		}
+		tag := key
+		if !required {
+			tag += ",omitempty"
+		}
+
+		for _, line := range g.fieldComment(property) {
+			decl.WriteString("\t//")
+			if line != "" {
+				decl.WriteString(" " + line)
+			}
+			decl.WriteString("\n")
+		}
+		fmt.Fprintf(&decl, "\t%s %s `json:%s yaml:%s`\n", fields.unique(keyName, keyName+"Value"), expr, strconv.Quote(tag), strconv.Quote(tag))
+	}
+	decl.WriteString("}\n")
+	g.decls[index] = decl.String()
+}
+
+func (g *goGenerator) declareEnum(name string, s *schema.Schema, path string) {
+	t, _ := types(s)
+	base := enumBase(s.Enum, t)
+
+	var decl strings.Builder
+	decl.WriteString(typeComment(name, s, path))
+	fmt.Fprintf(&decl, "\ntype %s %s\n\n", name, goScalars[base])
+	fmt.Fprintf(&decl, "// Allowed values of %s\nconst (\n", name)
+	for i, value := range s.Enum {
+		if value == nil {
+			continue
+		}
+		suffix := strconv.Itoa(i)
+		if valueWords := words(fmt.Sprint(value)); len(valueWords) > 0 {
+			// Numbers are appended as they are, e.g. Port8080
+			suffix = strings.TrimPrefix(exportedName(fmt.Sprint(value), true), "X")
+		}
+		fmt.Fprintf(&decl, "\t%s %s = %s\n", g.names.unique(name+suffix), name, jsonValue(value))
+	}
+	decl.WriteString(")\n")
+	g.decls = append(g.decls, decl.String())
+}
+
+// fieldComment returns the lines of the doc comment of a field
+func (g *goGenerator) fieldComment(s *schema.Schema) []string {
+	description := s.Description
+	if name, ok := definitionName(g.root, s.Ref); ok && description == "" {
+		description = g.root.Definitions[name].Description
+	}
+	lines := commentLines(description)
+	if s.Default != nil {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "Default: "+jsonValue(s.Default))
+	}
+	if s.Deprecated {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "Deprecated: this key should no longer be used.")
+	}
+	return lines
+}
+
+func typeComment(name string, s *schema.Schema, path string) string {
+	subject := path
+	if subject == "" {
+		subject = "the chart"
+	}
+	verb := "holds the values of"
+	if len(s.Enum) > 0 {
+		verb = "is one of the allowed values of"
+	}
+	return fmt.Sprintf("// %s %s %s.", name, verb, subject)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,136 @@
+package codegen
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateGo(t *testing.T) {
+	var values schema.Schema
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["image", "replicas"],
+		"properties": {
+			"replicas": {"type": "integer", "description": "Number of replicas\nof the deployment", "default": 1},
+			"image": {
+				"type": "object",
+				"required": ["repository"],
+				"properties": {
+					"repository": {"type": "string"},
+					"tag": {"type": ["string", "null"]},
+					"pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent"]}
+				}
+			},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"extra": {"type": "object"},
+			"hosts": {"type": "array", "items": {"anyOf": [
+				{"type": "object", "required": ["host"], "properties": {"host": {"type": "string"}}},
+				{"type": "object", "required": ["host"], "properties": {"host": {"type": "string"}, "paths": {"type": "array", "items": {"type": "string"}}}}
+			]}},
+			"legacy": {"type": "boolean", "deprecated": true},
+			"anything": {"type": ["string", "integer"]},
+			"tree": {"$ref": "#/definitions/node"},
+			"port": {"$ref": "#/definitions/port"}
+		},
+		"definitions": {
+			"node": {"type": "object", "description": "A node of a tree", "required": ["parent"], "properties": {
+				"parent": {"$ref": "#/definitions/node"},
+				"children": {"type": "array", "items": {"$ref": "#/definitions/node"}}
+			}},
+			"port": {"type": "integer", "minimum": 1}
+		}
+	}`), &values))
+
+	code, err := Generate(LanguageGo, &values, Options{Package: "config"})
+	assert.NoError(t, err)
+	// Struct tags are quoted with ' instead of backticks
+	assert.Equal(t, strings.ReplaceAll(`// Code generated by helm-schema. DO NOT EDIT.
+
+package config
+
+// Values holds the values of the chart.
+type Values struct {
+	Anything interface{}            'json:"anything,omitempty" yaml:"anything,omitempty"'
+	Extra    map[string]interface{} 'json:"extra,omitempty" yaml:"extra,omitempty"'
+	Hosts    []HostsItem            'json:"hosts,omitempty" yaml:"hosts,omitempty"'
+	Image    Image                  'json:"image" yaml:"image"'
+	Labels   map[string]string      'json:"labels,omitempty" yaml:"labels,omitempty"'
+	// Deprecated: this key should no longer be used.
+	Legacy *bool 'json:"legacy,omitempty" yaml:"legacy,omitempty"'
+	Port   *Port 'json:"port,omitempty" yaml:"port,omitempty"'
+	// Number of replicas
+	// of the deployment
+	//
+	// Default: 1
+	Replicas int64 'json:"replicas" yaml:"replicas"'
+	// A node of a tree
+	Tree *Node 'json:"tree,omitempty" yaml:"tree,omitempty"'
+}
+
+// HostsItem holds the values of hosts[].
+type HostsItem struct {
+	Host  string   'json:"host" yaml:"host"'
+	Paths []string 'json:"paths,omitempty" yaml:"paths,omitempty"'
+}
+
+// Image holds the values of image.
+type Image struct {
+	PullPolicy *PullPolicy 'json:"pullPolicy,omitempty" yaml:"pullPolicy,omitempty"'
+	Repository string      'json:"repository" yaml:"repository"'
+	Tag        *string     'json:"tag,omitempty" yaml:"tag,omitempty"'
+}
+
+// PullPolicy is one of the allowed values of image.pullPolicy.
+type PullPolicy string
+
+// Allowed values of PullPolicy
+const (
+	PullPolicyAlways       PullPolicy = "Always"
+	PullPolicyIfNotPresent PullPolicy = "IfNotPresent"
+)
+
+// Port holds the values of definitions.port.
+type Port int64
+
+// Node holds the values of definitions.node.
+type Node struct {
+	Children []Node 'json:"children,omitempty" yaml:"children,omitempty"'
+	// A node of a tree
+	Parent *Node 'json:"parent" yaml:"parent"'
+}
+`, "'", "`"), string(code))
+}
+
+func TestGenerateGo_Names(t *testing.T) {
+	var values schema.Schema
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"values": {"type": "object", "properties": {"port": {"type": "integer", "enum": [80, 443]}}},
+			"service": {"type": "object", "properties": {"port": {"type": "integer", "enum": [8080]}}}
+		}
+	}`), &values))
+
+	code, err := Generate(LanguageGo, &values, Options{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(code), "package values\n")
+	// Nested types make way for the root type and each other
+	assert.Contains(t, string(code), "\ntype ValuesValues struct {\n")
+	assert.Contains(t, string(code), "\tService *Service ")
+	assert.Contains(t, string(code), "\ntype Port int64\n")
+	assert.Contains(t, string(code), "\ntype ValuesValuesPort int64\n")
+	assert.Contains(t, string(code), "\tPort8080 Port = 8080\n")
+	assert.Contains(t, string(code), "\tValuesValuesPort80  ValuesValuesPort = 80\n")
+}
+
+func TestGenerateGo_InvalidOptions(t *testing.T) {
+	_, err := Generate(LanguageGo, &schema.Schema{}, Options{Package: "my-values"})
+	assert.EqualError(t, err, "invalid package name my-values")
+
+	_, err = Generate(LanguageGo, &schema.Schema{}, Options{TypeName: "values"})
+	assert.EqualError(t, err, "invalid type name values, must be an exported identifier")
+}
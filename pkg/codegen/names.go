@@ -0,0 +1,90 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// initialisms are written in upper case in Go names, e.g. ServiceURL
+var initialisms = map[string]bool{
+	"API": true, "CPU": true, "DNS": true, "GPU": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "OIDC": true, "SQL": true, "SSH": true,
+	"TCP": true, "TLS": true, "UDP": true, "UID": true, "URI": true, "URL": true,
+	"UUID": true, "YAML": true,
+}
+
+// words splits a key into words at non-alphanumeric characters and at case
+// changes, e.g. "podSecurity-context" into pod, Security, context
+func words(key string) []string {
+	var result []string
+	var current []rune
+	runes := []rune(key)
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, string(current))
+			current = nil
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			previous := current[len(current)-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return result
+}
+
+// exportedName converts a key into an exported identifier, e.g. "service-url"
+// into ServiceURL. Initialisms are only used if goStyle is set.
+func exportedName(key string, goStyle bool) string {
+	var name strings.Builder
+	for _, word := range words(key) {
+		if upper := strings.ToUpper(word); goStyle && initialisms[upper] {
+			name.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		name.WriteRune(unicode.ToUpper(runes[0]))
+		name.WriteString(string(runes[1:]))
+	}
+	result := name.String()
+	if result == "" {
+		return "Value"
+	}
+	if unicode.IsDigit([]rune(result)[0]) {
+		return "X" + result
+	}
+	return result
+}
+
+// names hands out unique names
+type names map[string]bool
+
+// unique returns the first of the candidates which isn't taken yet, or the
+// last one with a number
+func (n names) unique(candidates ...string) string {
+	for _, candidate := range candidates {
+		if !n[candidate] {
+			n[candidate] = true
+			return candidate
+		}
+	}
+	last := candidates[len(candidates)-1]
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s%d", last, i)
+		if !n[candidate] {
+			n[candidate] = true
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWords(t *testing.T) {
+	assert.Equal(t, []string{"pod", "Security", "context"}, words("podSecurity-context"))
+	assert.Equal(t, []string{"service", "URL"}, words("serviceURL"))
+	assert.Equal(t, []string{"HTTP", "Proxy"}, words("HTTPProxy"))
+	assert.Equal(t, []string{"ipv4", "Address"}, words("ipv4Address"))
+	assert.Empty(t, words("--"))
+}
+
+func TestExportedName(t *testing.T) {
+	tests := []struct {
+		key      string
+		goStyle  bool
+		expected string
+	}{
+		{key: "replicaCount", goStyle: true, expected: "ReplicaCount"},
+		{key: "service-url", goStyle: true, expected: "ServiceURL"},
+		{key: "service-url", goStyle: false, expected: "ServiceUrl"},
+		{key: "podSecurityContext", goStyle: true, expected: "PodSecurityContext"},
+		{key: "api_key", goStyle: true, expected: "APIKey"},
+		{key: "1st", goStyle: true, expected: "X1st"},
+		{key: "", goStyle: true, expected: "Value"},
+		{key: "ümlaut", goStyle: true, expected: "Ümlaut"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.expected, exportedName(tt.key, tt.goStyle))
+		})
+	}
+}
+
+func TestNamesUnique(t *testing.T) {
+	n := names{}
+	assert.Equal(t, "Image", n.unique("Image", "ValuesImage"))
+	assert.Equal(t, "ValuesImage", n.unique("Image", "ValuesImage"))
+	assert.Equal(t, "ValuesImage2", n.unique("Image", "ValuesImage"))
+	assert.Equal(t, "ValuesImage3", n.unique("ValuesImage"))
+}
@@ -0,0 +1,325 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+)
+
+var tsIdentifierRegex = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+var tsPrimitives = map[string]string{
+	"string":  "string",
+	"integer": "number",
+	"number":  "number",
+	"boolean": "boolean",
+	"null":    "null",
+}
+
+type tsGenerator struct {
+	root        *schema.Schema
+	names       names
+	decls       []string
+	definitions map[string]string
+}
+
+func generateTypeScript(root *schema.Schema, options Options) ([]byte, error) {
+	if !tsIdentifierRegex.MatchString(options.TypeName) {
+		return nil, fmt.Errorf("invalid type name %s, must be an identifier", options.TypeName)
+	}
+
+	g := &tsGenerator{
+		root:        root,
+		names:       names{},
+		definitions: map[string]string{},
+	}
+	rootName := g.names.unique(options.TypeName)
+	// Definitions keep their names, nested types have to make way
+	for _, name := range sortedKeys(root.Definitions) {
+		typeName := exportedName(name, false)
+		g.definitions[name] = g.names.unique(typeName, typeName+"Definition")
+	}
+
+	g.declareInterface(rootName, root, "")
+	for _, name := range sortedKeys(root.Definitions) {
+		g.declareDefinition(name)
+	}
+
+	var source strings.Builder
+	source.WriteString("// Code generated by helm-schema. DO NOT EDIT.\n")
+	for _, decl := range g.decls {
+		source.WriteString("\n")
+		source.WriteString(decl)
+	}
+	return []byte(source.String()), nil
+}
+
+// reserve keeps the place of a declaration, so types are declared before the
+// types nested in them
+func (g *tsGenerator) reserve() int {
+	g.decls = append(g.decls, "")
+	return len(g.decls) - 1
+}
+
+func (g *tsGenerator) declareDefinition(name string) {
+	typeName := g.definitions[name]
+	s := g.root.Definitions[name]
+	path := "definitions." + name
+	if len(s.Properties) > 0 && len(s.AnyOf) == 0 && len(s.OneOf) == 0 && len(s.AllOf) == 0 {
+		g.declareInterface(typeName, s, path)
+		return
+	}
+	index := g.reserve()
+	expr := g.tsType(s, []string{typeName + "Value"}, path)
+	g.decls[index] = fmt.Sprintf("%sexport type %s = %s;\n", jsDoc(definitionComment(s, path), ""), typeName, expr)
+}
+
+func (g *tsGenerator) declareInterface(name string, s *schema.Schema, path string) {
+	index := g.reserve()
+
+	var decl strings.Builder
+	decl.WriteString(jsDoc(definitionComment(s, path), ""))
+	fmt.Fprintf(&decl, "export interface %s {\n", name)
+	var propertyTypes []string
+	for _, key := range sortedKeys(s.Properties) {
+		property := s.Properties[key]
+		keyName := exportedName(key, false)
+		expr := g.tsType(property, []string{keyName, name + keyName}, joinPath(path, key))
+		propertyTypes = append(propertyTypes, expr)
+
+		optional := "?"
+		for _, requiredKey := range s.Required.Strings {
+			if requiredKey == key {
+				optional = ""
+			}
+		}
+		decl.WriteString(jsDoc(g.comment(property), "  "))
+		fmt.Fprintf(&decl, "  %s%s: %s;\n", tsKey(key), optional, expr)
+	}
+	if expr, ok := g.indexType(s, []string{name}, path); ok {
+		// The properties have to match the index signature as well
+		if expr != "unknown" {
+			expr = tsUnion(append([]string{expr}, propertyTypes...))
+		}
+		fmt.Fprintf(&decl, "  [key: string]: %s;\n", expr)
+	}
+	decl.WriteString("}\n")
+	g.decls[index] = decl.String()
+}
+
+// tsType returns the TypeScript type of the schema. Nested interfaces are
+// declared with the first free name of the candidates.
+func (g *tsGenerator) tsType(s *schema.Schema, candidates []string, path string) string {
+	if s.Ref != "" {
+		if name, ok := definitionName(g.root, s.Ref); ok {
+			return g.definitions[name]
+		}
+		return "unknown"
+	}
+
+	var parts []string
+	if base := g.baseType(s, candidates, path); base != "" {
+		parts = append(parts, base)
+	}
+	for _, junctor := range [][]*schema.Schema{s.AnyOf, s.OneOf} {
+		var branches []string
+		for _, branch := range junctor {
+			if branch != nil {
+				branches = append(branches, g.tsType(branch, candidates, path))
+			}
+		}
+		if len(branches) > 0 {
+			parts = append(parts, tsUnion(branches))
+		}
+	}
+	for _, branch := range s.AllOf {
+		if branch != nil {
+			parts = append(parts, g.tsType(branch, candidates, path))
+		}
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return tsIntersection(parts)
+}
+
+// baseType returns the type given by const, enum, type or properties
+func (g *tsGenerator) baseType(s *schema.Schema, candidates []string, path string) string {
+	if s.Const != nil {
+		return jsonValue(s.Const)
+	}
+	if len(s.Enum) > 0 {
+		literals := make([]string, len(s.Enum))
+		for i, value := range s.Enum {
+			literals[i] = jsonValue(value)
+		}
+		return tsUnion(literals)
+	}
+
+	t := []string(s.Type)
+	if len(t) == 0 && len(s.Properties) > 0 {
+		t = []string{"object"}
+	}
+	var result []string
+	for _, typeName := range t {
+		switch typeName {
+		case "array":
+			result = append(result, g.arrayType(s, candidates, path))
+		case "object":
+			result = append(result, g.objectType(s, candidates, path))
+		default:
+			if primitive, ok := tsPrimitives[typeName]; ok {
+				result = append(result, primitive)
+			}
+		}
+	}
+	return tsUnion(result)
+}
+
+func (g *tsGenerator) arrayType(s *schema.Schema, candidates []string, path string) string {
+	item := "unknown"
+	if s.Items != nil {
+		item = g.tsType(s.Items, suffixed(candidates, "Item"), path+"[]")
+	}
+	if len(s.PrefixItems) == 0 {
+		return tsParenthesize(item) + "[]"
+	}
+
+	var elements []string
+	for _, prefixItem := range s.PrefixItems {
+		elements = append(elements, g.tsType(prefixItem, suffixed(candidates, "Item"), path+"[]"))
+	}
+	if s.Items == nil {
+		if additional, allowed := additionalSchema(s.AdditionalItems); additional != nil {
+			item = g.tsType(additional, suffixed(candidates, "Item"), path+"[]")
+		} else if !allowed {
+			item = ""
+		}
+	}
+	if item != "" {
+		elements = append(elements, "..."+tsParenthesize(item)+"[]")
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func (g *tsGenerator) objectType(s *schema.Schema, candidates []string, path string) string {
+	if len(s.Properties) > 0 {
+		name := g.names.unique(candidates...)
+		g.declareInterface(name, s, path)
+		return name
+	}
+	if expr, ok := g.indexType(s, candidates, path); ok {
+		return fmt.Sprintf("{ [key: string]: %s }", expr)
+	}
+	return "Record<string, never>"
+}
+
+// indexType returns the type of the index signature given by
+// additionalProperties and patternProperties, or false if there are no
+// additional keys
+func (g *tsGenerator) indexType(s *schema.Schema, candidates []string, path string) (string, bool) {
+	additional, allowed := additionalSchema(s.AdditionalProperties)
+	if allowed && additional == nil {
+		return "unknown", true
+	}
+
+	var result []string
+	if additional != nil {
+		result = append(result, g.tsType(additional, suffixed(candidates, "Value"), path+".*"))
+	}
+	for _, pattern := range sortedKeys(s.PatternProperties) {
+		result = append(result, g.tsType(s.PatternProperties[pattern], suffixed(candidates, "Value"), path+".*"))
+	}
+	if len(result) == 0 {
+		return "", false
+	}
+	return tsUnion(result), true
+}
+
+// comment returns the lines of the JSDoc of a property
+func (g *tsGenerator) comment(s *schema.Schema) []string {
+	description := s.Description
+	if name, ok := definitionName(g.root, s.Ref); ok && description == "" {
+		description = g.root.Definitions[name].Description
+	}
+	lines := commentLines(description)
+	var tags []string
+	if s.Default != nil {
+		tags = append(tags, "@default "+jsonValue(s.Default))
+	}
+	for _, example := range s.Examples {
+		tags = append(tags, "@example "+jsonValue(example))
+	}
+	if s.Deprecated {
+		tags = append(tags, "@deprecated")
+	}
+	if len(lines) > 0 && len(tags) > 0 {
+		lines = append(lines, "")
+	}
+	return append(lines, tags...)
+}
+
+// jsDoc returns the lines as a JSDoc comment
+func jsDoc(lines []string, indent string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	for i, line := range lines {
+		lines[i] = strings.ReplaceAll(line, "*/", "*\\/")
+	}
+	if len(lines) == 1 {
+		return fmt.Sprintf("%s/** %s */\n", indent, lines[0])
+	}
+	var doc strings.Builder
+	doc.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		doc.WriteString(strings.TrimRight(indent+" * "+line, " ") + "\n")
+	}
+	doc.WriteString(indent + " */\n")
+	return doc.String()
+}
+
+// tsKey quotes keys which aren't identifiers
+func tsKey(key string) string {
+	if tsIdentifierRegex.MatchString(key) {
+		return key
+	}
+	return jsonValue(key)
+}
+
+// tsUnion joins the distinct types with |
+func tsUnion(types []string) string {
+	var distinct []string
+	seen := map[string]bool{}
+	for _, t := range types {
+		if t == "unknown" {
+			return t
+		}
+		if !seen[t] {
+			seen[t] = true
+			distinct = append(distinct, t)
+		}
+	}
+	return strings.Join(distinct, " | ")
+}
+
+func tsIntersection(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = tsParenthesize(t)
+	}
+	return strings.Join(parts, " & ")
+}
+
+// tsParenthesize wraps unions and intersections in parentheses
+func tsParenthesize(t string) string {
+	if strings.Contains(t, " | ") || strings.Contains(t, " & ") {
+		return "(" + t + ")"
+	}
+	return t
+}
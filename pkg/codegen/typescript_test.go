@@ -0,0 +1,134 @@
+package codegen
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateTypeScript(t *testing.T) {
+	var values schema.Schema
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["image", "replicas"],
+		"additionalProperties": false,
+		"properties": {
+			"replicas": {"type": "integer", "description": "Number of replicas\nof the deployment", "default": 1, "examples": [3]},
+			"image": {
+				"type": "object",
+				"required": ["repository"],
+				"additionalProperties": false,
+				"properties": {
+					"repository": {"type": "string"},
+					"tag": {"type": ["string", "null"]},
+					"pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent"]}
+				}
+			},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"env": {"type": "object", "additionalProperties": false, "patternProperties": {"^[A-Z_]+$": {"type": "string"}}},
+			"extra": {"type": "object", "properties": {"enabled": {"type": "boolean"}}},
+			"hosts": {"type": "array", "items": {"anyOf": [
+				{"type": "string"},
+				{"type": "object", "additionalProperties": false, "properties": {"host": {"type": "string"}}}
+			]}},
+			"legacy": {"type": "boolean", "deprecated": true, "description": "Don't use */ here"},
+			"mode": {"const": "fast"},
+			"pair": {"type": "array", "prefixItems": [{"type": "string"}, {"type": "integer"}], "additionalItems": false},
+			"pod-annotations": {"type": "object", "additionalProperties": {"type": "string"}, "properties": {"replicas": {"type": "integer"}}},
+			"anything": {},
+			"port": {"$ref": "#/definitions/port"},
+			"tree": {"$ref": "#/definitions/node"}
+		},
+		"definitions": {
+			"node": {"type": "object", "additionalProperties": false, "properties": {
+				"children": {"type": "array", "items": {"$ref": "#/definitions/node"}}
+			}},
+			"port": {"type": "integer", "enum": [80, 443], "description": "A port"}
+		}
+	}`), &values))
+
+	code, err := Generate(LanguageTypeScript, &values, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, `// Code generated by helm-schema. DO NOT EDIT.
+
+/** The values of the chart. */
+export interface Values {
+  anything?: unknown;
+  env?: { [key: string]: string };
+  extra?: Extra;
+  hosts?: (string | HostsItem)[];
+  image: Image;
+  labels?: { [key: string]: string };
+  /**
+   * Don't use *\/ here
+   *
+   * @deprecated
+   */
+  legacy?: boolean;
+  mode?: "fast";
+  pair?: [string, number];
+  "pod-annotations"?: PodAnnotations;
+  /** A port */
+  port?: Port;
+  /**
+   * Number of replicas
+   * of the deployment
+   *
+   * @default 1
+   * @example 3
+   */
+  replicas: number;
+  tree?: Node;
+}
+
+/** The values of extra. */
+export interface Extra {
+  enabled?: boolean;
+  [key: string]: unknown;
+}
+
+/** The values of hosts[]. */
+export interface HostsItem {
+  host?: string;
+}
+
+/** The values of image. */
+export interface Image {
+  pullPolicy?: "Always" | "IfNotPresent";
+  repository: string;
+  tag?: string | null;
+}
+
+/** The values of pod-annotations. */
+export interface PodAnnotations {
+  replicas?: number;
+  [key: string]: string | number;
+}
+
+/** The values of definitions.node. */
+export interface Node {
+  children?: Node[];
+}
+
+/** A port */
+export type Port = 80 | 443;
+`, string(code))
+
+	_, err = Generate(LanguageTypeScript, &values, Options{TypeName: "my-values"})
+	assert.EqualError(t, err, "invalid type name my-values, must be an identifier")
+}
+
+func TestJSDoc(t *testing.T) {
+	assert.Equal(t, "", jsDoc(nil, "  "))
+	assert.Equal(t, "  /** single */\n", jsDoc([]string{"single"}, "  "))
+	assert.Equal(t, "/**\n * first\n *\n * second\n */\n", jsDoc([]string{"first", "", "second"}, ""))
+}
+
+func TestTSKey(t *testing.T) {
+	assert.Equal(t, "replicas", tsKey("replicas"))
+	assert.Equal(t, "$schema", tsKey("$schema"))
+	assert.Equal(t, `"pod-annotations"`, tsKey("pod-annotations"))
+	assert.Equal(t, `"1st"`, tsKey("1st"))
+}
@@ -0,0 +1,212 @@
+package crd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Scopes of a custom resource
+const (
+	ScopeNamespaced = "Namespaced"
+	ScopeCluster    = "Cluster"
+)
+
+var (
+	groupRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)+$`)
+	kindRegex  = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	// labelRegex matches DNS-1035 labels, which are used for versions and names
+	labelRegex = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// Options of the CustomResourceDefinition
+type Options struct {
+	// Group is the API group, e.g. example.com
+	Group string
+	// Kind of the custom resource, e.g. MyApp
+	Kind string
+	// Version of the API, e.g. v1alpha1
+	Version string
+	// Plural name of the resource, defaults to the lowercase kind + s
+	Plural string
+	// Scope is Namespaced or Cluster
+	Scope string
+}
+
+// Validate returns an error if the options can't be used for a
+// CustomResourceDefinition. Missing plural and scope are defaulted.
+func (o *Options) Validate() error {
+	if o.Group == "" {
+		return errors.New("group is required")
+	}
+	if !groupRegex.MatchString(o.Group) {
+		return fmt.Errorf("invalid group %s, must be a lowercase domain with at least one dot", o.Group)
+	}
+	if !kindRegex.MatchString(o.Kind) {
+		return fmt.Errorf("invalid kind %s, must be alphanumeric and start with an uppercase letter", o.Kind)
+	}
+	if !labelRegex.MatchString(o.Version) {
+		return fmt.Errorf("invalid version %s, must be a lowercase DNS label like v1alpha1", o.Version)
+	}
+	if o.Plural == "" {
+		o.Plural = strings.ToLower(o.Kind) + "s"
+	}
+	if !labelRegex.MatchString(o.Plural) {
+		return fmt.Errorf("invalid plural %s, must be a lowercase DNS label", o.Plural)
+	}
+	if o.Scope == "" {
+		o.Scope = ScopeNamespaced
+	}
+	if o.Scope != ScopeNamespaced && o.Scope != ScopeCluster {
+		return fmt.Errorf("invalid scope %s, must be one of (%s, %s)", o.Scope, ScopeNamespaced, ScopeCluster)
+	}
+	return nil
+}
+
+// KindFromName returns a kind for a chart name, e.g. MyApp for my-app
+func KindFromName(name string) string {
+	var kind strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if kind.Len() == 0 && !unicode.IsLetter(r) {
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		kind.WriteRune(r)
+	}
+	return kind.String()
+}
+
+// CustomResourceDefinition of apiextensions.k8s.io/v1
+type CustomResourceDefinition struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Metadata   Metadata `yaml:"metadata"`
+	Spec       Spec     `yaml:"spec"`
+}
+
+// Metadata of the CustomResourceDefinition
+type Metadata struct {
+	Name string `yaml:"name"`
+}
+
+// Spec of the CustomResourceDefinition
+type Spec struct {
+	Group    string    `yaml:"group"`
+	Names    Names     `yaml:"names"`
+	Scope    string    `yaml:"scope"`
+	Versions []Version `yaml:"versions"`
+}
+
+// Names of the custom resource
+type Names struct {
+	Kind     string `yaml:"kind"`
+	ListKind string `yaml:"listKind"`
+	Plural   string `yaml:"plural"`
+	Singular string `yaml:"singular"`
+}
+
+// Version of the custom resource
+type Version struct {
+	Name         string        `yaml:"name"`
+	Served       bool          `yaml:"served"`
+	Storage      bool          `yaml:"storage"`
+	Schema       VersionSchema `yaml:"schema"`
+	Subresources Subresources  `yaml:"subresources"`
+}
+
+// VersionSchema holds the schema of a version
+type VersionSchema struct {
+	OpenAPIV3Schema *JSONSchemaProps `yaml:"openAPIV3Schema"`
+}
+
+// Subresources of a version
+type Subresources struct {
+	Status map[string]interface{} `yaml:"status"`
+}
+
+// New returns a CustomResourceDefinition whose spec is the values schema, as
+// used by helm based operators. The status is not validated. The warnings
+// name the keywords of the values schema which are dropped.
+func New(values *schema.Schema, options Options) (*CustomResourceDefinition, []string, error) {
+	if err := options.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	spec, warnings := Structural(values)
+	spec.Title = ""
+	if spec.Description == "" {
+		spec.Description = fmt.Sprintf("Spec holds the values of the %s", options.Kind)
+	}
+
+	status := anyValue()
+	status.Type = "object"
+	status.Description = fmt.Sprintf("Status of the %s", options.Kind)
+
+	root := &JSONSchemaProps{
+		Type:        "object",
+		Description: fmt.Sprintf("%s is the Schema for the %s API", options.Kind, options.Plural),
+		Properties: map[string]*JSONSchemaProps{
+			"apiVersion": {Type: "string", Description: "APIVersion defines the versioned schema of this representation of an object."},
+			"kind":       {Type: "string", Description: "Kind is a string value representing the REST resource this object represents."},
+			"metadata":   {Type: "object"},
+			"spec":       spec,
+			"status":     status,
+		},
+	}
+
+	return &CustomResourceDefinition{
+		APIVersion: "apiextensions.k8s.io/v1",
+		Kind:       "CustomResourceDefinition",
+		Metadata:   Metadata{Name: options.Plural + "." + options.Group},
+		Spec: Spec{
+			Group: options.Group,
+			Names: Names{
+				Kind:     options.Kind,
+				ListKind: options.Kind + "List",
+				Plural:   options.Plural,
+				Singular: strings.ToLower(options.Kind),
+			},
+			Scope: options.Scope,
+			Versions: []Version{{
+				Name:         options.Version,
+				Served:       true,
+				Storage:      true,
+				Schema:       VersionSchema{OpenAPIV3Schema: root},
+				Subresources: Subresources{Status: map[string]interface{}{}},
+			}},
+		},
+	}, warnings, nil
+}
+
+// ToYaml returns the manifest of the CustomResourceDefinition
+func (c *CustomResourceDefinition) ToYaml() ([]byte, error) {
+	return toYaml(c)
+}
+
+// toYaml encodes the value with the indentation of kubernetes manifests
+func toYaml(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,97 @@
+package crd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	var values schema.Schema
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "object", "title": "Values", "additionalProperties": false, "properties": {"replicas": {"type": "integer", "default": 1}}}`), &values))
+
+	definition, warnings, err := New(&values, Options{Group: "example.com", Kind: "MyApp", Version: "v1alpha1"})
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	manifest, err := definition.ToYaml()
+	assert.NoError(t, err)
+	assert.Equal(t, `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: myapps.example.com
+spec:
+  group: example.com
+  names:
+    kind: MyApp
+    listKind: MyAppList
+    plural: myapps
+    singular: myapp
+  scope: Namespaced
+  versions:
+    - name: v1alpha1
+      served: true
+      storage: true
+      schema:
+        openAPIV3Schema:
+          type: object
+          description: MyApp is the Schema for the myapps API
+          properties:
+            apiVersion:
+              type: string
+              description: APIVersion defines the versioned schema of this representation of an object.
+            kind:
+              type: string
+              description: Kind is a string value representing the REST resource this object represents.
+            metadata:
+              type: object
+            spec:
+              type: object
+              description: Spec holds the values of the MyApp
+              properties:
+                replicas:
+                  type: integer
+                  default: 1
+            status:
+              type: object
+              description: Status of the MyApp
+              x-kubernetes-preserve-unknown-fields: true
+      subresources:
+        status: {}
+`, string(manifest))
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		err     string
+	}{
+		{name: "valid", options: Options{Group: "example.com", Kind: "MyApp", Version: "v1", Scope: ScopeCluster}},
+		{name: "missing group", options: Options{Kind: "MyApp", Version: "v1"}, err: "group is required"},
+		{name: "group without dot", options: Options{Group: "example", Kind: "MyApp", Version: "v1"}, err: "invalid group example, must be a lowercase domain with at least one dot"},
+		{name: "lowercase kind", options: Options{Group: "example.com", Kind: "myapp", Version: "v1"}, err: "invalid kind myapp, must be alphanumeric and start with an uppercase letter"},
+		{name: "invalid version", options: Options{Group: "example.com", Kind: "MyApp", Version: "V1"}, err: "invalid version V1, must be a lowercase DNS label like v1alpha1"},
+		{name: "invalid scope", options: Options{Group: "example.com", Kind: "MyApp", Version: "v1", Scope: "Global"}, err: "invalid scope Global, must be one of (Namespaced, Cluster)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestKindFromName(t *testing.T) {
+	assert.Equal(t, "MyApp", KindFromName("my-app"))
+	assert.Equal(t, "Redis", KindFromName("redis"))
+	assert.Equal(t, "KubePrometheusStack", KindFromName("kube_prometheus.stack"))
+	assert.Equal(t, "App2", KindFromName("1-app2"))
+}
@@ -0,0 +1,565 @@
+package crd
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+)
+
+// Extensions of apiextensions which are set by the converter
+const (
+	PreserveUnknownFields = "x-kubernetes-preserve-unknown-fields"
+	IntOrString           = "x-kubernetes-int-or-string"
+	ListType              = "x-kubernetes-list-type"
+)
+
+// JSONSchemaProps is the OpenAPI v3 schema of apiextensions.k8s.io/v1
+type JSONSchemaProps struct {
+	Type                 string                      `yaml:"type,omitempty"`
+	Format               string                      `yaml:"format,omitempty"`
+	Title                string                      `yaml:"title,omitempty"`
+	Description          string                      `yaml:"description,omitempty"`
+	Nullable             bool                        `yaml:"nullable,omitempty"`
+	Default              interface{}                 `yaml:"default,omitempty"`
+	Example              interface{}                 `yaml:"example,omitempty"`
+	Enum                 []interface{}               `yaml:"enum,omitempty"`
+	Minimum              *float64                    `yaml:"minimum,omitempty"`
+	ExclusiveMinimum     bool                        `yaml:"exclusiveMinimum,omitempty"`
+	Maximum              *float64                    `yaml:"maximum,omitempty"`
+	ExclusiveMaximum     bool                        `yaml:"exclusiveMaximum,omitempty"`
+	MultipleOf           *float64                    `yaml:"multipleOf,omitempty"`
+	MinLength            *int                        `yaml:"minLength,omitempty"`
+	MaxLength            *int                        `yaml:"maxLength,omitempty"`
+	Pattern              string                      `yaml:"pattern,omitempty"`
+	MinItems             *int                        `yaml:"minItems,omitempty"`
+	MaxItems             *int                        `yaml:"maxItems,omitempty"`
+	MinProperties        *int                        `yaml:"minProperties,omitempty"`
+	MaxProperties        *int                        `yaml:"maxProperties,omitempty"`
+	Required             []string                    `yaml:"required,omitempty"`
+	Properties           map[string]*JSONSchemaProps `yaml:"properties,omitempty"`
+	AdditionalProperties *JSONSchemaProps            `yaml:"additionalProperties,omitempty"`
+	Items                *JSONSchemaProps            `yaml:"items,omitempty"`
+	AllOf                []*JSONSchemaProps          `yaml:"allOf,omitempty"`
+	AnyOf                []*JSONSchemaProps          `yaml:"anyOf,omitempty"`
+	OneOf                []*JSONSchemaProps          `yaml:"oneOf,omitempty"`
+	Not                  *JSONSchemaProps            `yaml:"not,omitempty"`
+	// Extensions holds the x-kubernetes-* fields
+	Extensions map[string]interface{} `yaml:",inline"`
+}
+
+func (p *JSONSchemaProps) setExtension(name string, value interface{}) {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[name] = value
+}
+
+func (p *JSONSchemaProps) preservesUnknownFields() bool {
+	preserve, _ := p.Extensions[PreserveUnknownFields].(bool)
+	return preserve
+}
+
+// Structural converts the schema into a structural schema as required by
+// CustomResourceDefinitions: references are inlined, open objects preserve
+// unknown fields, null types become nullable and allOf, anyOf, oneOf and not
+// only contain value validations of fields which are specified outside of
+// them. Keywords which can't be expressed are dropped, a warning is returned
+// for each of them.
+func Structural(root *schema.Schema) (*JSONSchemaProps, []string) {
+	c := converter{root: root}
+	props := c.convert(root, "", nil)
+	return props, c.warnings
+}
+
+type converter struct {
+	root     *schema.Schema
+	warnings []string
+}
+
+func (c *converter) warn(pointer, format string, args ...interface{}) {
+	if pointer == "" {
+		pointer = "/"
+	}
+	c.warnings = append(c.warnings, pointer+": "+fmt.Sprintf(format, args...))
+}
+
+// resolve follows local references to definitions. Keywords of the
+// referencing schema take precedence over the ones of the definition. It
+// returns an error for references which can't be inlined.
+func (c *converter) resolve(s *schema.Schema, seenRefs []string) (*schema.Schema, []string, error) {
+	if s.Ref == "" {
+		return s, seenRefs, nil
+	}
+	name, ok := strings.CutPrefix(s.Ref, "#/definitions/")
+	if !ok {
+		return nil, seenRefs, fmt.Errorf("reference %s can't be inlined", s.Ref)
+	}
+	if slices.Contains(seenRefs, s.Ref) {
+		return nil, seenRefs, fmt.Errorf("recursive reference %s can't be inlined", s.Ref)
+	}
+	definition, ok := c.root.Definitions[name]
+	if !ok || definition == nil {
+		return nil, seenRefs, fmt.Errorf("definition %s not found", name)
+	}
+	seenRefs = append(slices.Clone(seenRefs), s.Ref)
+
+	definition, seenRefs, err := c.resolve(definition, seenRefs)
+	if err != nil {
+		return nil, seenRefs, err
+	}
+	merged := *definition
+	if s.Title != "" {
+		merged.Title = s.Title
+	}
+	if s.Description != "" {
+		merged.Description = s.Description
+	}
+	if s.Default != nil {
+		merged.Default = s.Default
+	}
+	if len(s.Type) > 0 {
+		merged.Type = s.Type
+	}
+	return &merged, seenRefs, nil
+}
+
+func anyValue() *JSONSchemaProps {
+	props := &JSONSchemaProps{}
+	props.setExtension(PreserveUnknownFields, true)
+	return props
+}
+
+// convert converts a schema outside of allOf, anyOf, oneOf and not
+func (c *converter) convert(s *schema.Schema, pointer string, seenRefs []string) *JSONSchemaProps {
+	s, seenRefs, err := c.resolve(s, seenRefs)
+	if err != nil {
+		c.warn(pointer, "%s, any value is accepted", err)
+		return anyValue()
+	}
+
+	props := &JSONSchemaProps{
+		Title:       s.Title,
+		Description: s.Description,
+		Default:     s.Default,
+	}
+	for name, value := range s.CustomAnnotations {
+		if strings.HasPrefix(name, "x-kubernetes-") {
+			props.setExtension(name, value)
+		}
+	}
+	if len(s.Examples) > 0 {
+		props.Example = s.Examples[0]
+	}
+	c.convertValidations(s, props, pointer)
+
+	// Fields specified in junctors must also be specified outside of them
+	branches := c.branches(s, seenRefs)
+	c.setType(props, c.types(s, branches), pointer)
+	if slices.Contains(props.Enum, nil) {
+		props.Nullable = true
+	}
+
+	switch props.Type {
+	case "object":
+		c.convertObject(s, props, branches, pointer, seenRefs)
+	case "array":
+		c.convertArray(s, props, branches, pointer, seenRefs)
+	}
+
+	if props.Type == "" && !props.preservesUnknownFields() && props.Extensions[IntOrString] == nil {
+		props.setExtension(PreserveUnknownFields, true)
+	}
+
+	c.convertJunctors(s, props, pointer, seenRefs)
+	return props
+}
+
+// branches returns the resolved schemas of allOf, anyOf and oneOf, the
+// junctors report the references which can't be resolved
+func (c *converter) branches(s *schema.Schema, seenRefs []string) []*schema.Schema {
+	var branches []*schema.Schema
+	for _, branch := range slices.Concat(s.AllOf, s.AnyOf, s.OneOf) {
+		if branch == nil {
+			continue
+		}
+		if resolved, _, err := c.resolve(branch, seenRefs); err == nil {
+			branches = append(branches, resolved)
+		}
+	}
+	return branches
+}
+
+// types returns the types of the schema. Without a type, it is derived from
+// the keywords or the types of the branches. Branches without a type only
+// validate values, they are skipped.
+func (c *converter) types(s *schema.Schema, branches []*schema.Schema) []string {
+	if len(s.Type) > 0 {
+		return s.Type
+	}
+	if len(s.Properties) > 0 {
+		return []string{"object"}
+	}
+	if s.AdditionalProperties != nil || len(s.PatternProperties) > 0 {
+		return []string{"object"}
+	}
+	if s.Items != nil {
+		return []string{"array"}
+	}
+
+	var types []string
+	values := s.Enum
+	if s.Const != nil {
+		values = append(slices.Clone(values), s.Const)
+	}
+	for _, value := range values {
+		if t := valueType(value); !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	if len(types) > 0 {
+		return types
+	}
+
+	for _, branch := range branches {
+		for _, t := range c.types(branch, nil) {
+			if !slices.Contains(types, t) {
+				types = append(types, t)
+			}
+		}
+	}
+	return types
+}
+
+func (c *converter) setType(props *JSONSchemaProps, types []string, pointer string) {
+	var nonNull []string
+	for _, t := range types {
+		if t == "null" {
+			props.Nullable = true
+		} else if !slices.Contains(nonNull, t) {
+			nonNull = append(nonNull, t)
+		}
+	}
+	sort.Strings(nonNull)
+
+	switch {
+	case len(nonNull) == 0:
+		return
+	case len(nonNull) == 1:
+		props.Type = nonNull[0]
+	case slices.Equal(nonNull, []string{"integer", "string"}):
+		props.setExtension(IntOrString, true)
+	case slices.Equal(nonNull, []string{"integer", "number"}):
+		props.Type = "number"
+	default:
+		c.warn(pointer, "types %s can't be combined, any value is accepted", strings.Join(nonNull, ", "))
+		props.setExtension(PreserveUnknownFields, true)
+	}
+}
+
+// convertValidations converts the value validations, which are allowed in
+// junctors as well
+func (c *converter) convertValidations(s *schema.Schema, props *JSONSchemaProps, pointer string) {
+	props.Format = s.Format
+	props.Pattern = s.Pattern
+	props.Enum = s.Enum
+	if s.Const != nil && len(s.Enum) == 0 {
+		props.Enum = []interface{}{s.Const}
+	}
+	props.Minimum = s.Minimum
+	props.Maximum = s.Maximum
+	// exclusiveMinimum and exclusiveMaximum are booleans in OpenAPI v3
+	if s.ExclusiveMinimum != nil && (s.Minimum == nil || *s.ExclusiveMinimum >= *s.Minimum) {
+		props.Minimum = s.ExclusiveMinimum
+		props.ExclusiveMinimum = true
+	}
+	if s.ExclusiveMaximum != nil && (s.Maximum == nil || *s.ExclusiveMaximum <= *s.Maximum) {
+		props.Maximum = s.ExclusiveMaximum
+		props.ExclusiveMaximum = true
+	}
+	props.MultipleOf = s.MultipleOf
+	props.MinLength = s.MinLength
+	props.MaxLength = s.MaxLength
+	props.MinItems = s.MinItems
+	props.MaxItems = s.MaxItems
+	props.MinProperties = s.MinProperties
+	props.MaxProperties = s.MaxProperties
+	props.Required = s.Required.Strings
+
+	unsupported := map[string]bool{
+		"if":                    s.If != nil,
+		"then":                  s.Then != nil,
+		"else":                  s.Else != nil,
+		"contains":              s.Contains != nil,
+		"propertyNames":         s.PropertyNames != nil,
+		"patternProperties":     len(s.PatternProperties) > 0,
+		"dependencies":          len(s.Dependencies) > 0,
+		"dependentRequired":     len(s.DependentRequired) > 0,
+		"dependentSchemas":      len(s.DependentSchemas) > 0,
+		"prefixItems":           len(s.PrefixItems) > 0,
+		"additionalItems":       s.AdditionalItems != nil,
+		"unevaluatedProperties": s.UnevaluatedProperties != nil,
+		"unevaluatedItems":      s.UnevaluatedItems != nil,
+	}
+	keywords := make([]string, 0, len(unsupported))
+	for keyword, isSet := range unsupported {
+		if isSet {
+			keywords = append(keywords, keyword)
+		}
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		c.warn(pointer, "%s is not supported by CustomResourceDefinitions, it is dropped", keyword)
+	}
+}
+
+func (c *converter) convertObject(s *schema.Schema, props *JSONSchemaProps, branches []*schema.Schema, pointer string, seenRefs []string) {
+	for name, property := range s.Properties {
+		if property == nil {
+			continue
+		}
+		if props.Properties == nil {
+			props.Properties = make(map[string]*JSONSchemaProps)
+		}
+		props.Properties[name] = c.convert(property, pointer+"/properties/"+escapePointer(name), seenRefs)
+	}
+	for _, branch := range branches {
+		for name, property := range branch.Properties {
+			if property == nil || props.Properties[name] != nil {
+				continue
+			}
+			if props.Properties == nil {
+				props.Properties = make(map[string]*JSONSchemaProps)
+			}
+			props.Properties[name] = c.convert(property, pointer+"/properties/"+escapePointer(name), seenRefs)
+		}
+	}
+
+	additionalProperties, err := schemaOrBool(s.AdditionalProperties)
+	if err != nil {
+		c.warn(pointer, "invalid additionalProperties: %s", err)
+	}
+	if additionalProperties == nil && len(branches) > 0 && closed(branches) {
+		additionalProperties = false
+	}
+	switch value := additionalProperties.(type) {
+	case nil:
+		if !props.preservesUnknownFields() && len(s.PatternProperties) == 0 {
+			props.setExtension(PreserveUnknownFields, true)
+		}
+	case bool:
+		if value {
+			props.setExtension(PreserveUnknownFields, true)
+		}
+	case *schema.Schema:
+		if len(props.Properties) > 0 {
+			c.warn(pointer, "additionalProperties can't be combined with properties, unknown fields are preserved")
+			props.setExtension(PreserveUnknownFields, true)
+		} else {
+			props.AdditionalProperties = c.convert(value, pointer+"/additionalProperties", seenRefs)
+		}
+	}
+	if len(s.PatternProperties) > 0 && !props.preservesUnknownFields() && props.AdditionalProperties == nil {
+		props.setExtension(PreserveUnknownFields, true)
+	}
+}
+
+func (c *converter) convertArray(s *schema.Schema, props *JSONSchemaProps, branches []*schema.Schema, pointer string, seenRefs []string) {
+	items := s.Items
+	for _, branch := range branches {
+		if items == nil && branch.Items != nil {
+			items = branch.Items
+		}
+	}
+	if items != nil {
+		props.Items = c.convert(items, pointer+"/items", seenRefs)
+	} else {
+		props.Items = anyValue()
+	}
+
+	if s.UniqueItems {
+		switch props.Items.Type {
+		case "string", "integer", "number", "boolean":
+			if props.Extensions[ListType] == nil {
+				props.setExtension(ListType, "set")
+			}
+		default:
+			c.warn(pointer, "uniqueItems is only supported for arrays of scalars, it is dropped")
+		}
+	}
+}
+
+// convertJunctors converts allOf, anyOf, oneOf and not. They can only contain
+// value validations of the fields the outer schema specifies.
+func (c *converter) convertJunctors(s *schema.Schema, props *JSONSchemaProps, pointer string, seenRefs []string) {
+	convertList := func(name string, schemas []*schema.Schema) []*JSONSchemaProps {
+		var converted []*JSONSchemaProps
+		for i, branch := range schemas {
+			if branch == nil {
+				continue
+			}
+			junctor := c.convertJunctor(branch, props, fmt.Sprintf("%s/%s/%d", pointer, name, i), seenRefs)
+			if junctor == nil {
+				if name == "allOf" {
+					continue
+				}
+				// A branch without validations matches every value
+				return nil
+			}
+			converted = append(converted, junctor)
+		}
+		return converted
+	}
+
+	props.AllOf = convertList("allOf", s.AllOf)
+	props.AnyOf = convertList("anyOf", s.AnyOf)
+	props.OneOf = convertList("oneOf", s.OneOf)
+	if s.Not != nil {
+		props.Not = c.convertJunctor(s.Not, props, pointer+"/not", seenRefs)
+		if props.Not == nil {
+			c.warn(pointer, "not without value validations is dropped")
+		}
+	}
+}
+
+// convertJunctor converts a schema inside of allOf, anyOf, oneOf or not. It
+// returns nil if no validation is left.
+func (c *converter) convertJunctor(s *schema.Schema, outer *JSONSchemaProps, pointer string, seenRefs []string) *JSONSchemaProps {
+	s, seenRefs, err := c.resolve(s, seenRefs)
+	if err != nil {
+		c.warn(pointer, "%s, it is dropped", err)
+		return nil
+	}
+
+	props := &JSONSchemaProps{}
+	c.convertValidations(s, props, pointer)
+	// null is only accepted by nullable
+	props.Enum = slices.DeleteFunc(slices.Clone(props.Enum), func(v interface{}) bool { return v == nil })
+	for name, property := range s.Properties {
+		if property == nil || outer.Properties[name] == nil {
+			continue
+		}
+		if junctor := c.convertJunctor(property, outer.Properties[name], pointer+"/properties/"+escapePointer(name), seenRefs); junctor != nil {
+			if props.Properties == nil {
+				props.Properties = make(map[string]*JSONSchemaProps)
+			}
+			props.Properties[name] = junctor
+		}
+	}
+	if s.Items != nil && outer.Items != nil {
+		props.Items = c.convertJunctor(s.Items, outer.Items, pointer+"/items", seenRefs)
+	}
+
+	nested := &schema.Schema{AllOf: s.AllOf, AnyOf: s.AnyOf, OneOf: s.OneOf, Not: s.Not}
+	c.convertJunctors(nested, props, pointer, seenRefs)
+	// Nested junctors validate the fields of the outer schema
+	for _, junctors := range [][]*JSONSchemaProps{props.AllOf, props.AnyOf, props.OneOf} {
+		for _, junctor := range junctors {
+			c.restrict(junctor, outer)
+		}
+	}
+	if props.Not != nil {
+		c.restrict(props.Not, outer)
+	}
+
+	if isEmpty(props) {
+		return nil
+	}
+	return props
+}
+
+// restrict removes the properties and items which aren't specified by the
+// outer schema
+func (c *converter) restrict(props, outer *JSONSchemaProps) {
+	for name, property := range props.Properties {
+		if outer.Properties[name] == nil {
+			delete(props.Properties, name)
+			continue
+		}
+		c.restrict(property, outer.Properties[name])
+	}
+	if props.Items != nil {
+		if outer.Items == nil {
+			props.Items = nil
+		} else {
+			c.restrict(props.Items, outer.Items)
+		}
+	}
+}
+
+// closed returns true if none of the schemas allows additional properties
+func closed(schemas []*schema.Schema) bool {
+	for _, s := range schemas {
+		if value, _ := schemaOrBool(s.AdditionalProperties); value != false {
+			return false
+		}
+	}
+	return true
+}
+
+// valueType returns the json type of a value
+func valueType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+func isEmpty(props *JSONSchemaProps) bool {
+	return props.Format == "" && props.Pattern == "" && len(props.Enum) == 0 &&
+		props.Minimum == nil && props.Maximum == nil && props.MultipleOf == nil &&
+		props.MinLength == nil && props.MaxLength == nil && props.MinItems == nil && props.MaxItems == nil &&
+		props.MinProperties == nil && props.MaxProperties == nil && len(props.Required) == 0 &&
+		len(props.Properties) == 0 && props.Items == nil &&
+		len(props.AllOf) == 0 && len(props.AnyOf) == 0 && len(props.OneOf) == 0 && props.Not == nil
+}
+
+// schemaOrBool returns the bool or the schema of a SchemaOrBool field
+func schemaOrBool(value schema.SchemaOrBool) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return v, nil
+	case *bool:
+		if v == nil {
+			return nil, nil
+		}
+		return *v, nil
+	case *schema.Schema:
+		return v, nil
+	case schema.Schema:
+		return &v, nil
+	}
+	// When unmarshaled from YAML, a schema object becomes a map
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var s schema.Schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
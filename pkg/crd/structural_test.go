@@ -0,0 +1,192 @@
+package crd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructural(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		expected string
+		warnings []string
+	}{
+		{
+			name:   "types and nullable",
+			schema: `{"type": "object", "additionalProperties": false, "properties": {"tag": {"type": ["string", "null"]}, "port": {"type": ["integer", "string"]}, "ratio": {"type": ["integer", "number"]}}}`,
+			expected: `
+type: object
+properties:
+  port:
+    x-kubernetes-int-or-string: true
+  ratio:
+    type: number
+  tag:
+    type: string
+    nullable: true
+`,
+		},
+		{
+			name:   "open objects preserve unknown fields",
+			schema: `{"type": "object", "properties": {"annotations": {"type": "object", "additionalProperties": true}, "labels": {"additionalProperties": {"type": "string"}}, "closed": {"type": "object", "additionalProperties": false}}}`,
+			expected: `
+type: object
+properties:
+  annotations:
+    type: object
+    x-kubernetes-preserve-unknown-fields: true
+  closed:
+    type: object
+  labels:
+    type: object
+    additionalProperties:
+      type: string
+x-kubernetes-preserve-unknown-fields: true
+`,
+		},
+		{
+			name:   "definitions are inlined",
+			schema: `{"type": "object", "additionalProperties": false, "definitions": {"port": {"type": "integer", "minimum": 1}}, "properties": {"port": {"$ref": "#/definitions/port", "description": "The port"}}}`,
+			expected: `
+type: object
+properties:
+  port:
+    type: integer
+    description: The port
+    minimum: 1
+`,
+		},
+		{
+			name:   "recursive definitions accept any value",
+			schema: `{"type": "object", "additionalProperties": false, "definitions": {"node": {"type": "object", "additionalProperties": false, "properties": {"child": {"$ref": "#/definitions/node"}}}}, "properties": {"tree": {"$ref": "#/definitions/node"}}}`,
+			expected: `
+type: object
+properties:
+  tree:
+    type: object
+    properties:
+      child:
+        x-kubernetes-preserve-unknown-fields: true
+`,
+			warnings: []string{"/properties/tree/properties/child: recursive reference #/definitions/node can't be inlined, any value is accepted"},
+		},
+		{
+			name:   "exclusive bounds are booleans",
+			schema: `{"type": "integer", "exclusiveMinimum": 0, "maximum": 10, "const": 5}`,
+			expected: `
+type: integer
+enum:
+  - 5
+minimum: 0
+exclusiveMinimum: true
+maximum: 10
+`,
+		},
+		{
+			name:   "junctors keep value validations",
+			schema: `{"type": "object", "additionalProperties": false, "properties": {"name": {"type": "string"}}, "anyOf": [{"type": "object", "description": "by name", "required": ["name"], "properties": {"name": {"type": "string", "minLength": 1}}}, {"required": ["secret"]}]}`,
+			expected: `
+type: object
+properties:
+  name:
+    type: string
+anyOf:
+  - required:
+      - name
+    properties:
+      name:
+        minLength: 1
+  - required:
+      - secret
+`,
+		},
+		{
+			name:   "properties of branches are specified outside",
+			schema: `{"type": "array", "items": {"anyOf": [{"type": "object", "additionalProperties": false, "required": ["name"], "properties": {"name": {"type": "string"}}}]}}`,
+			expected: `
+type: array
+items:
+  type: object
+  properties:
+    name:
+      type: string
+  anyOf:
+    - required:
+        - name
+`,
+		},
+		{
+			name:   "branches without validations match everything",
+			schema: `{"type": "string", "anyOf": [{"pattern": "^a"}, {"type": "string"}]}`,
+			expected: `
+type: string
+`,
+		},
+		{
+			name:   "arrays",
+			schema: `{"type": "object", "additionalProperties": false, "properties": {"args": {"type": "array", "uniqueItems": true, "items": {"type": "string"}}, "list": {"type": "array"}}}`,
+			expected: `
+type: object
+properties:
+  args:
+    type: array
+    items:
+      type: string
+    x-kubernetes-list-type: set
+  list:
+    type: array
+    items:
+      x-kubernetes-preserve-unknown-fields: true
+`,
+		},
+		{
+			name:   "unsupported keywords are dropped",
+			schema: `{"type": "object", "additionalProperties": false, "if": {"required": ["a"]}, "then": {"required": ["b"]}, "properties": {"ext": {"$ref": "https://example.com/schema.json"}}}`,
+			expected: `
+type: object
+properties:
+  ext:
+    x-kubernetes-preserve-unknown-fields: true
+`,
+			warnings: []string{
+				"/: if is not supported by CustomResourceDefinitions, it is dropped",
+				"/: then is not supported by CustomResourceDefinitions, it is dropped",
+				"/properties/ext: reference https://example.com/schema.json can't be inlined, any value is accepted",
+			},
+		},
+		{
+			name:   "kubernetes extensions are kept",
+			schema: `{"type": "array", "x-kubernetes-list-type": "map", "x-kubernetes-list-map-keys": ["name"], "x-custom": true, "items": {"type": "object", "additionalProperties": false, "required": ["name"], "properties": {"name": {"type": "string"}}}}`,
+			expected: `
+type: array
+items:
+  type: object
+  required:
+    - name
+  properties:
+    name:
+      type: string
+x-kubernetes-list-map-keys:
+  - name
+x-kubernetes-list-type: map
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s schema.Schema
+			assert.NoError(t, json.Unmarshal([]byte(tt.schema), &s))
+
+			props, warnings := Structural(&s)
+			result, err := toYaml(props)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected[1:], string(result))
+			assert.Equal(t, tt.warnings, warnings)
+		})
+	}
+}
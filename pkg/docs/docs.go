@@ -0,0 +1,350 @@
+package docs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/dadav/helm-schema/pkg/chart"
+	"github.com/dadav/helm-schema/pkg/schema"
+)
+
+// Supported output formats
+const (
+	FormatMarkdown = "markdown"
+	FormatAsciiDoc = "asciidoc"
+)
+
+// Formats lists all supported output formats
+var Formats = []string{FormatMarkdown, FormatAsciiDoc}
+
+// Row documents a single key of the values
+type Row struct {
+	// Key is the dot-separated path of the key, array items are marked with []
+	Key         string
+	Type        string
+	Default     string
+	Description string
+	Enum        []string
+	Constraints []string
+	Required    bool
+	Deprecated  bool
+}
+
+// Data is passed to the templates
+type Data struct {
+	Chart *chart.ChartFile
+	Rows  []Row
+}
+
+// Rows returns one row per property of the schema, sorted by key. Nested
+// objects, array items and references to definitions are followed.
+func Rows(s *schema.Schema) []Row {
+	var rows []Row
+	walk(s, s, "", nil, &rows)
+	return rows
+}
+
+func walk(root, s *schema.Schema, prefix string, seenRefs []string, rows *[]Row) {
+	s, seenRefs = resolve(root, s, seenRefs)
+	if s == nil {
+		return
+	}
+
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property := s.Properties[name]
+		if property == nil {
+			continue
+		}
+		key := joinKey(prefix, name)
+		resolved, propertyRefs := resolve(root, property, seenRefs)
+		if resolved == nil {
+			continue
+		}
+
+		*rows = append(*rows, newRow(key, property, resolved, slices.Contains(s.Required.Strings, name)))
+
+		walk(root, resolved, key, propertyRefs, rows)
+		if resolved.Items != nil {
+			walk(root, resolved.Items, key+"[]", propertyRefs, rows)
+		}
+	}
+}
+
+// resolve follows local references to definitions. Keywords of the
+// referencing schema take precedence over the ones of the definition.
+// References which were already followed on the current path return nil to
+// break cycles.
+func resolve(root, s *schema.Schema, seenRefs []string) (*schema.Schema, []string) {
+	if s == nil || !strings.HasPrefix(s.Ref, "#/definitions/") {
+		return s, seenRefs
+	}
+	if slices.Contains(seenRefs, s.Ref) {
+		return nil, seenRefs
+	}
+	definition, ok := root.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+	if !ok || definition == nil {
+		return s, seenRefs
+	}
+	seenRefs = append(slices.Clone(seenRefs), s.Ref)
+
+	definition, seenRefs = resolve(root, definition, seenRefs)
+	if definition == nil {
+		return nil, seenRefs
+	}
+	merged := *definition
+	if s.Description != "" {
+		merged.Description = s.Description
+	}
+	if s.Default != nil {
+		merged.Default = s.Default
+	}
+	if len(s.Type) > 0 {
+		merged.Type = s.Type
+	}
+	if s.Deprecated {
+		merged.Deprecated = true
+	}
+	return &merged, seenRefs
+}
+
+func joinKey(prefix, name string) string {
+	if strings.ContainsAny(name, ". ") {
+		name = strconv.Quote(name)
+	}
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func newRow(key string, property, resolved *schema.Schema, required bool) Row {
+	row := Row{
+		Key:         key,
+		Type:        typeString(resolved),
+		Description: resolved.Description,
+		Required:    required,
+		Deprecated:  resolved.Deprecated || property.Deprecated,
+	}
+	if resolved.Default != nil {
+		row.Default = valueString(resolved.Default)
+	}
+	for _, value := range resolved.Enum {
+		row.Enum = append(row.Enum, valueString(value))
+	}
+	row.Constraints = constraints(resolved)
+	return row
+}
+
+// typeString returns the type of the schema, falling back to the types of
+// its alternatives.
+func typeString(s *schema.Schema) string {
+	types := slices.Clone([]string(s.Type))
+	if len(types) == 0 {
+		for _, alternatives := range [][]*schema.Schema{s.AnyOf, s.OneOf} {
+			for _, alternative := range alternatives {
+				if alternative == nil {
+					continue
+				}
+				for _, t := range alternative.Type {
+					if !slices.Contains(types, t) {
+						types = append(types, t)
+					}
+				}
+			}
+		}
+	}
+	if slices.Contains(types, "array") && s.Items != nil && len(s.Items.Type) == 1 {
+		types[slices.Index(types, "array")] = s.Items.Type[0] + "[]"
+	}
+	return strings.Join(types, " | ")
+}
+
+func constraints(s *schema.Schema) []string {
+	var result []string
+	addFloat := func(name string, value *float64) {
+		if value != nil {
+			result = append(result, fmt.Sprintf("%s: %s", name, strconv.FormatFloat(*value, 'f', -1, 64)))
+		}
+	}
+	addInt := func(name string, value *int) {
+		if value != nil {
+			result = append(result, fmt.Sprintf("%s: %d", name, *value))
+		}
+	}
+
+	if s.Const != nil {
+		result = append(result, "const: "+valueString(s.Const))
+	}
+	addFloat("minimum", s.Minimum)
+	addFloat("exclusiveMinimum", s.ExclusiveMinimum)
+	addFloat("maximum", s.Maximum)
+	addFloat("exclusiveMaximum", s.ExclusiveMaximum)
+	addFloat("multipleOf", s.MultipleOf)
+	addInt("minLength", s.MinLength)
+	addInt("maxLength", s.MaxLength)
+	if s.Pattern != "" {
+		result = append(result, "pattern: "+s.Pattern)
+	}
+	if s.Format != "" {
+		result = append(result, "format: "+s.Format)
+	}
+	addInt("minItems", s.MinItems)
+	addInt("maxItems", s.MaxItems)
+	if s.UniqueItems {
+		result = append(result, "uniqueItems")
+	}
+	addInt("minProperties", s.MinProperties)
+	addInt("maxProperties", s.MaxProperties)
+	if s.ReadOnly {
+		result = append(result, "readOnly")
+	}
+	if s.WriteOnly {
+		result = append(result, "writeOnly")
+	}
+	return result
+}
+
+func valueString(value interface{}) string {
+	res, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(res)
+}
+
+const markdownTemplate = `| Key | Type | Default | Description | Enum | Constraints | Deprecated |
+| --- | ---- | ------- | ----------- | ---- | ----------- | ---------- |
+{{- range .Rows }}
+| {{ code .Key }} | {{ code .Type }} | {{ code .Default }} | {{ cell .Description }} | {{ codeList .Enum }} | {{ constraintList . }} | {{ if .Deprecated }}yes{{ end }} |
+{{- end }}
+`
+
+const asciidocTemplate = `[cols="2,1,1,3,1,2,1",options="header"]
+|===
+| Key | Type | Default | Description | Enum | Constraints | Deprecated
+{{ range .Rows }}
+|{{ with code .Key }} {{ . }}{{ end }}
+|{{ with code .Type }} {{ . }}{{ end }}
+|{{ with code .Default }} {{ . }}{{ end }}
+|{{ with cell .Description }} {{ . }}{{ end }}
+|{{ with codeList .Enum }} {{ . }}{{ end }}
+|{{ with constraintList . }} {{ . }}{{ end }}
+|{{ if .Deprecated }} yes{{ end }}
+{{ end -}}
+|===
+`
+
+// newTemplate parses a template with the helper functions for the given format
+func newTemplate(name, format, text string) (*template.Template, error) {
+	var cell func(string) string
+	var code func(string) string
+	switch format {
+	case FormatMarkdown:
+		cell = func(s string) string {
+			s = strings.ReplaceAll(s, "|", `\|`)
+			return strings.ReplaceAll(strings.TrimSpace(s), "\n", "<br>")
+		}
+		code = func(s string) string {
+			if s == "" {
+				return ""
+			}
+			return "`" + strings.ReplaceAll(s, "|", `\|`) + "`"
+		}
+	case FormatAsciiDoc:
+		cell = func(s string) string {
+			s = strings.ReplaceAll(s, "|", `\|`)
+			return strings.ReplaceAll(strings.TrimSpace(s), "\n", " +\n")
+		}
+		code = func(s string) string {
+			if s == "" {
+				return ""
+			}
+			return "`+" + strings.ReplaceAll(s, "|", `\|`) + "+`"
+		}
+	default:
+		return nil, ValidateFormat(format)
+	}
+
+	codeList := func(values []string) string {
+		coded := make([]string, 0, len(values))
+		for _, value := range values {
+			coded = append(coded, code(value))
+		}
+		return strings.Join(coded, ", ")
+	}
+
+	return template.New(name).Funcs(template.FuncMap{
+		"cell":     cell,
+		"code":     code,
+		"codeList": codeList,
+		"join":     strings.Join,
+		"constraintList": func(row Row) string {
+			list := row.Constraints
+			if row.Required {
+				list = append([]string{"required"}, list...)
+			}
+			return codeList(list)
+		},
+	}).Parse(text)
+}
+
+// ValidateFormat returns an error if the format is not supported
+func ValidateFormat(format string) error {
+	if !slices.Contains(Formats, format) {
+		return fmt.Errorf("unsupported docs format %s, must be one of (%s)", format, strings.Join(Formats, ", "))
+	}
+	return nil
+}
+
+// Renderer renders the documentation of chart schemas
+type Renderer struct {
+	tmpl *template.Template
+}
+
+// NewRenderer creates a renderer for the given format. If templateFile is
+// set, it is used instead of the builtin template of the format. The
+// template gets a Data value and can use the helpers cell, code, codeList,
+// constraintList and join, which escape their input for the format.
+func NewRenderer(format, templateFile string) (*Renderer, error) {
+	text := markdownTemplate
+	if format == FormatAsciiDoc {
+		text = asciidocTemplate
+	}
+	name := format
+	if templateFile != "" {
+		content, err := os.ReadFile(templateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		text = string(content)
+		name = templateFile
+	}
+
+	tmpl, err := newTemplate(name, format, text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	return &Renderer{tmpl: tmpl}, nil
+}
+
+// Render renders the documentation of the schema of a chart
+func (r *Renderer) Render(chartFile *chart.ChartFile, s *schema.Schema) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := r.tmpl.Execute(&buf, Data{Chart: chartFile, Rows: Rows(s)}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,159 @@
+package docs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dadav/helm-schema/pkg/chart"
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = `{
+  "type": "object",
+  "required": ["replicas"],
+  "properties": {
+    "replicas": {
+      "type": "integer",
+      "default": 1,
+      "description": "Number of replicas",
+      "minimum": 1,
+      "enum": [1, 2, 3]
+    },
+    "image": {
+      "type": "object",
+      "properties": {
+        "tag": {"type": "string", "default": "latest", "description": "The tag | or digest"},
+        "policy": {"type": "string", "deprecated": true, "description": "Use pullPolicy instead"}
+      }
+    },
+    "ports": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {"name": {"type": "string", "pattern": "^[a-z]+$"}}
+      }
+    },
+    "resources": {"$ref": "#/definitions/resources", "description": "Resources of the pod"},
+    "dotted.key": {"anyOf": [{"type": "string"}, {"type": "null"}]}
+  },
+  "definitions": {
+    "resources": {
+      "type": "object",
+      "properties": {"limits": {"type": "object", "description": "Upper limits"}}
+    }
+  }
+}`
+
+func loadTestSchema(t *testing.T) *schema.Schema {
+	var s schema.Schema
+	assert.NoError(t, json.Unmarshal([]byte(testSchema), &s))
+	return &s
+}
+
+func TestRows(t *testing.T) {
+	rows := Rows(loadTestSchema(t))
+
+	var keys []string
+	for _, row := range rows {
+		keys = append(keys, row.Key)
+	}
+	assert.Equal(t, []string{
+		`"dotted.key"`,
+		"image",
+		"image.policy",
+		"image.tag",
+		"ports",
+		"ports[].name",
+		"replicas",
+		"resources",
+		"resources.limits",
+	}, keys)
+
+	byKey := make(map[string]Row)
+	for _, row := range rows {
+		byKey[row.Key] = row
+	}
+	assert.Equal(t, Row{
+		Key:         "replicas",
+		Type:        "integer",
+		Default:     "1",
+		Description: "Number of replicas",
+		Enum:        []string{"1", "2", "3"},
+		Constraints: []string{"minimum: 1"},
+		Required:    true,
+	}, byKey["replicas"])
+	assert.Equal(t, "string | null", byKey[`"dotted.key"`].Type)
+	assert.Equal(t, "object[]", byKey["ports"].Type)
+	assert.Equal(t, []string{"pattern: ^[a-z]+$"}, byKey["ports[].name"].Constraints)
+	assert.True(t, byKey["image.policy"].Deprecated)
+	assert.Equal(t, "object", byKey["resources"].Type)
+	assert.Equal(t, "Resources of the pod", byKey["resources"].Description)
+	assert.Equal(t, "Upper limits", byKey["resources.limits"].Description)
+}
+
+func TestRows_CyclicReference(t *testing.T) {
+	var s schema.Schema
+	assert.NoError(t, json.Unmarshal([]byte(`{
+  "properties": {"node": {"$ref": "#/definitions/node"}},
+  "definitions": {
+    "node": {"type": "object", "properties": {"child": {"$ref": "#/definitions/node"}}}
+  }
+}`), &s))
+
+	rows := Rows(&s)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, "node", rows[0].Key)
+	}
+}
+
+func TestRender_Markdown(t *testing.T) {
+	renderer, err := NewRenderer(FormatMarkdown, "")
+	assert.NoError(t, err)
+
+	out, err := renderer.Render(&chart.ChartFile{Name: "test"}, loadTestSchema(t))
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "| Key | Type | Default | Description | Enum | Constraints | Deprecated |\n")
+	assert.Contains(t, string(out), "| `replicas` | `integer` | `1` | Number of replicas | `1`, `2`, `3` | `required`, `minimum: 1` |  |\n")
+	assert.Contains(t, string(out), "| `image.tag` | `string` | `\"latest\"` | The tag \\| or digest |  |  |  |\n")
+	assert.Contains(t, string(out), "| `image.policy` | `string` |  | Use pullPolicy instead |  |  | yes |\n")
+}
+
+func TestRender_AsciiDoc(t *testing.T) {
+	renderer, err := NewRenderer(FormatAsciiDoc, "")
+	assert.NoError(t, err)
+
+	out, err := renderer.Render(&chart.ChartFile{Name: "test"}, loadTestSchema(t))
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "|===\n| Key | Type | Default | Description | Enum | Constraints | Deprecated\n")
+	assert.Contains(t, string(out), "\n| `+replicas+`\n| `+integer+`\n| `+1+`\n| Number of replicas\n| `+1+`, `+2+`, `+3+`\n| `+required+`, `+minimum: 1+`\n|\n")
+	assert.NotRegexp(t, " \n", string(out))
+}
+
+func TestRender_CustomTemplate(t *testing.T) {
+	templateFile := filepath.Join(t.TempDir(), "docs.tmpl")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(
+		"# {{ .Chart.Name }}\n{{ range .Rows }}{{ if .Required }}* {{ code .Key }}: {{ .Description }}\n{{ end }}{{ end }}"), 0o644))
+
+	renderer, err := NewRenderer(FormatMarkdown, templateFile)
+	assert.NoError(t, err)
+
+	out, err := renderer.Render(&chart.ChartFile{Name: "test"}, loadTestSchema(t))
+	assert.NoError(t, err)
+	assert.Equal(t, "# test\n* `replicas`: Number of replicas\n", string(out))
+}
+
+func TestNewRenderer_Errors(t *testing.T) {
+	_, err := NewRenderer("html", "")
+	assert.Error(t, err)
+
+	_, err = NewRenderer(FormatMarkdown, filepath.Join(t.TempDir(), "missing.tmpl"))
+	assert.ErrorContains(t, err, "failed to read template")
+
+	templateFile := filepath.Join(t.TempDir(), "broken.tmpl")
+	assert.NoError(t, os.WriteFile(templateFile, []byte("{{ range .Rows }"), 0o644))
+	_, err = NewRenderer(FormatMarkdown, templateFile)
+	assert.ErrorContains(t, err, "failed to parse template")
+}
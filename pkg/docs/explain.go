@@ -0,0 +1,211 @@
+package docs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+)
+
+// ErrKeyNotFound is returned by Explain if the path doesn't exist in the schema
+var ErrKeyNotFound = errors.New("key not found")
+
+// Explain writes the documentation of the key at the dot-separated path of
+// the values, like kubectl explain. Array items are entered implicitly or with
+// "[]", e.g. "ingress.tls.hosts" or "ingress.tls[].hosts". References to
+// definitions are followed. With recursive, the whole subtree of keys is
+// listed instead of the direct child keys.
+func Explain(w io.Writer, root *schema.Schema, path string, recursive bool) error {
+	key, property, resolved, required, err := lookup(root, path)
+	if err != nil {
+		return err
+	}
+
+	row := newRow(key, property, resolved, required)
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	if row.Key != "" {
+		fmt.Fprintf(tw, "KEY:\t%s\n", row.Key)
+	}
+	fmt.Fprintf(tw, "TYPE:\t%s\n", typeOrAny(row.Type))
+	if row.Required {
+		fmt.Fprintln(tw, "REQUIRED:\ttrue")
+	}
+	if row.Deprecated {
+		fmt.Fprintln(tw, "DEPRECATED:\ttrue")
+	}
+	if row.Default != "" {
+		fmt.Fprintf(tw, "DEFAULT:\t%s\n", row.Default)
+	}
+	if len(row.Enum) > 0 {
+		fmt.Fprintf(tw, "ENUM:\t%s\n", strings.Join(row.Enum, ", "))
+	}
+	if len(row.Constraints) > 0 {
+		fmt.Fprintf(tw, "CONSTRAINTS:\t%s\n", strings.Join(row.Constraints, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if row.Description != "" {
+		fmt.Fprintf(w, "\nDESCRIPTION:\n%s\n", indent(row.Description, "  "))
+	}
+
+	children := childKeys(root, resolved, nil)
+	if children == nil {
+		return nil
+	}
+	fmt.Fprintln(w, "\nKEYS:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	writeKeys(tw, root, children, 1, recursive, nil)
+	return tw.Flush()
+}
+
+// lookup returns the key, the schema and the resolved schema of the path and
+// if it is required by its parent
+func lookup(root *schema.Schema, path string) (string, *schema.Schema, *schema.Schema, bool, error) {
+	key := ""
+	property := root
+	current, _ := resolve(root, root, nil)
+	required := false
+
+	// "tls[]" is split into "tls" and "[]"
+	var parts []string
+	for _, part := range strings.Split(path, ".") {
+		arrays := 0
+		for strings.HasSuffix(part, "[]") {
+			part = strings.TrimSuffix(part, "[]")
+			arrays++
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+		for ; arrays > 0; arrays-- {
+			parts = append(parts, "[]")
+		}
+	}
+
+	for _, part := range parts {
+		if part != "[]" && current.GetPropertyAtPath(part) == nil && current.Items != nil {
+			// Arrays are explained through their items
+			key += "[]"
+			property = current.Items
+			current, _ = resolve(root, current.Items, nil)
+		}
+
+		if part == "[]" {
+			if current.Items == nil {
+				return "", nil, nil, false, fmt.Errorf("%w: %s is no array", ErrKeyNotFound, keyOrRoot(key))
+			}
+			key += "[]"
+			property = current.Items
+			required = false
+		} else {
+			next := current.GetPropertyAtPath(part)
+			if next == nil {
+				return "", nil, nil, false, fmt.Errorf("%w: %s", ErrKeyNotFound, joinKey(key, part))
+			}
+			required = slices.Contains(current.Required.Strings, part)
+			key = joinKey(key, part)
+			property = next
+		}
+		current, _ = resolve(root, property, nil)
+		if current == nil {
+			return "", nil, nil, false, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+		}
+	}
+
+	return key, property, current, required, nil
+}
+
+// childKeys returns the schema whose properties are the child keys, which are
+// the properties of the items for arrays. Nil if there are none.
+func childKeys(root, s *schema.Schema, seenRefs []string) *schema.Schema {
+	if len(s.Properties) > 0 {
+		return s
+	}
+	if items, _ := resolve(root, s.Items, seenRefs); items != nil && len(items.Properties) > 0 {
+		return s.Items
+	}
+	return nil
+}
+
+// writeKeys writes the child keys of s. Without recursive, the descriptions
+// are written below the keys. References already followed on the current
+// path aren't entered again.
+func writeKeys(w io.Writer, root, s *schema.Schema, depth int, recursive bool, seenRefs []string) {
+	s, seenRefs = resolve(root, s, seenRefs)
+	if s == nil {
+		return
+	}
+
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	prefix := strings.Repeat("  ", depth)
+	for _, name := range names {
+		property := s.Properties[name]
+		if property == nil {
+			continue
+		}
+		resolved, propertyRefs := resolve(root, property, seenRefs)
+		if resolved == nil {
+			// Cyclic reference
+			fmt.Fprintf(w, "%s%s\t<%s>\n", prefix, name, strings.TrimPrefix(property.Ref, "#/definitions/"))
+			continue
+		}
+
+		var flags []string
+		if slices.Contains(s.Required.Strings, name) {
+			flags = append(flags, "required")
+		}
+		if resolved.Deprecated || property.Deprecated {
+			flags = append(flags, "deprecated")
+		}
+		line := fmt.Sprintf("%s%s\t<%s>", prefix, name, typeOrAny(typeString(resolved)))
+		if len(flags) > 0 {
+			line += "\t" + strings.Join(flags, ", ")
+		}
+		fmt.Fprintln(w, line)
+
+		if !recursive {
+			if resolved.Description != "" {
+				fmt.Fprintf(w, "%s\n", indent(resolved.Description, prefix+"  "))
+			}
+			continue
+		}
+		if children := childKeys(root, resolved, propertyRefs); children != nil {
+			writeKeys(w, root, children, depth+1, recursive, propertyRefs)
+		}
+	}
+}
+
+func typeOrAny(t string) string {
+	if t == "" {
+		return "any"
+	}
+	return t
+}
+
+func keyOrRoot(key string) string {
+	if key == "" {
+		return "the root"
+	}
+	return key
+}
+
+// indent prefixes every line of text
+func indent(text, prefix string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
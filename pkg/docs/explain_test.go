@@ -0,0 +1,95 @@
+package docs
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		recursive bool
+		want      string
+	}{
+		{
+			name: "leaf key",
+			path: "replicas",
+			want: "KEY:         replicas\nTYPE:        integer\nREQUIRED:    true\nDEFAULT:     1\nENUM:        1, 2, 3\nCONSTRAINTS: minimum: 1\n\n" +
+				"DESCRIPTION:\n  Number of replicas\n",
+		},
+		{
+			name: "child keys",
+			path: "image",
+			want: "KEY:  image\nTYPE: object\n\nKEYS:\n" +
+				"  policy  <string>  deprecated\n    Use pullPolicy instead\n" +
+				"  tag  <string>\n    The tag | or digest\n",
+		},
+		{
+			name: "array items",
+			path: "ports.name",
+			want: "KEY:         ports[].name\nTYPE:        string\nCONSTRAINTS: pattern: ^[a-z]+$\n",
+		},
+		{
+			name: "explicit array items",
+			path: "ports[]",
+			want: "KEY:  ports[]\nTYPE: object\n\nKEYS:\n  name  <string>\n",
+		},
+		{
+			name: "references",
+			path: "resources",
+			want: "KEY:  resources\nTYPE: object\n\nDESCRIPTION:\n  Resources of the pod\n\nKEYS:\n  limits  <object>\n    Upper limits\n",
+		},
+		{
+			name:      "recursive",
+			path:      "",
+			recursive: true,
+			want: "TYPE: object\n\nKEYS:\n" +
+				"  dotted.key  <string | null>\n" +
+				"  image       <object>\n" +
+				"    policy    <string>  deprecated\n" +
+				"    tag       <string>\n" +
+				"  ports       <object[]>\n" +
+				"    name      <string>\n" +
+				"  replicas    <integer>  required\n" +
+				"  resources   <object>\n" +
+				"    limits    <object>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			assert.NoError(t, Explain(&out, loadTestSchema(t), tt.path, tt.recursive))
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
+
+func TestExplain_NotFound(t *testing.T) {
+	var out bytes.Buffer
+	err := Explain(&out, loadTestSchema(t), "image.missing", false)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.ErrorContains(t, err, "image.missing")
+
+	err = Explain(&out, loadTestSchema(t), "replicas[]", false)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestExplain_CyclicReference(t *testing.T) {
+	var s schema.Schema
+	assert.NoError(t, json.Unmarshal([]byte(`{
+  "properties": {"node": {"$ref": "#/definitions/node"}},
+  "definitions": {
+    "node": {"type": "object", "properties": {"child": {"$ref": "#/definitions/node"}}}
+  }
+}`), &s))
+
+	var out bytes.Buffer
+	assert.NoError(t, Explain(&out, &s, "node.child.child", true))
+	assert.Equal(t, "KEY:  node.child.child\nTYPE: object\n\nKEYS:\n  child    <object>\n    child  <node>\n", out.String())
+}
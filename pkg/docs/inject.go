@@ -0,0 +1,49 @@
+package docs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Markers which enclose the generated documentation in an existing file.
+// They are matched anywhere in a line, so they can be wrapped in the comment
+// syntax of the format, e.g. <!-- helm-schema-docs-start --> in Markdown or
+// // helm-schema-docs-start in AsciiDoc.
+const (
+	MarkerStart = "helm-schema-docs-start"
+	MarkerEnd   = "helm-schema-docs-end"
+)
+
+// ErrNoMarkers is returned by Inject if the content has no start marker
+var ErrNoMarkers = errors.New("no " + MarkerStart + " marker found")
+
+// Inject replaces everything between the marker lines in content with docs.
+// The marker lines themselves are kept.
+func Inject(content, docs []byte) ([]byte, error) {
+	start := bytes.Index(content, []byte(MarkerStart))
+	if start < 0 {
+		return nil, ErrNoMarkers
+	}
+	startLineEnd := bytes.IndexByte(content[start:], '\n')
+	if startLineEnd < 0 {
+		return nil, fmt.Errorf("no %s marker found after %s", MarkerEnd, MarkerStart)
+	}
+	startLineEnd += start + 1
+
+	end := bytes.Index(content[startLineEnd:], []byte(MarkerEnd))
+	if end < 0 {
+		return nil, fmt.Errorf("no %s marker found after %s", MarkerEnd, MarkerStart)
+	}
+	end += startLineEnd
+	endLineStart := bytes.LastIndexByte(content[:end], '\n') + 1
+
+	var buf bytes.Buffer
+	buf.Write(content[:startLineEnd])
+	buf.Write(docs)
+	if len(docs) > 0 && docs[len(docs)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	buf.Write(content[endLineStart:])
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,39 @@
+package docs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInject(t *testing.T) {
+	content := "# chart\n\n<!-- helm-schema-docs-start -->\nold\ntable\n<!-- helm-schema-docs-end -->\n\nfooter\n"
+
+	out, err := Inject([]byte(content), []byte("new table"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# chart\n\n<!-- helm-schema-docs-start -->\nnew table\n<!-- helm-schema-docs-end -->\n\nfooter\n", string(out))
+
+	// Injecting again must not change anything
+	again, err := Inject(out, []byte("new table\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(out), string(again))
+}
+
+func TestInject_AsciiDocMarkers(t *testing.T) {
+	content := "= chart\n// helm-schema-docs-start\n// helm-schema-docs-end\n"
+
+	out, err := Inject([]byte(content), []byte("|===\n|===\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "= chart\n// helm-schema-docs-start\n|===\n|===\n// helm-schema-docs-end\n", string(out))
+}
+
+func TestInject_Errors(t *testing.T) {
+	_, err := Inject([]byte("# chart\n"), []byte("docs"))
+	assert.ErrorIs(t, err, ErrNoMarkers)
+
+	_, err = Inject([]byte("<!-- helm-schema-docs-start -->\nno end\n"), []byte("docs"))
+	assert.ErrorContains(t, err, "no helm-schema-docs-end marker found")
+
+	_, err = Inject([]byte("<!-- helm-schema-docs-end -->\n<!-- helm-schema-docs-start -->\n"), []byte("docs"))
+	assert.ErrorContains(t, err, "no helm-schema-docs-end marker found")
+}
@@ -0,0 +1,565 @@
+package fuzz
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+const (
+	// maxAttempts is the number of documents which are generated until one
+	// of them is valid
+	maxAttempts = 100
+	// maxDepth limits the nesting of optional keys, deeper objects only get
+	// their required keys, which ends recursive schemas
+	maxDepth = 6
+	// maxRequiredDepth ends recursive schemas with required keys
+	maxRequiredDepth = 32
+)
+
+// Generator generates values documents for a schema. The documents only
+// depend on the seed.
+type Generator struct {
+	root     *schema.Schema
+	compiled *jsonschema.Schema
+	rand     *rand.Rand
+	// full includes every optional key, the documents are used as base of
+	// the invalid cases
+	full bool
+}
+
+// New returns a generator for the schema, the compiled schema is used to
+// validate the generated documents
+func New(root *schema.Schema, compiled *jsonschema.Schema, seed int64) *Generator {
+	return &Generator{
+		root:     root,
+		compiled: compiled,
+		rand:     rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Valid returns count values documents which are valid against the schema.
+// Types, enums, patterns, numeric ranges and array sizes are generated
+// directly, documents which still violate the schema (e.g. because of not or
+// if/then/else) are generated again.
+func (g *Generator) Valid(count int) ([]interface{}, error) {
+	docs := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		doc, err := g.validDocument()
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func (g *Generator) validDocument() (interface{}, error) {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		doc := g.value(g.root, 0)
+		if doc == nil {
+			// Helm treats empty values like an empty map
+			doc = map[string]interface{}{}
+		}
+		if err = g.compiled.Validate(doc); err == nil {
+			return doc, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to generate valid values in %d attempts: %w", maxAttempts, err)
+}
+
+// definition returns the definition a local reference points to
+func (g *Generator) definition(ref string) *schema.Schema {
+	for _, prefix := range []string{"#/definitions/", "#/$defs/"} {
+		if name, ok := strings.CutPrefix(ref, prefix); ok {
+			return g.root.Definitions[name]
+		}
+	}
+	if ref == "#" {
+		return g.root
+	}
+	return nil
+}
+
+// resolve follows local references, nil is returned for other references
+func (g *Generator) resolve(s *schema.Schema) *schema.Schema {
+	for i := 0; s != nil && s.Ref != "" && i < maxRequiredDepth; i++ {
+		s = g.definition(s.Ref)
+	}
+	if s != nil && s.Ref != "" {
+		return nil
+	}
+	return s
+}
+
+// flatten merges the branches of allOf and a random branch of anyOf and
+// oneOf into the schema
+func (g *Generator) flatten(s *schema.Schema) *schema.Schema {
+	if len(s.AllOf) == 0 && len(s.AnyOf) == 0 && len(s.OneOf) == 0 {
+		return s
+	}
+	branches := slices.Clone(s.AllOf)
+	for _, junctor := range [][]*schema.Schema{s.AnyOf, s.OneOf} {
+		if len(junctor) > 0 {
+			branches = append(branches, junctor[g.rand.Intn(len(junctor))])
+		}
+	}
+
+	result := *s
+	result.AllOf, result.AnyOf, result.OneOf = nil, nil, nil
+	merged := &result
+	for _, branch := range branches {
+		if branch = g.resolve(branch); branch != nil {
+			merged = merge(merged, g.flatten(branch))
+		}
+	}
+	return merged
+}
+
+// merge returns a schema with the keywords of both schemas. Properties of
+// both are combined with allOf, the required keys are joined, the types
+// intersected and other keywords of the base win.
+func merge(base, other *schema.Schema) *schema.Schema {
+	var baseKeywords, otherKeywords map[string]interface{}
+	for _, s := range []struct {
+		schema   *schema.Schema
+		keywords *map[string]interface{}
+	}{{base, &baseKeywords}, {other, &otherKeywords}} {
+		encoded, err := json.Marshal(s.schema)
+		if err != nil {
+			return base
+		}
+		if err := json.Unmarshal(encoded, s.keywords); err != nil {
+			return base
+		}
+	}
+
+	for keyword, value := range otherKeywords {
+		existing, ok := baseKeywords[keyword]
+		if !ok {
+			baseKeywords[keyword] = value
+			continue
+		}
+		switch keyword {
+		case "properties":
+			properties, _ := existing.(map[string]interface{})
+			otherProperties, _ := value.(map[string]interface{})
+			for name, property := range otherProperties {
+				if baseProperty, ok := properties[name]; ok {
+					properties[name] = map[string]interface{}{"allOf": []interface{}{baseProperty, property}}
+				} else {
+					properties[name] = property
+				}
+			}
+		case "required":
+			required, _ := existing.([]interface{})
+			otherRequired, _ := value.([]interface{})
+			baseKeywords[keyword] = append(required, otherRequired...)
+		case "type":
+			baseKeywords[keyword] = intersectTypes(existing, value)
+		}
+	}
+
+	encoded, err := json.Marshal(baseKeywords)
+	if err != nil {
+		return base
+	}
+	var result schema.Schema
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return base
+	}
+	return &result
+}
+
+func intersectTypes(a, b interface{}) interface{} {
+	toSlice := func(value interface{}) []string {
+		switch v := value.(type) {
+		case string:
+			return []string{v}
+		case []interface{}:
+			var result []string
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					result = append(result, s)
+				}
+			}
+			return result
+		}
+		return nil
+	}
+	var result []string
+	otherTypes := toSlice(b)
+	for _, t := range toSlice(a) {
+		if slices.Contains(otherTypes, t) || (t == "number" && slices.Contains(otherTypes, "integer")) {
+			if t == "number" && !slices.Contains(otherTypes, "number") {
+				t = "integer"
+			}
+			result = append(result, t)
+		}
+	}
+	if len(result) == 0 {
+		return a
+	}
+	return result
+}
+
+// value returns a random value which is valid against the schema
+func (g *Generator) value(s *schema.Schema, depth int) interface{} {
+	s = g.resolve(s)
+	if s == nil || depth > maxRequiredDepth {
+		return nil
+	}
+	s = g.flatten(s)
+
+	if s.Const != nil {
+		return s.Const
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[g.rand.Intn(len(s.Enum))]
+	}
+
+	switch g.pickType(s) {
+	case "null":
+		return nil
+	case "boolean":
+		return g.rand.Intn(2) == 0
+	case "integer":
+		return g.integer(s)
+	case "number":
+		return g.number(s)
+	case "string":
+		return g.string(s)
+	case "array":
+		return g.array(s, depth)
+	case "object":
+		return g.object(s, depth)
+	}
+	return nil
+}
+
+// pickType returns a random type of the schema. Without type, it's inferred
+// from the other keywords.
+func (g *Generator) pickType(s *schema.Schema) string {
+	if len(s.Type) > 0 {
+		t := s.Type[g.rand.Intn(len(s.Type))]
+		// Optional values are more useful than null values
+		if t == "null" && len(s.Type) > 1 && g.rand.Intn(4) > 0 {
+			return g.pickType(&schema.Schema{Type: slices.DeleteFunc(slices.Clone(s.Type), func(t string) bool { return t == "null" })})
+		}
+		return t
+	}
+	switch {
+	case len(s.Properties) > 0 || len(s.Required.Strings) > 0 || s.AdditionalProperties != nil || len(s.PatternProperties) > 0:
+		return "object"
+	case s.Items != nil || len(s.PrefixItems) > 0 || s.MinItems != nil || s.MaxItems != nil:
+		return "array"
+	case s.Pattern != "" || s.MinLength != nil || s.MaxLength != nil || s.Format != "":
+		return "string"
+	case s.Minimum != nil || s.Maximum != nil || s.ExclusiveMinimum != nil || s.ExclusiveMaximum != nil || s.MultipleOf != nil:
+		return "number"
+	}
+	return []string{"string", "integer", "boolean"}[g.rand.Intn(3)]
+}
+
+// bounds returns the inclusive range of numbers of the schema. Open ranges
+// are limited to 100 numbers.
+func bounds(s *schema.Schema, integer bool) (float64, float64) {
+	lo, hi := math.Inf(-1), math.Inf(1)
+	if s.Minimum != nil {
+		lo = *s.Minimum
+	}
+	if s.Maximum != nil {
+		hi = *s.Maximum
+	}
+	step := 0.01
+	if integer {
+		step = 1
+		lo, hi = math.Ceil(lo), math.Floor(hi)
+	}
+	if s.ExclusiveMinimum != nil && *s.ExclusiveMinimum >= lo {
+		lo = math.Floor(*s.ExclusiveMinimum/step)*step + step
+	}
+	if s.ExclusiveMaximum != nil && *s.ExclusiveMaximum <= hi {
+		hi = math.Ceil(*s.ExclusiveMaximum/step)*step - step
+	}
+
+	switch {
+	case math.IsInf(lo, -1) && math.IsInf(hi, 1):
+		lo, hi = 0, 100
+	case math.IsInf(lo, -1):
+		lo = hi - 100
+	case math.IsInf(hi, 1):
+		hi = lo + 100
+	}
+	return lo, hi
+}
+
+func (g *Generator) integer(s *schema.Schema) int64 {
+	lo, hi := bounds(s, true)
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		return int64(g.multiple(lo, hi, *s.MultipleOf))
+	}
+	if hi < lo {
+		return int64(lo)
+	}
+	return int64(lo) + g.rand.Int63n(int64(hi-lo)+1)
+}
+
+func (g *Generator) number(s *schema.Schema) float64 {
+	lo, hi := bounds(s, false)
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		return g.multiple(lo, hi, *s.MultipleOf)
+	}
+	value := math.Round((lo+g.rand.Float64()*(hi-lo))*100) / 100
+	return math.Max(lo, math.Min(hi, value))
+}
+
+// multiple returns a random multiple of the factor in the range
+func (g *Generator) multiple(lo, hi, factor float64) float64 {
+	first, last := math.Ceil(lo/factor), math.Floor(hi/factor)
+	if last < first {
+		return first * factor
+	}
+	return (first + float64(g.rand.Int63n(int64(last-first)+1))) * factor
+}
+
+func (g *Generator) string(s *schema.Schema) string {
+	minLength, maxLength := 0, 12
+	if s.MinLength != nil {
+		minLength = *s.MinLength
+		maxLength = max(maxLength, minLength+12)
+	}
+	if s.MaxLength != nil {
+		maxLength = *s.MaxLength
+	}
+
+	if s.Pattern != "" {
+		var value string
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			var err error
+			if value, err = g.regexString(s.Pattern); err != nil {
+				break
+			}
+			if length := len([]rune(value)); length >= minLength && length <= maxLength {
+				return value
+			}
+		}
+		return value
+	}
+	if value := g.formatString(s.Format); value != "" {
+		return value
+	}
+
+	length := minLength
+	if maxLength > minLength {
+		length += g.rand.Intn(maxLength - minLength + 1)
+	}
+	return g.letters(length)
+}
+
+func (g *Generator) letters(length int) string {
+	var sb strings.Builder
+	for i := 0; i < length; i++ {
+		sb.WriteByte(byte('a' + g.rand.Intn(26)))
+	}
+	return sb.String()
+}
+
+// formatString returns a random string of the format, or an empty string for
+// unknown formats
+func (g *Generator) formatString(format string) string {
+	switch format {
+	case "date-time":
+		return fmt.Sprintf("20%02d-%02d-%02dT%02d:%02d:%02dZ", g.rand.Intn(30), 1+g.rand.Intn(12), 1+g.rand.Intn(28), g.rand.Intn(24), g.rand.Intn(60), g.rand.Intn(60))
+	case "date":
+		return fmt.Sprintf("20%02d-%02d-%02d", g.rand.Intn(30), 1+g.rand.Intn(12), 1+g.rand.Intn(28))
+	case "time":
+		return fmt.Sprintf("%02d:%02d:%02dZ", g.rand.Intn(24), g.rand.Intn(60), g.rand.Intn(60))
+	case "email":
+		return g.letters(6) + "@example.com"
+	case "hostname":
+		return g.letters(6) + ".example.com"
+	case "ipv4":
+		return fmt.Sprintf("10.%d.%d.%d", g.rand.Intn(256), g.rand.Intn(256), 1+g.rand.Intn(254))
+	case "ipv6":
+		return fmt.Sprintf("2001:db8::%x", 1+g.rand.Intn(0xffff))
+	case "uri", "url", "iri":
+		return "https://example.com/" + g.letters(6)
+	case "uuid":
+		hex := fmt.Sprintf("%016x%016x", g.rand.Uint64(), g.rand.Uint64())
+		return hex[:8] + "-" + hex[8:12] + "-" + hex[12:16] + "-" + hex[16:20] + "-" + hex[20:]
+	}
+	return ""
+}
+
+func (g *Generator) array(s *schema.Schema, depth int) []interface{} {
+	minItems, maxItems := 0, 3
+	if g.full {
+		minItems = 1
+	}
+	if s.MinItems != nil {
+		minItems = *s.MinItems
+		maxItems = max(maxItems, minItems+3)
+	}
+	if s.MaxItems != nil {
+		maxItems = *s.MaxItems
+		minItems = min(minItems, maxItems)
+	}
+	if depth >= maxDepth && (s.MinItems == nil || *s.MinItems == 0) {
+		maxItems = 0
+		minItems = 0
+	}
+	length := minItems
+	if maxItems > minItems {
+		length += g.rand.Intn(maxItems - minItems + 1)
+	}
+
+	additionalItems, additionalAllowed := additionalSchema(s.AdditionalItems)
+	items := []interface{}{}
+	seen := map[string]bool{}
+	for i := 0; i < length; i++ {
+		itemSchema := s.Items
+		if i < len(s.PrefixItems) {
+			itemSchema = s.PrefixItems[i]
+		} else if len(s.PrefixItems) > 0 && s.Items == nil {
+			if !additionalAllowed {
+				break
+			}
+			itemSchema = additionalItems
+		}
+
+		var item interface{}
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			if itemSchema == nil {
+				item = g.letters(1 + g.rand.Intn(8))
+			} else {
+				item = g.value(itemSchema, depth+1)
+			}
+			if !s.UniqueItems {
+				break
+			}
+			key, _ := json.Marshal(item)
+			if !seen[string(key)] {
+				seen[string(key)] = true
+				break
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func (g *Generator) object(s *schema.Schema, depth int) map[string]interface{} {
+	result := map[string]interface{}{}
+	var optional []string
+	for _, key := range sortedKeys(s.Properties) {
+		if slices.Contains(s.Required.Strings, key) {
+			result[key] = g.value(s.Properties[key], depth+1)
+			continue
+		}
+		if depth < maxDepth && (g.full || g.rand.Intn(2) == 0) {
+			result[key] = g.value(s.Properties[key], depth+1)
+			continue
+		}
+		optional = append(optional, key)
+	}
+	for _, key := range s.Required.Strings {
+		// Required keys without property schema
+		if _, ok := result[key]; !ok {
+			result[key] = g.letters(4)
+		}
+	}
+
+	minProperties := 0
+	if s.MinProperties != nil {
+		minProperties = *s.MinProperties
+	}
+	for _, key := range optional {
+		if len(result) >= minProperties {
+			break
+		}
+		result[key] = g.value(s.Properties[key], depth+1)
+	}
+
+	// Maps get some keys of the additional or pattern properties
+	additional, allowed := additionalSchema(s.AdditionalProperties)
+	count := 0
+	if len(s.Properties) == 0 && depth < maxDepth && (additional != nil || len(s.PatternProperties) > 0) {
+		count = g.rand.Intn(3)
+		if g.full {
+			count = 1 + g.rand.Intn(2)
+		}
+	}
+	count = max(count, minProperties-len(result))
+	if s.MaxProperties != nil {
+		count = min(count, *s.MaxProperties-len(result))
+	}
+	patterns := sortedKeys(s.PatternProperties)
+	for i := 0; i < count; i++ {
+		if len(patterns) > 0 && (additional == nil || g.rand.Intn(2) == 0) {
+			pattern := patterns[g.rand.Intn(len(patterns))]
+			if key, err := g.regexString(pattern); err == nil {
+				if _, exists := result[key]; !exists {
+					result[key] = g.value(s.PatternProperties[pattern], depth+1)
+				}
+				continue
+			}
+		}
+		if !allowed {
+			break
+		}
+		key := fmt.Sprintf("key%d", i+1)
+		if additional != nil {
+			result[key] = g.value(additional, depth+1)
+		} else {
+			result[key] = g.letters(4)
+		}
+	}
+	return result
+}
+
+// additionalSchema returns the schema of additionalProperties or
+// additionalItems and whether additional values are allowed at all
+func additionalSchema(value schema.SchemaOrBool) (*schema.Schema, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, true
+	case bool:
+		return nil, v
+	case *bool:
+		return nil, v == nil || *v
+	case *schema.Schema:
+		return v, true
+	case schema.Schema:
+		return &v, true
+	}
+	// When unmarshaled from YAML, a schema object becomes a map
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, true
+	}
+	var s schema.Schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, true
+	}
+	return &s, true
+}
+
+func sortedKeys(m map[string]*schema.Schema) []string {
+	keys := make([]string, 0, len(m))
+	for key, value := range m {
+		if value != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
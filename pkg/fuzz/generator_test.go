@@ -0,0 +1,116 @@
+package fuzz
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = `{
+	"type": "object",
+	"additionalProperties": false,
+	"required": ["image"],
+	"properties": {
+		"replicas": {"type": "integer", "minimum": 1, "maximum": 10},
+		"ratio": {"type": "number", "exclusiveMinimum": 0, "maximum": 1, "multipleOf": 0.25},
+		"image": {
+			"type": "object",
+			"additionalProperties": false,
+			"required": ["repository"],
+			"properties": {
+				"repository": {"type": "string", "pattern": "^[a-z]+(/[a-z]+)*$", "minLength": 3},
+				"tag": {"type": "string", "maxLength": 8},
+				"pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent"]}
+			}
+		},
+		"port": {"$ref": "#/definitions/port"},
+		"hosts": {"type": "array", "minItems": 1, "maxItems": 3, "uniqueItems": true, "items": {"type": "string", "format": "hostname"}},
+		"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+		"mode": {"anyOf": [{"type": "string", "const": "fast"}, {"type": "null"}]},
+		"resources": {"allOf": [
+			{"type": "object", "properties": {"cpu": {"type": "string", "pattern": "^[0-9]+m$"}}},
+			{"required": ["memory"], "properties": {"memory": {"type": "integer", "multipleOf": 64}}}
+		]},
+		"tree": {"$ref": "#/definitions/node"}
+	},
+	"definitions": {
+		"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+		"node": {"type": "object", "additionalProperties": false, "properties": {
+			"name": {"type": "string"},
+			"children": {"type": "array", "items": {"$ref": "#/definitions/node"}}
+		}}
+	}
+}`
+
+func newTestGenerator(t *testing.T, source string, seed int64) *Generator {
+	t.Helper()
+	var s schema.Schema
+	if !assert.NoError(t, json.Unmarshal([]byte(source), &s)) {
+		t.FailNow()
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(source)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	compiler := jsonschema.NewCompiler()
+	if !assert.NoError(t, compiler.AddResource("values.schema.json", doc)) {
+		t.FailNow()
+	}
+	compiled, err := compiler.Compile("values.schema.json")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return New(&s, compiled, seed)
+}
+
+func TestValid(t *testing.T) {
+	g := newTestGenerator(t, testSchema, 1)
+	docs, err := g.Valid(50)
+	assert.NoError(t, err)
+	assert.Len(t, docs, 50)
+
+	seen := map[string]bool{}
+	for _, doc := range docs {
+		assert.NoError(t, g.compiled.Validate(doc))
+		encoded, _ := json.Marshal(doc)
+		seen[string(encoded)] = true
+	}
+	assert.Greater(t, len(seen), 40, "documents should differ")
+}
+
+func TestValid_Deterministic(t *testing.T) {
+	first, err := newTestGenerator(t, testSchema, 42).Valid(5)
+	assert.NoError(t, err)
+	second, err := newTestGenerator(t, testSchema, 42).Valid(5)
+	assert.NoError(t, err)
+	other, err := newTestGenerator(t, testSchema, 43).Valid(5)
+	assert.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, other)
+}
+
+func TestValid_Unsatisfiable(t *testing.T) {
+	g := newTestGenerator(t, `{"type": "object", "properties": {"a": {"type": "string"}}, "required": ["a"], "not": {"required": ["a"]}}`, 1)
+	_, err := g.Valid(1)
+	assert.ErrorContains(t, err, "failed to generate valid values in 100 attempts")
+}
+
+func TestBounds(t *testing.T) {
+	minimum, maximum := 1.5, 10.0
+	lo, hi := bounds(&schema.Schema{Minimum: &minimum, ExclusiveMaximum: &maximum}, true)
+	assert.Equal(t, 2.0, lo)
+	assert.Equal(t, 9.0, hi)
+
+	lo, hi = bounds(&schema.Schema{ExclusiveMinimum: &minimum}, false)
+	assert.InDelta(t, 1.51, lo, 1e-9)
+	assert.InDelta(t, 101.51, hi, 1e-9)
+
+	lo, hi = bounds(&schema.Schema{}, true)
+	assert.Equal(t, 0.0, lo)
+	assert.Equal(t, 100.0, hi)
+}
@@ -0,0 +1,424 @@
+package fuzz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+)
+
+// Case is a values document which violates exactly one constraint of the
+// schema
+type Case struct {
+	// Pointer is the JSON pointer of the value violating the constraint
+	Pointer string
+	// Keyword is the violated keyword, e.g. minimum
+	Keyword string
+	// SchemaPointer is the location of the keyword in the schema, e.g.
+	// #/properties/replicas/minimum
+	SchemaPointer string
+	Values        interface{}
+}
+
+// mutation replaces the value at the path to violate the keyword
+type mutation struct {
+	path    []string
+	keyword string
+	apply   func(value interface{}) interface{}
+}
+
+// Invalid returns values documents which violate exactly one constraint each.
+// They are mutations of a valid document with every optional key, only
+// mutations which result in a single validation error of the expected
+// keyword at the expected location are kept.
+func (g *Generator) Invalid() ([]Case, error) {
+	g.full = true
+	base, err := g.validDocument()
+	g.full = false
+	if err != nil {
+		return nil, err
+	}
+
+	var mutations []mutation
+	g.mutations(g.root, base, nil, 0, func(m mutation) {
+		mutations = append(mutations, m)
+	})
+
+	var cases []Case
+	seen := map[string]bool{}
+	for _, m := range mutations {
+		doc := replaceAt(deepCopy(base), m.path, m.apply)
+		var validationErr *jsonschema.ValidationError
+		if !errors.As(g.compiled.Validate(doc), &validationErr) {
+			continue
+		}
+		leaves := validationLeaves(validationErr)
+		if len(leaves) != 1 {
+			continue
+		}
+		keywordPath := leaves[0].ErrorKind.KeywordPath()
+		if len(keywordPath) == 0 || keywordPath[len(keywordPath)-1] != m.keyword ||
+			!slices.Equal(leaves[0].InstanceLocation, m.path) {
+			continue
+		}
+
+		c := Case{
+			Pointer:       jsonPointer(m.path),
+			Keyword:       m.keyword,
+			SchemaPointer: schemaPointer(leaves[0]),
+			Values:        doc,
+		}
+		key := c.Pointer + " " + c.SchemaPointer
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// mutations walks the schema along the value and adds the mutations of
+// every constraint
+func (g *Generator) mutations(s *schema.Schema, value interface{}, path []string, depth int, add func(mutation)) {
+	s = g.resolve(s)
+	if s == nil || depth > maxRequiredDepth {
+		return
+	}
+	set := func(keyword string, replacement interface{}) {
+		add(mutation{path: path, keyword: keyword, apply: func(interface{}) interface{} { return replacement }})
+	}
+
+	// Values files which aren't maps can't be loaded at all
+	if len(s.Type) > 0 && len(path) > 0 {
+		if wrong, ok := wrongType(s.Type); ok {
+			set("type", wrong)
+		}
+	}
+	if len(s.Enum) > 0 {
+		set("enum", outsideOf(s.Enum, value))
+	}
+	if s.Const != nil {
+		set("const", outsideOf([]interface{}{s.Const}, value))
+	}
+
+	switch v := value.(type) {
+	case string:
+		g.stringMutations(s, v, set)
+	case int, int64, float64:
+		numberMutations(s, set)
+	case []interface{}:
+		g.arrayMutations(s, v, path, depth, add)
+	case map[string]interface{}:
+		g.objectMutations(s, v, path, depth, add)
+	}
+
+	// Every branch of allOf has to be satisfied, unlike those of anyOf and oneOf
+	for _, branch := range s.AllOf {
+		g.mutations(branch, value, path, depth+1, add)
+	}
+}
+
+func (g *Generator) stringMutations(s *schema.Schema, value string, set func(string, interface{})) {
+	runes := []rune(value)
+	if s.MinLength != nil && *s.MinLength > 0 {
+		set("minLength", string(runes[:min(len(runes), *s.MinLength-1)]))
+	}
+	if s.MaxLength != nil {
+		set("maxLength", value+strings.Repeat(string(append(runes, 'a')[0]), *s.MaxLength+1-len(runes)))
+	}
+	if s.Pattern != "" {
+		for _, candidate := range []string{"", "-", "invalid value", "0", value + "!", "!" + value} {
+			length := len([]rune(candidate))
+			if s.MinLength != nil && length < *s.MinLength || s.MaxLength != nil && length > *s.MaxLength {
+				continue
+			}
+			if !patternMatches(s.Pattern, candidate) {
+				set("pattern", candidate)
+				break
+			}
+		}
+	}
+	if s.Format != "" {
+		set("format", "invalid value")
+	}
+}
+
+func numberMutations(s *schema.Schema, set func(string, interface{})) {
+	step := 1.0
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		step = *s.MultipleOf
+	}
+	integer := len(s.Type) > 0 && !s.Type.Matches("number") && s.Type.Matches("integer")
+	number := func(value float64) interface{} {
+		if value == math.Trunc(value) {
+			return int64(value)
+		}
+		return value
+	}
+
+	if s.Minimum != nil {
+		set("minimum", number(*s.Minimum-step))
+	}
+	if s.Maximum != nil {
+		set("maximum", number(*s.Maximum+step))
+	}
+	if s.ExclusiveMinimum != nil {
+		set("exclusiveMinimum", number(*s.ExclusiveMinimum))
+	}
+	if s.ExclusiveMaximum != nil {
+		set("exclusiveMaximum", number(*s.ExclusiveMaximum))
+	}
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		lo, hi := bounds(s, integer)
+		factor := *s.MultipleOf
+		// The first multiple in range, moved off the grid
+		offset := factor / 2
+		if factor == math.Trunc(factor) && factor > 1 {
+			offset = 1
+		}
+		set("multipleOf", number(math.Min(math.Ceil(lo/factor)*factor+offset, hi)))
+	}
+}
+
+func (g *Generator) arrayMutations(s *schema.Schema, value []interface{}, path []string, depth int, add func(mutation)) {
+	set := func(keyword string, replacement interface{}) {
+		add(mutation{path: path, keyword: keyword, apply: func(interface{}) interface{} { return replacement }})
+	}
+
+	if s.MinItems != nil && *s.MinItems > 0 && len(value) >= *s.MinItems {
+		set("minItems", slices.Clone(value[:*s.MinItems-1]))
+	}
+	if s.MaxItems != nil && len(value) > 0 {
+		items := slices.Clone(value)
+		for i := 0; len(items) <= *s.MaxItems && i < maxAttempts; i++ {
+			var item interface{} = g.letters(1 + g.rand.Intn(8))
+			if s.Items != nil {
+				item = g.value(s.Items, depth+1)
+			}
+			if s.UniqueItems && slices.ContainsFunc(items, func(existing interface{}) bool {
+				return reflect.DeepEqual(existing, item)
+			}) {
+				continue
+			}
+			items = append(items, item)
+		}
+		set("maxItems", items)
+	}
+	if s.UniqueItems && len(value) > 0 {
+		// The first item is repeated, the last one makes way at the maximum
+		items := slices.Clone(value)
+		if s.MaxItems == nil || len(items) < *s.MaxItems {
+			items = append(items, nil)
+		}
+		if len(items) > 1 {
+			items[len(items)-1] = deepCopy(value[0])
+			set("uniqueItems", items)
+		}
+	}
+
+	for i, item := range value {
+		itemSchema := s.Items
+		if i < len(s.PrefixItems) {
+			itemSchema = s.PrefixItems[i]
+		} else if len(s.PrefixItems) > 0 || i > 0 {
+			// The items share their schema, the first one suffices
+			continue
+		}
+		if itemSchema != nil {
+			g.mutations(itemSchema, item, append(slices.Clone(path), strconv.Itoa(i)), depth+1, add)
+		}
+	}
+}
+
+func (g *Generator) objectMutations(s *schema.Schema, value map[string]interface{}, path []string, depth int, add func(mutation)) {
+	for _, key := range s.Required.Strings {
+		if _, ok := value[key]; !ok {
+			continue
+		}
+		add(mutation{path: path, keyword: "required", apply: func(value interface{}) interface{} {
+			object := value.(map[string]interface{})
+			delete(object, key)
+			return object
+		}})
+	}
+	if _, allowed := additionalSchema(s.AdditionalProperties); !allowed {
+		key := "unexpectedKey"
+		for i := 1; s.Properties[key] != nil || matchesAnyPattern(s.PatternProperties, key); i++ {
+			key = fmt.Sprintf("unexpectedKey%d", i)
+		}
+		add(mutation{path: path, keyword: "additionalProperties", apply: func(value interface{}) interface{} {
+			object := value.(map[string]interface{})
+			object[key] = "unexpected"
+			return object
+		}})
+	}
+
+	for _, key := range sortedKeys(s.Properties) {
+		if propertyValue, ok := value[key]; ok {
+			g.mutations(s.Properties[key], propertyValue, append(slices.Clone(path), key), depth+1, add)
+		}
+	}
+
+	// The additional keys share their schema, the first one suffices
+	additional, _ := additionalSchema(s.AdditionalProperties)
+	for _, key := range sortedValueKeys(value) {
+		if _, ok := s.Properties[key]; ok {
+			continue
+		}
+		var keySchema *schema.Schema
+		for _, pattern := range sortedKeys(s.PatternProperties) {
+			if patternMatches(pattern, key) {
+				keySchema = s.PatternProperties[pattern]
+				break
+			}
+		}
+		if keySchema == nil {
+			keySchema = additional
+		}
+		if keySchema != nil {
+			g.mutations(keySchema, value[key], append(slices.Clone(path), key), depth+1, add)
+			break
+		}
+	}
+}
+
+func sortedValueKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// wrongType returns a value of none of the types
+func wrongType(types schema.StringOrArrayOfString) (interface{}, bool) {
+	candidates := []struct {
+		typeName string
+		value    interface{}
+	}{
+		{"string", "invalid value"},
+		{"boolean", true},
+		{"object", map[string]interface{}{}},
+		{"integer", int64(1)},
+		{"array", []interface{}{}},
+	}
+	for _, candidate := range candidates {
+		matches := types.Matches(candidate.typeName) || (candidate.typeName == "integer" && types.Matches("number"))
+		if !matches {
+			return candidate.value, true
+		}
+	}
+	return nil, false
+}
+
+// outsideOf returns a value of the same type which isn't one of the values
+func outsideOf(values []interface{}, value interface{}) interface{} {
+	var candidates []interface{}
+	switch value.(type) {
+	case string:
+		candidates = []interface{}{"invalid value", "invalid value 2"}
+	case bool:
+		candidates = []interface{}{true, false}
+	case int, int64, float64:
+		candidates = []interface{}{int64(-999999), int64(999999)}
+	case []interface{}:
+		candidates = []interface{}{[]interface{}{}, []interface{}{"invalid value"}}
+	case map[string]interface{}:
+		candidates = []interface{}{map[string]interface{}{}, map[string]interface{}{"invalid": "value"}}
+	}
+	candidates = append(candidates, "invalid value", "invalid value 2")
+	for _, candidate := range candidates {
+		if !slices.ContainsFunc(values, func(allowed interface{}) bool { return jsonEqual(allowed, candidate) }) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func jsonEqual(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
+
+// replaceAt replaces the value at the path with the result of apply
+func replaceAt(doc interface{}, path []string, apply func(interface{}) interface{}) interface{} {
+	if len(path) == 0 {
+		return apply(doc)
+	}
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		v[path[0]] = replaceAt(v[path[0]], path[1:], apply)
+	case []interface{}:
+		if i, err := strconv.Atoi(path[0]); err == nil && i < len(v) {
+			v[i] = replaceAt(v[i], path[1:], apply)
+		}
+	}
+	return doc
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = deepCopy(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = deepCopy(item)
+		}
+		return result
+	}
+	return value
+}
+
+// validationLeaves returns the errors without causes, anyOf and oneOf errors
+// are kept because their causes are alternatives
+func validationLeaves(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	switch err.ErrorKind.(type) {
+	case *kind.AnyOf, *kind.OneOf:
+		return []*jsonschema.ValidationError{err}
+	}
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		leaves = append(leaves, validationLeaves(cause)...)
+	}
+	return leaves
+}
+
+// schemaPointer returns the location of the violated keyword in the schema
+func schemaPointer(err *jsonschema.ValidationError) string {
+	fragment := "#"
+	if _, after, ok := strings.Cut(err.SchemaURL, "#"); ok {
+		fragment += after
+	}
+	return fragment + jsonPointer(err.ErrorKind.KeywordPath())
+}
+
+// jsonPointer joins path tokens into a json pointer
+func jsonPointer(path []string) string {
+	var sb strings.Builder
+	replacer := strings.NewReplacer("~", "~0", "/", "~1")
+	for _, token := range path {
+		sb.WriteByte('/')
+		sb.WriteString(replacer.Replace(token))
+	}
+	return sb.String()
+}
@@ -0,0 +1,63 @@
+package fuzz
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalid(t *testing.T) {
+	g := newTestGenerator(t, testSchema, 1)
+	cases, err := g.Invalid()
+	assert.NoError(t, err)
+
+	labels := map[string]string{}
+	for _, c := range cases {
+		var validationErr *jsonschema.ValidationError
+		if assert.True(t, errors.As(g.compiled.Validate(c.Values), &validationErr), "%s should be invalid", c.Pointer) {
+			assert.Len(t, validationLeaves(validationErr), 1)
+		}
+		labels[c.Pointer+" "+c.Keyword] = c.SchemaPointer
+	}
+
+	for label, schemaPointer := range map[string]string{
+		" required":                    "#/required",
+		" additionalProperties":        "#/additionalProperties",
+		"/replicas minimum":            "#/properties/replicas/minimum",
+		"/replicas maximum":            "#/properties/replicas/maximum",
+		"/ratio exclusiveMinimum":      "#/properties/ratio/exclusiveMinimum",
+		"/ratio multipleOf":            "#/properties/ratio/multipleOf",
+		"/image required":              "#/properties/image/required",
+		"/image/repository pattern":    "#/properties/image/properties/repository/pattern",
+		"/image/repository minLength":  "#/properties/image/properties/repository/minLength",
+		"/image/tag maxLength":         "#/properties/image/properties/tag/maxLength",
+		"/image/pullPolicy enum":       "#/properties/image/properties/pullPolicy/enum",
+		"/port maximum":                "#/definitions/port/maximum",
+		"/hosts minItems":              "#/properties/hosts/minItems",
+		"/hosts maxItems":              "#/properties/hosts/maxItems",
+		"/hosts uniqueItems":           "#/properties/hosts/uniqueItems",
+		"/hosts/0 type":                "#/properties/hosts/items/type",
+		"/labels/key1 type":            "#/properties/labels/additionalProperties/type",
+		"/resources required":          "#/properties/resources/allOf/1/required",
+		"/resources/memory multipleOf": "#/properties/resources/allOf/1/properties/memory/multipleOf",
+		"/tree additionalProperties":   "#/definitions/node/additionalProperties",
+		"/tree/children/0/name type":   "#/definitions/node/properties/name/type",
+	} {
+		assert.Equal(t, schemaPointer, labels[label], label)
+	}
+}
+
+func TestInvalid_Deterministic(t *testing.T) {
+	first, err := newTestGenerator(t, testSchema, 7).Invalid()
+	assert.NoError(t, err)
+	second, err := newTestGenerator(t, testSchema, 7).Invalid()
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestJsonPointer(t *testing.T) {
+	assert.Equal(t, "", jsonPointer(nil))
+	assert.Equal(t, "/a~1b/c~0d/0", jsonPointer([]string{"a/b", "c~d", "0"}))
+}
@@ -0,0 +1,95 @@
+package fuzz
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+)
+
+// regexString returns a random string which matches the pattern. Anchors and
+// word boundaries are ignored, the caller validates the result.
+func (g *Generator) regexString(pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	g.writeRegex(&sb, re.Simplify())
+	return sb.String(), nil
+}
+
+func (g *Generator) writeRegex(sb *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		sb.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		sb.WriteRune(g.classRune(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		sb.WriteByte(byte('a' + g.rand.Intn(26)))
+	case syntax.OpCapture:
+		g.writeRegex(sb, re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.writeRegex(sb, sub)
+		}
+	case syntax.OpAlternate:
+		g.writeRegex(sb, re.Sub[g.rand.Intn(len(re.Sub))])
+	case syntax.OpStar:
+		g.repeatRegex(sb, re.Sub[0], 0, 3)
+	case syntax.OpPlus:
+		g.repeatRegex(sb, re.Sub[0], 1, 3)
+	case syntax.OpQuest:
+		g.repeatRegex(sb, re.Sub[0], 0, 1)
+	case syntax.OpRepeat:
+		maxCount := re.Max
+		if maxCount < 0 {
+			maxCount = re.Min + 3
+		}
+		g.repeatRegex(sb, re.Sub[0], re.Min, maxCount)
+	}
+}
+
+func (g *Generator) repeatRegex(sb *strings.Builder, re *syntax.Regexp, minCount, maxCount int) {
+	count := minCount + g.rand.Intn(maxCount-minCount+1)
+	for i := 0; i < count; i++ {
+		g.writeRegex(sb, re)
+	}
+}
+
+// classRune returns a random rune of the ranges of a character class,
+// printable ASCII characters are preferred
+func (g *Generator) classRune(ranges []rune) rune {
+	var printable []rune
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := max(ranges[i], '!'), min(ranges[i+1], '~')
+		if lo <= hi {
+			printable = append(printable, lo, hi)
+		}
+	}
+	if len(printable) > 0 {
+		ranges = printable
+	}
+	if len(ranges) < 2 {
+		return 'a'
+	}
+	i := 2 * g.rand.Intn(len(ranges)/2)
+	return ranges[i] + rune(g.rand.Intn(int(ranges[i+1]-ranges[i])+1))
+}
+
+// patternMatches reports whether the value matches the pattern, invalid
+// patterns match every value
+func patternMatches(pattern, value string) bool {
+	re, err := regexp.Compile(pattern)
+	return err != nil || re.MatchString(value)
+}
+
+func matchesAnyPattern(patterns map[string]*schema.Schema, value string) bool {
+	for pattern := range patterns {
+		if patternMatches(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package fuzz
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegexString(t *testing.T) {
+	g := New(nil, nil, 1)
+	for _, pattern := range []string{
+		`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`,
+		`^[0-9]+(m|Mi|Gi)$`,
+		`^v\d{1,2}\.\d+\.\d+(-rc\.\d)?$`,
+		`^(Always|IfNotPresent|Never)$`,
+		`^\w+@\w+\.(com|org)$`,
+		`^[^\s]{3,}$`,
+		`prefix-.*`,
+	} {
+		re := regexp.MustCompile(pattern)
+		for i := 0; i < 50; i++ {
+			value, err := g.regexString(pattern)
+			assert.NoError(t, err)
+			assert.Regexp(t, re, value, "pattern %s", pattern)
+		}
+	}
+
+	_, err := g.regexString(`(unclosed`)
+	assert.Error(t, err)
+}
+
+func TestPatternMatches(t *testing.T) {
+	assert.True(t, patternMatches(`^a+$`, "aaa"))
+	assert.False(t, patternMatches(`^a+$`, "b"))
+	assert.True(t, patternMatches(`(invalid`, "b"))
+}
@@ -0,0 +1,273 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity of a finding
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	// SeverityOff disables a rule
+	SeverityOff Severity = "off"
+)
+
+// Rules
+const (
+	// RuleDocumented requires a description for every leaf key
+	RuleDocumented = "documented"
+	// RuleRootAdditionalProperties forbids additionalProperties: true at the root
+	RuleRootAdditionalProperties = "root-additional-properties"
+	// RulePlaintextPassword forbids non-empty defaults for password-like keys
+	RulePlaintextPassword = "plaintext-password"
+	// RuleDeprecatedReplacement requires deprecated keys to name their replacement
+	RuleDeprecatedReplacement = "deprecated-replacement"
+	// RuleRemoteRef forbids $refs to http(s) URLs
+	RuleRemoteRef = "remote-ref"
+)
+
+// DefaultSeverities are the severities of the rules if they aren't configured
+var DefaultSeverities = map[string]Severity{
+	RuleDocumented:               SeverityWarning,
+	RuleRootAdditionalProperties: SeverityError,
+	RulePlaintextPassword:        SeverityError,
+	RuleDeprecatedReplacement:    SeverityWarning,
+	RuleRemoteRef:                SeverityError,
+}
+
+// RuleNames returns the names of all rules, sorted
+func RuleNames() []string {
+	names := make([]string, 0, len(DefaultSeverities))
+	for name := range DefaultSeverities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	// passwordKeyRegex matches keys which usually hold credentials. Keys like
+	// existingSecret or passwordKey, which name a secret, don't match.
+	passwordKeyRegex = regexp.MustCompile(`(?i)(password|passwd|pwd|token|api[-_]?key|secret[-_]?key)$`)
+	// replacementRegex matches descriptions which point to a replacement
+	replacementRegex = regexp.MustCompile(`(?i)\b(instead|replaced|use|superseded|moved to|migrate)\b`)
+)
+
+// Config enables, disables and sets the severity of the rules
+type Config struct {
+	Rules map[string]Severity `yaml:"rules"`
+}
+
+// ReadConfig reads a config file like
+//
+//	rules:
+//	  documented: error
+//	  remote-ref: off
+func ReadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid lint config %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// Validate returns an error for unknown rules and severities
+func (c *Config) Validate() error {
+	for rule, severity := range c.Rules {
+		if _, ok := DefaultSeverities[rule]; !ok {
+			return fmt.Errorf("unknown rule %s, must be one of (%s)", rule, strings.Join(RuleNames(), ", "))
+		}
+		if !slices.Contains([]Severity{SeverityError, SeverityWarning, SeverityOff}, severity) {
+			return fmt.Errorf("unknown severity %s of rule %s, must be one of (error, warning, off)", severity, rule)
+		}
+	}
+	return nil
+}
+
+// Severity returns the configured severity of the rule
+func (c *Config) Severity(rule string) Severity {
+	if c != nil {
+		if severity, ok := c.Rules[rule]; ok {
+			return severity
+		}
+	}
+	return DefaultSeverities[rule]
+}
+
+// Finding is a rule violation in a values file
+type Finding struct {
+	Rule     string
+	Severity Severity
+	// Key is the dot-separated path of the key, empty for the root
+	Key     string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", f.Line, f.Column, f.Message, f.Rule)
+}
+
+// Lint checks the schema generated from the values document by
+// schema.YamlToSchema and returns the findings of the enabled rules, sorted
+// by their position. Keys of array items aren't checked.
+func Lint(doc *yaml.Node, s *schema.Schema, config *Config) []Finding {
+	l := linter{root: s, config: config}
+
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	// Root problems are reported at the first key, where the @schema.root
+	// block is written
+	rootPosition := root
+	if root.Kind == yaml.MappingNode && len(root.Content) > 0 {
+		rootPosition = root.Content[0]
+	}
+
+	if isTrue(s.AdditionalProperties) {
+		l.add(RuleRootAdditionalProperties, "", rootPosition, "the root allows additional properties, typos in the values aren't detected")
+	}
+	for _, ref := range remoteRefs(s, true) {
+		l.add(RuleRemoteRef, "", rootPosition, fmt.Sprintf("the root schema references the remote schema %s", ref))
+	}
+
+	if root.Kind == yaml.MappingNode {
+		l.lintMapping(root, s, "")
+	}
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		if l.findings[i].Line != l.findings[j].Line {
+			return l.findings[i].Line < l.findings[j].Line
+		}
+		return l.findings[i].Column < l.findings[j].Column
+	})
+	return l.findings
+}
+
+type linter struct {
+	root     *schema.Schema
+	config   *Config
+	findings []Finding
+}
+
+func (l *linter) add(rule, key string, node *yaml.Node, message string) {
+	severity := l.config.Severity(rule)
+	if severity == SeverityOff {
+		return
+	}
+	l.findings = append(l.findings, Finding{
+		Rule:     rule,
+		Severity: severity,
+		Key:      key,
+		Line:     node.Line,
+		Column:   node.Column,
+		Message:  message,
+	})
+}
+
+func (l *linter) lintMapping(node *yaml.Node, s *schema.Schema, prefix string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+		if valueNode.Kind == yaml.AliasNode && valueNode.Alias != nil {
+			valueNode = valueNode.Alias
+		}
+
+		property := s.Properties[keyNode.Value]
+		if property == nil {
+			continue
+		}
+		key := keyNode.Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		definition := l.definition(property)
+		description := property.Description
+		if description == "" && definition != nil {
+			description = definition.Description
+		}
+
+		isLeaf := valueNode.Kind != yaml.MappingNode || len(valueNode.Content) == 0
+		if isLeaf && strings.TrimSpace(description) == "" {
+			l.add(RuleDocumented, key, keyNode, fmt.Sprintf("key %s has no description", key))
+		}
+
+		if (property.Deprecated || (definition != nil && definition.Deprecated)) && !replacementRegex.MatchString(description) {
+			l.add(RuleDeprecatedReplacement, key, keyNode, fmt.Sprintf("deprecated key %s doesn't explain its replacement", key))
+		}
+
+		if passwordKeyRegex.MatchString(keyNode.Value) && valueNode.Kind == yaml.ScalarNode && valueNode.Tag == "!!str" && valueNode.Value != "" {
+			l.add(RulePlaintextPassword, key, valueNode, fmt.Sprintf("key %s has a plaintext default, leave it empty or reference a secret", key))
+		}
+
+		// The properties of child keys are checked with them
+		hasChildKeys := valueNode.Kind == yaml.MappingNode && property.Ref == ""
+		for _, ref := range remoteRefs(property, hasChildKeys) {
+			l.add(RuleRemoteRef, key, keyNode, fmt.Sprintf("key %s references the remote schema %s", key, ref))
+		}
+
+		if hasChildKeys {
+			l.lintMapping(valueNode, property, key)
+		}
+	}
+}
+
+// definition returns the definition referenced by the property, if any
+func (l *linter) definition(property *schema.Schema) *schema.Schema {
+	name, ok := strings.CutPrefix(property.Ref, "#/definitions/")
+	if !ok {
+		return nil
+	}
+	return l.root.Definitions[name]
+}
+
+// remoteRefs returns the http(s) $refs of the schema and its subschemas.
+// With skipProperties, the properties aren't searched.
+func remoteRefs(s *schema.Schema, skipProperties bool) []string {
+	var refs []string
+	if strings.HasPrefix(s.Ref, "http://") || strings.HasPrefix(s.Ref, "https://") {
+		refs = append(refs, s.Ref)
+	}
+	properties := make(map[*schema.Schema]bool)
+	if skipProperties {
+		for _, property := range s.Properties {
+			properties[property] = true
+		}
+	}
+	for _, subSchema := range s.Subschemas() {
+		if !properties[subSchema] {
+			refs = append(refs, remoteRefs(subSchema, false)...)
+		}
+	}
+	return refs
+}
+
+// isTrue returns true for additionalProperties: true
+func isTrue(value schema.SchemaOrBool) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case *bool:
+		return v != nil && *v
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func lintValues(t *testing.T, values string, config *Config) []Finding {
+	var doc yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(values), &doc))
+	s, err := schema.YamlToSchema("values.yaml", &doc, false, false, false, true, nil, nil)
+	assert.NoError(t, err)
+	return Lint(&doc, s, config)
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		want   []Finding
+	}{
+		{
+			name:   "documented keys",
+			values: "# Number of replicas\nreplicas: 1\nimage:\n  # The tag\n  tag: latest\n  pullPolicy: Always\n",
+			want: []Finding{
+				{Rule: RuleDocumented, Severity: SeverityWarning, Key: "image.pullPolicy", Line: 6, Column: 3, Message: "key image.pullPolicy has no description"},
+			},
+		},
+		{
+			name:   "definitions document keys",
+			values: "# @schema.root\n# definitions:\n#   port:\n#     type: integer\n#     description: The port\n# @schema.root\n# @schema\n# $ref: '#/definitions/port'\n# @schema\nport: 80\n",
+			want:   nil,
+		},
+		{
+			name:   "root additional properties",
+			values: "# @schema.root\n# additionalProperties: true\n# @schema.root\n# The name\nname: x\n",
+			want: []Finding{
+				{Rule: RuleRootAdditionalProperties, Severity: SeverityError, Line: 5, Column: 1, Message: "the root allows additional properties, typos in the values aren't detected"},
+			},
+		},
+		{
+			name:   "plaintext passwords",
+			values: "auth:\n  # The password\n  password: changeme\n  # The token\n  apiToken: \"\"\n  # The secret with the password\n  existingSecret: auth\n",
+			want: []Finding{
+				{Rule: RulePlaintextPassword, Severity: SeverityError, Key: "auth.password", Line: 3, Column: 13, Message: "key auth.password has a plaintext default, leave it empty or reference a secret"},
+			},
+		},
+		{
+			name: "deprecated keys",
+			values: "# @schema\n# deprecated: true\n# @schema\n# Old name\nold: x\n" +
+				"# @schema\n# deprecated: true\n# @schema\n# Use newer instead\nolder: x\n",
+			want: []Finding{
+				{Rule: RuleDeprecatedReplacement, Severity: SeverityWarning, Key: "old", Line: 5, Column: 1, Message: "deprecated key old doesn't explain its replacement"},
+			},
+		},
+		{
+			name: "remote refs",
+			values: "# @schema\n# $ref: https://example.com/schema.json\n# description: The config\n# @schema\nconfig: {}\n" +
+				"# @schema\n# type: array\n# items:\n#   $ref: http://example.com/item.json\n# @schema\n# The list\nlist: []\n",
+			want: []Finding{
+				{Rule: RuleRemoteRef, Severity: SeverityError, Key: "config", Line: 5, Column: 1, Message: "key config references the remote schema https://example.com/schema.json"},
+				{Rule: RuleRemoteRef, Severity: SeverityError, Key: "list", Line: 12, Column: 1, Message: "key list references the remote schema http://example.com/item.json"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, lintValues(t, tt.values, nil))
+		})
+	}
+}
+
+func TestLint_Config(t *testing.T) {
+	values := "password: changeme\n"
+
+	findings := lintValues(t, values, &Config{Rules: map[string]Severity{RuleDocumented: SeverityOff, RulePlaintextPassword: SeverityWarning}})
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, RulePlaintextPassword, findings[0].Rule)
+		assert.Equal(t, SeverityWarning, findings[0].Severity)
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "lint.yaml")
+
+	assert.NoError(t, os.WriteFile(path, []byte("rules:\n  documented: error\n  remote-ref: off\n"), 0o644))
+	config, err := ReadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, SeverityError, config.Severity(RuleDocumented))
+	assert.Equal(t, SeverityOff, config.Severity(RuleRemoteRef))
+	assert.Equal(t, SeverityError, config.Severity(RulePlaintextPassword))
+
+	assert.NoError(t, os.WriteFile(path, []byte("rules:\n  unknown: error\n"), 0o644))
+	_, err = ReadConfig(path)
+	assert.ErrorContains(t, err, "unknown rule unknown")
+
+	assert.NoError(t, os.WriteFile(path, []byte("rules:\n  documented: fatal\n"), 0o644))
+	_, err = ReadConfig(path)
+	assert.ErrorContains(t, err, "unknown severity fatal")
+}
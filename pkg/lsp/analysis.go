@@ -0,0 +1,371 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/dadav/helm-schema/pkg/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Options configure how the effective schema of a document is generated.
+// They correspond to the flags of the generator.
+type Options struct {
+	KeepFullComment           bool
+	HelmDocsCompatibilityMode bool
+	DontRemoveHelmDocsPrefix  bool
+	DontAddGlobal             bool
+	SkipAutoGeneration        *schema.SkipAutoGenerationConfig
+}
+
+// schemaTypes are offered as completion for the type keyword
+var schemaTypes = []string{"array", "boolean", "integer", "null", "number", "object", "string"}
+
+var yamlErrorLineRegex = regexp.MustCompile(`line (\d+)`)
+
+// annotationBlock is a @schema or @schema.root block of a document
+type annotationBlock struct {
+	// start and end are the zero-based lines of the opening and the last line
+	// of the block, which is the closing marker if the block is closed
+	start, end int
+	root       bool
+	closed     bool
+}
+
+func splitLines(text string) []string {
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+// findBlocks returns all annotation blocks. A block which isn't closed ends at
+// the last comment line following its opening marker.
+func findBlocks(lines []string) []annotationBlock {
+	var blocks []annotationBlock
+	var current *annotationBlock
+
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if current != nil {
+			if !strings.HasPrefix(trimmed, schema.CommentPrefix) {
+				current.end = i - 1
+				blocks = append(blocks, *current)
+				current = nil
+				continue
+			}
+			if isMarker(trimmed, current.root) {
+				current.end = i
+				current.closed = true
+				blocks = append(blocks, *current)
+				current = nil
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, schema.SchemaRootPrefix) {
+			current = &annotationBlock{start: i, root: true}
+		} else if strings.HasPrefix(trimmed, schema.SchemaPrefix) {
+			current = &annotationBlock{start: i}
+		}
+	}
+	if current != nil {
+		current.end = len(lines) - 1
+		blocks = append(blocks, *current)
+	}
+
+	return blocks
+}
+
+func isMarker(trimmedLine string, root bool) bool {
+	if root {
+		return strings.HasPrefix(trimmedLine, schema.SchemaRootPrefix)
+	}
+	return strings.HasPrefix(trimmedLine, schema.SchemaPrefix) && !strings.HasPrefix(trimmedLine, schema.SchemaRootPrefix)
+}
+
+// blockComment returns the comment of the block without indentation, like
+// it is passed to the annotation parser by the generator
+func blockComment(lines []string, block annotationBlock) string {
+	commentLines := make([]string, 0, block.end-block.start+1)
+	for _, line := range lines[block.start : block.end+1] {
+		commentLines = append(commentLines, strings.TrimLeft(line, " \t"))
+	}
+	return strings.Join(commentLines, "\n")
+}
+
+// utf16Len returns the length of s in UTF-16 code units, which LSP uses for
+// character offsets
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// byteOffset converts a UTF-16 character offset into a byte offset of line
+func byteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(line)
+}
+
+// runeOffset converts a UTF-16 character offset into a rune offset of line,
+// yaml columns count runes
+func runeOffset(line string, character int) int {
+	units := 0
+	for i, r := range []rune(line) {
+		if units >= character {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return utf8.RuneCountInString(line)
+}
+
+func lineRange(lines []string, start, end int) lspRange {
+	if end >= len(lines) {
+		end = len(lines) - 1
+	}
+	if start > end {
+		start = end
+	}
+	return lspRange{
+		Start: position{Line: start},
+		End:   position{Line: end, Character: utf16Len(lines[end])},
+	}
+}
+
+func errorLine(err error) int {
+	if line := schema.ErrorLine(err); line > 0 {
+		return line
+	}
+	if match := yamlErrorLineRegex.FindStringSubmatch(err.Error()); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return line
+	}
+	return 0
+}
+
+// generate returns the schema the generator creates for the document.
+// Empty documents return nil.
+func generate(text, valuesPath string, opts Options) (*schema.Schema, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &node); err != nil {
+		return nil, err
+	}
+	if node.Kind == 0 {
+		return nil, nil
+	}
+	return schema.YamlToSchema(
+		valuesPath,
+		&node,
+		opts.KeepFullComment,
+		opts.HelmDocsCompatibilityMode,
+		opts.DontRemoveHelmDocsPrefix,
+		opts.DontAddGlobal,
+		opts.SkipAutoGeneration,
+		nil,
+	)
+}
+
+// diagnose returns the problems of the annotation blocks of the document.
+// Once every block is valid, the errors of the generator are reported.
+func diagnose(text, valuesPath string, opts Options) []diagnostic {
+	lines := splitLines(text)
+	diagnostics := []diagnostic{}
+	addDiagnostic := func(r lspRange, message string) {
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    r,
+			Severity: severityError,
+			Source:   "helm-schema",
+			Message:  message,
+		})
+	}
+
+	for _, block := range findBlocks(lines) {
+		if !block.closed {
+			addDiagnostic(lineRange(lines, block.start, block.start), "unclosed @schema block")
+			continue
+		}
+
+		comment := blockComment(lines, block)
+		var result schema.Schema
+		var err error
+		if block.root {
+			result, _, err = schema.GetRootSchemaFromComment(comment)
+		} else {
+			result, _, err = schema.GetSchemaFromComment(comment)
+		}
+		if err != nil {
+			r := lineRange(lines, block.start, block.end)
+			// Lines of yaml errors are relative to the content of the block
+			if line := errorLine(err); line > 0 && block.start+line < block.end {
+				r = lineRange(lines, block.start+line, block.start+line)
+			}
+			addDiagnostic(r, fmt.Sprintf("invalid @schema block: %s", err))
+			continue
+		}
+		if err := result.Validate(); err != nil {
+			addDiagnostic(lineRange(lines, block.start, block.end), fmt.Sprintf("invalid schema: %s", err))
+		}
+	}
+
+	if len(diagnostics) == 0 {
+		if _, err := generate(text, valuesPath, opts); err != nil {
+			line := max(errorLine(err)-1, 0)
+			addDiagnostic(lineRange(lines, line, line), err.Error())
+		}
+	}
+
+	return diagnostics
+}
+
+// complete returns keyword completions inside of annotation blocks and type
+// completions for the type keyword.
+func complete(text string, pos position) []completionItem {
+	lines := splitLines(text)
+	if pos.Line >= len(lines) {
+		return nil
+	}
+
+	inBlock := false
+	for _, block := range findBlocks(lines) {
+		if pos.Line > block.start && (pos.Line < block.end || (!block.closed && pos.Line == block.end)) {
+			inBlock = true
+			break
+		}
+	}
+	if !inBlock {
+		return nil
+	}
+
+	line := lines[pos.Line]
+	prefix := strings.TrimLeft(line[:byteOffset(line, pos.Character)], " \t")
+	content := strings.TrimLeft(strings.TrimPrefix(prefix, schema.CommentPrefix), " -")
+
+	if key, _, found := strings.Cut(content, ":"); found {
+		if strings.TrimSpace(key) != "type" {
+			return nil
+		}
+		items := make([]completionItem, 0, len(schemaTypes))
+		for _, t := range schemaTypes {
+			items = append(items, completionItem{Label: t, Kind: completionKindValue, Detail: "JSON Schema type"})
+		}
+		return items
+	}
+	if strings.ContainsAny(content, " \t") {
+		return nil
+	}
+
+	keywords := schema.AnnotationKeywords()
+	items := make([]completionItem, 0, len(keywords))
+	for _, keyword := range keywords {
+		items = append(items, completionItem{
+			Label:      keyword,
+			Kind:       completionKindKeyword,
+			Detail:     "JSON Schema keyword",
+			InsertText: keyword + ": ",
+		})
+	}
+	return items
+}
+
+// findKeyAt returns the path and node of the mapping key at the given
+// one-based line and zero-based rune column. Array items are added to the
+// path as [].
+func findKeyAt(node *yaml.Node, line, column int, path []string) ([]string, *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if found, key := findKeyAt(child, line, column, path); key != nil {
+				return found, key
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			keyPath := append(append([]string{}, path...), keyNode.Value)
+			keyLength := utf8.RuneCountInString(keyNode.Value)
+			if keyNode.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+				keyLength += 2
+			}
+			if keyNode.Line == line && column >= keyNode.Column-1 && column <= keyNode.Column-1+keyLength {
+				return keyPath, keyNode
+			}
+			if found, key := findKeyAt(valueNode, line, column, keyPath); key != nil {
+				return found, key
+			}
+		}
+	case yaml.SequenceNode:
+		itemPath := append(append([]string{}, path...), "[]")
+		for _, item := range node.Content {
+			if found, key := findKeyAt(item, line, column, itemPath); key != nil {
+				return found, key
+			}
+		}
+	}
+	return nil, nil
+}
+
+// hoverAt returns the effective schema of the key at the given position
+func hoverAt(text, valuesPath string, pos position, opts Options) *hover {
+	lines := splitLines(text)
+	if pos.Line >= len(lines) {
+		return nil
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &node); err != nil {
+		return nil
+	}
+	path, keyNode := findKeyAt(&node, pos.Line+1, runeOffset(lines[pos.Line], pos.Character), nil)
+	if keyNode == nil {
+		return nil
+	}
+
+	keyLine := []rune(lines[keyNode.Line-1])
+	start := min(keyNode.Column-1, len(keyLine))
+	end := min(start+utf8.RuneCountInString(keyNode.Value), len(keyLine))
+	r := &lspRange{
+		Start: position{Line: keyNode.Line - 1, Character: utf16Len(string(keyLine[:start]))},
+		End:   position{Line: keyNode.Line - 1, Character: utf16Len(string(keyLine[:end]))},
+	}
+
+	generated, err := generate(text, valuesPath, opts)
+	if err != nil {
+		return &hover{
+			Contents: markupContent{Kind: "markdown", Value: fmt.Sprintf("schema could not be generated: %s", err)},
+			Range:    r,
+		}
+	}
+
+	current := generated
+	for _, part := range path {
+		if current == nil {
+			break
+		}
+		if part == "[]" {
+			current = current.Items
+		} else {
+			current = current.Properties[part]
+		}
+	}
+	if current == nil {
+		return nil
+	}
+
+	jsonStr, err := current.ToJson()
+	if err != nil {
+		return nil
+	}
+	return &hover{
+		Contents: markupContent{
+			Kind:  "markdown",
+			Value: fmt.Sprintf("**%s**\n\n```json\n%s\n```", strings.Join(path, "."), jsonStr),
+		},
+		Range: r,
+	}
+}
@@ -0,0 +1,126 @@
+package lsp
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDocument = `# @schema
+# type: integer
+# minimum: 1
+# @schema
+replicas: 1
+image:
+  # @schema
+  # type: [string
+  # @schema
+  tag: latest
+  # @schema
+  # type: strin
+  # @schema
+  pullPolicy: Always
+# @schema
+# enum: [a, b]
+unclosed: a
+`
+
+func TestFindBlocks(t *testing.T) {
+	blocks := findBlocks(splitLines(testDocument))
+	assert.Equal(t, []annotationBlock{
+		{start: 0, end: 3, closed: true},
+		{start: 6, end: 8, closed: true},
+		{start: 10, end: 12, closed: true},
+		{start: 14, end: 15},
+	}, blocks)
+
+	rootBlocks := findBlocks(splitLines("# @schema.root\n# title: x\n# @schema.root\n# @schema\n# type: string\n# @schema\nkey: a\n"))
+	assert.Equal(t, []annotationBlock{
+		{start: 0, end: 2, root: true, closed: true},
+		{start: 3, end: 5, closed: true},
+	}, rootBlocks)
+}
+
+func TestDiagnose(t *testing.T) {
+	diagnostics := diagnose(testDocument, "", Options{})
+
+	if assert.Len(t, diagnostics, 3) {
+		// yaml errors point to the line inside of the block
+		assert.Equal(t, 7, diagnostics[0].Range.Start.Line)
+		assert.Equal(t, 7, diagnostics[0].Range.End.Line)
+		assert.Contains(t, diagnostics[0].Message, "invalid @schema block")
+
+		assert.Equal(t, lspRange{Start: position{Line: 10}, End: position{Line: 12, Character: 11}}, diagnostics[1].Range)
+		assert.Equal(t, "invalid schema: unsupported type strin", diagnostics[1].Message)
+
+		assert.Equal(t, 14, diagnostics[2].Range.Start.Line)
+		assert.Equal(t, "unclosed @schema block", diagnostics[2].Message)
+	}
+}
+
+func TestDiagnose_GeneratorErrors(t *testing.T) {
+	// The block itself is valid, but the generator rejects it
+	diagnostics := diagnose("a: 1\n# @schema\n# const-from-value: true\n# const: x\n# @schema\nb: x\n", "", Options{})
+	if assert.Len(t, diagnostics, 1) {
+		assert.Equal(t, 5, diagnostics[0].Range.Start.Line)
+	}
+
+	diagnostics = diagnose("a: [\n", "", Options{})
+	if assert.Len(t, diagnostics, 1) {
+		assert.Contains(t, diagnostics[0].Message, "yaml:")
+	}
+
+	assert.Empty(t, diagnose("# @schema\n# type: string\n# @schema\nvalid: a\n", "", Options{}))
+	assert.Empty(t, diagnose("", "", Options{}))
+}
+
+func TestComplete(t *testing.T) {
+	labels := func(items []completionItem) []string {
+		var result []string
+		for _, item := range items {
+			result = append(result, item.Label)
+		}
+		return result
+	}
+
+	// Keywords inside of a block
+	items := complete(testDocument, position{Line: 2, Character: 4})
+	assert.Contains(t, labels(items), "minimum")
+	assert.Contains(t, labels(items), "const-from-value")
+	assert.Equal(t, "minimum: ", items[slices.Index(labels(items), "minimum")].InsertText)
+
+	// Types after the type keyword
+	assert.Equal(t, schemaTypes, labels(complete(testDocument, position{Line: 11, Character: 10})))
+
+	// No completion for other values, outside of blocks or on the markers
+	assert.Empty(t, complete(testDocument, position{Line: 2, Character: 11}))
+	assert.Empty(t, complete(testDocument, position{Line: 4, Character: 2}))
+	assert.Empty(t, complete(testDocument, position{Line: 0, Character: 2}))
+
+	// Unclosed blocks are completed while typing
+	assert.NotEmpty(t, complete(testDocument, position{Line: 15, Character: 3}))
+}
+
+func TestHoverAt(t *testing.T) {
+	document := "# @schema\n# minimum: 1\n# @schema\n# The number of replicas\nreplicas: 1\nimage:\n  tag: latest\n"
+
+	result := hoverAt(document, "", position{Line: 4, Character: 3}, Options{})
+	if assert.NotNil(t, result) {
+		assert.Equal(t, "markdown", result.Contents.Kind)
+		assert.True(t, strings.HasPrefix(result.Contents.Value, "**replicas**\n\n```json\n"))
+		assert.Contains(t, result.Contents.Value, `"minimum": 1`)
+		assert.Contains(t, result.Contents.Value, `"description": "The number of replicas"`)
+		assert.Equal(t, &lspRange{Start: position{Line: 4}, End: position{Line: 4, Character: 8}}, result.Range)
+	}
+
+	result = hoverAt(document, "", position{Line: 6, Character: 2}, Options{})
+	if assert.NotNil(t, result) {
+		assert.True(t, strings.HasPrefix(result.Contents.Value, "**image.tag**"))
+	}
+
+	// Values and comments have no hover
+	assert.Nil(t, hoverAt(document, "", position{Line: 6, Character: 9}, Options{}))
+	assert.Nil(t, hoverAt(document, "", position{Line: 1, Character: 3}, Options{}))
+}
@@ -0,0 +1,188 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Server is a language server for values files. It publishes diagnostics
+// for invalid @schema annotations, completes keywords inside of them and
+// shows the generated schema of the key under the cursor on hover.
+type Server struct {
+	version   string
+	opts      Options
+	documents map[string]string
+	out       io.Writer
+	shutdown  bool
+}
+
+// NewServer creates a new language server
+func NewServer(version string, opts Options) *Server {
+	return &Server{
+		version:   version,
+		opts:      opts,
+		documents: make(map[string]string),
+	}
+}
+
+// Run serves the language server protocol until the client sends exit or
+// closes the input.
+func (s *Server) Run(in io.Reader, out io.Writer) error {
+	s.out = out
+	reader := bufio.NewReader(in)
+
+	for {
+		msg, err := readMessage(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			if msg == nil {
+				// The framing is broken, we can't continue
+				return err
+			}
+			log.Errorf("Failed to parse message: %s", err)
+			s.replyError(nil, codeParseError, err.Error())
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("received exit before shutdown")
+			}
+			return nil
+		}
+		s.handle(msg)
+	}
+}
+
+func (s *Server) handle(msg *message) {
+	switch msg.Method {
+	case "initialize":
+		s.reply(msg.ID, map[string]any{
+			"capabilities": map[string]any{
+				// Full document sync
+				"textDocumentSync":   1,
+				"completionProvider": map[string]any{"triggerCharacters": []string{" ", ":"}},
+				"hoverProvider":      true,
+			},
+			"serverInfo": map[string]any{"name": "helm-schema", "version": s.version},
+		})
+	case "shutdown":
+		s.shutdown = true
+		s.reply(msg.ID, nil)
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if s.decodeParams(msg, &params) {
+			s.documents[params.TextDocument.URI] = params.TextDocument.Text
+			s.publishDiagnostics(params.TextDocument.URI)
+		}
+	case "textDocument/didChange":
+		var params didChangeParams
+		if s.decodeParams(msg, &params) && len(params.ContentChanges) > 0 {
+			s.documents[params.TextDocument.URI] = params.ContentChanges[len(params.ContentChanges)-1].Text
+			s.publishDiagnostics(params.TextDocument.URI)
+		}
+	case "textDocument/didClose":
+		var params didCloseParams
+		if s.decodeParams(msg, &params) {
+			delete(s.documents, params.TextDocument.URI)
+			s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+				URI:         params.TextDocument.URI,
+				Diagnostics: []diagnostic{},
+			})
+		}
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if s.decodeParams(msg, &params) {
+			items := complete(s.documents[params.TextDocument.URI], params.Position)
+			if items == nil {
+				items = []completionItem{}
+			}
+			s.reply(msg.ID, items)
+		}
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if s.decodeParams(msg, &params) {
+			uri := params.TextDocument.URI
+			result := hoverAt(s.documents[uri], pathFromURI(uri), params.Position, s.opts)
+			if result == nil {
+				s.reply(msg.ID, nil)
+			} else {
+				s.reply(msg.ID, result)
+			}
+		}
+	default:
+		// Unknown notifications are ignored, unknown requests must be answered
+		if msg.ID != nil {
+			s.replyError(msg.ID, codeMethodNotFound, "method not found: "+msg.Method)
+		}
+	}
+}
+
+// decodeParams decodes the params of the message and answers requests with
+// an error if they are invalid
+func (s *Server) decodeParams(msg *message, params any) bool {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		log.Errorf("Invalid params for %s: %s", msg.Method, err)
+		if msg.ID != nil {
+			s.replyError(msg.ID, codeInvalidParams, err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+func (s *Server) publishDiagnostics(uri string) {
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diagnose(s.documents[uri], pathFromURI(uri), s.opts),
+	})
+}
+
+func (s *Server) reply(id *json.RawMessage, result any) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		s.replyError(id, codeParseError, err.Error())
+		return
+	}
+	s.write(&message{ID: id, Result: raw})
+}
+
+func (s *Server) replyError(id *json.RawMessage, code int, text string) {
+	if id == nil {
+		null := json.RawMessage("null")
+		id = &null
+	}
+	s.write(&message{ID: id, Error: &responseError{Code: code, Message: text}})
+}
+
+func (s *Server) notify(method string, params any) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		log.Errorf("Failed to marshal %s: %s", method, err)
+		return
+	}
+	s.write(&message{Method: method, Params: raw})
+}
+
+func (s *Server) write(msg *message) {
+	if err := writeMessage(s.out, msg); err != nil {
+		log.Errorf("Failed to write message: %s", err)
+	}
+}
+
+// pathFromURI returns the local path of a file URI, used to resolve relative
+// $ref paths. Other URIs return an empty path.
+func pathFromURI(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return ""
+	}
+	return parsed.Path
+}
@@ -0,0 +1,118 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func frame(t *testing.T, messages ...string) *bytes.Buffer {
+	var buf bytes.Buffer
+	for _, msg := range messages {
+		assert.True(t, json.Valid([]byte(msg)), msg)
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	return &buf
+}
+
+func readAll(t *testing.T, out *bytes.Buffer) []map[string]any {
+	var result []map[string]any
+	reader := bufio.NewReader(out)
+	for reader.Buffered() > 0 || out.Len() > 0 {
+		msg, err := readMessage(reader)
+		if !assert.NoError(t, err) {
+			break
+		}
+		raw, err := json.Marshal(msg)
+		assert.NoError(t, err)
+		var decoded map[string]any
+		assert.NoError(t, json.Unmarshal(raw, &decoded))
+		result = append(result, decoded)
+	}
+	return result
+}
+
+func TestServer(t *testing.T) {
+	in := frame(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///tmp/values.yaml","text":"# @schema\n# type: strin\n# @schema\nkey: a\n"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///tmp/values.yaml"},"contentChanges":[{"text":"# @schema\n# type: string\n# @schema\nkey: a\n"}]}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/completion","params":{"textDocument":{"uri":"file:///tmp/values.yaml"},"position":{"line":1,"character":2}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///tmp/values.yaml"},"position":{"line":3,"character":1}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///tmp/values.yaml"},"position":{"line":0,"character":1}}}`,
+		`{"jsonrpc":"2.0","id":"five","method":"unknown/method","params":{}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	var out bytes.Buffer
+
+	err := NewServer("1.0.0", Options{}).Run(in, &out)
+	assert.NoError(t, err)
+
+	messages := readAll(t, &out)
+	if !assert.Len(t, messages, 8) {
+		return
+	}
+
+	// initialize
+	assert.Equal(t, float64(1), messages[0]["id"])
+	result := messages[0]["result"].(map[string]any)
+	assert.Equal(t, true, result["capabilities"].(map[string]any)["hoverProvider"])
+
+	// didOpen publishes the invalid type
+	assert.Equal(t, "textDocument/publishDiagnostics", messages[1]["method"])
+	diagnostics := messages[1]["params"].(map[string]any)["diagnostics"].([]any)
+	if assert.Len(t, diagnostics, 1) {
+		assert.Equal(t, "invalid schema: unsupported type strin", diagnostics[0].(map[string]any)["message"])
+	}
+
+	// didChange clears it
+	assert.Empty(t, messages[2]["params"].(map[string]any)["diagnostics"])
+
+	// completion
+	assert.Equal(t, float64(2), messages[3]["id"])
+	assert.NotEmpty(t, messages[3]["result"])
+
+	// hover on the key and on the comment
+	assert.Contains(t, messages[4]["result"].(map[string]any)["contents"].(map[string]any)["value"], `"type": "string"`)
+	assert.Contains(t, messages[5], "result")
+	assert.Nil(t, messages[5]["result"])
+
+	// unknown requests are rejected
+	assert.Equal(t, "five", messages[6]["id"])
+	assert.Equal(t, float64(codeMethodNotFound), messages[6]["error"].(map[string]any)["code"])
+
+	// shutdown
+	assert.Equal(t, float64(6), messages[7]["id"])
+}
+
+func TestServer_ExitWithoutShutdown(t *testing.T) {
+	var out bytes.Buffer
+	err := NewServer("1.0.0", Options{}).Run(frame(t, `{"jsonrpc":"2.0","method":"exit"}`), &out)
+	assert.Error(t, err)
+}
+
+func TestServer_InvalidMessage(t *testing.T) {
+	var out bytes.Buffer
+	in := bytes.NewBufferString("Content-Length: 3\r\n\r\n{x}")
+	in.Write(frame(t, `{"jsonrpc":"2.0","id":1,"method":"shutdown"}`, `{"jsonrpc":"2.0","method":"exit"}`).Bytes())
+
+	err := NewServer("1.0.0", Options{}).Run(in, &out)
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), `"id":null`)
+	messages := readAll(t, &out)
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, float64(codeParseError), messages[0]["error"].(map[string]any)["code"])
+	}
+}
+
+func TestPathFromURI(t *testing.T) {
+	assert.Equal(t, "/charts/my chart/values.yaml", pathFromURI("file:///charts/my%20chart/values.yaml"))
+	assert.Equal(t, "", pathFromURI("untitled:Untitled-1"))
+}
@@ -0,0 +1,425 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Supported report formats
+const (
+	FormatJSON   = "json"
+	FormatJUnit  = "junit"
+	FormatSARIF  = "sarif"
+	FormatGitHub = "github"
+)
+
+// Formats lists all supported report formats
+var Formats = []string{FormatJSON, FormatJUnit, FormatSARIF, FormatGitHub}
+
+// Severity of a report entry
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Categories of report entries
+const (
+	// CategoryValues is used for errors while reading or parsing the values of a chart
+	CategoryValues = "values"
+	// CategoryStaleSchema is used for schema files which are missing or not up-to-date (--check)
+	CategoryStaleSchema = "stale-schema"
+	// CategoryInvalidSchema is used for generated schemas which don't compile
+	CategoryInvalidSchema = "invalid-schema"
+	// CategoryDependency is used for problems with chart dependencies
+	CategoryDependency = "dependency"
+	// CategoryOutput is used for errors while serializing or writing the schema
+	CategoryOutput = "output"
+	// CategorySearch is used for errors while searching for charts
+	CategorySearch = "search"
+)
+
+var categoryDescriptions = map[string]string{
+	CategoryValues:        "The values file could not be converted into a jsonschema",
+	CategoryStaleSchema:   "The schema file is missing or not up-to-date",
+	CategoryInvalidSchema: "The generated schema is not a valid jsonschema",
+	CategoryDependency:    "A chart dependency could not be resolved",
+	CategoryOutput:        "The schema could not be written",
+	CategorySearch:        "Charts could not be searched",
+}
+
+// Chart identifies a processed chart
+type Chart struct {
+	Name       string `json:"name"`
+	ChartPath  string `json:"chartPath"`
+	ValuesPath string `json:"valuesPath,omitempty"`
+}
+
+// Entry is a single problem found while processing the charts
+type Entry struct {
+	Chart
+	Severity Severity `json:"severity"`
+	Category string   `json:"category"`
+	Message  string   `json:"message"`
+	// Line of the values file the problem was found at, 0 if unknown
+	Line int `json:"line,omitempty"`
+}
+
+// Report collects the outcome of a helm-schema run. All methods are safe to
+// call on a nil Report, which makes reporting optional for callers.
+type Report struct {
+	ToolVersion string
+
+	mu      sync.Mutex
+	charts  []Chart
+	entries []Entry
+}
+
+// New creates a new report for the given helm-schema version
+func New(toolVersion string) *Report {
+	return &Report{ToolVersion: toolVersion}
+}
+
+// AddChart records a processed chart
+func (r *Report) AddChart(chart Chart) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !slices.Contains(r.charts, chart) {
+		r.charts = append(r.charts, chart)
+	}
+}
+
+// Add records a problem
+func (r *Report) Add(entry Entry) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// Charts returns the processed charts, sorted by their path
+func (r *Report) Charts() []Chart {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	charts := slices.Clone(r.charts)
+	slices.SortFunc(charts, func(a, b Chart) int {
+		return strings.Compare(a.ChartPath, b.ChartPath)
+	})
+	return charts
+}
+
+// Entries returns the recorded problems
+func (r *Report) Entries() []Entry {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.entries)
+}
+
+// ValidateFormat returns an error if the format is not supported
+func ValidateFormat(format string) error {
+	if !slices.Contains(Formats, format) {
+		return fmt.Errorf("unsupported report format %s, must be one of (%s)", format, strings.Join(Formats, ", "))
+	}
+	return nil
+}
+
+// Write writes the report in the given format
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		return r.writeJSON(w)
+	case FormatJUnit:
+		return r.writeJUnit(w)
+	case FormatSARIF:
+		return r.writeSARIF(w)
+	case FormatGitHub:
+		return r.writeGitHub(w)
+	}
+	return ValidateFormat(format)
+}
+
+// location returns the file an entry should be reported at
+func (e Entry) location() string {
+	if e.ValuesPath != "" {
+		return e.ValuesPath
+	}
+	return e.ChartPath
+}
+
+func (r *Report) writeJSON(w io.Writer) error {
+	charts := r.Charts()
+	entries := r.Entries()
+	if charts == nil {
+		charts = []Chart{}
+	}
+	if entries == nil {
+		entries = []Entry{}
+	}
+
+	errorCount := 0
+	for _, entry := range entries {
+		if entry.Severity == SeverityError {
+			errorCount++
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Version string  `json:"version"`
+		Success bool    `json:"success"`
+		Charts  []Chart `json:"charts"`
+		Entries []Entry `json:"entries"`
+	}{
+		Version: r.ToolVersion,
+		Success: errorCount == 0,
+		Charts:  charts,
+		Entries: entries,
+	})
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []junitFailure `xml:"failure,omitempty"`
+	SystemOut string         `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes one test case per chart. Errors become failures, warnings
+// are added to the output of the test case.
+func (r *Report) writeJUnit(w io.Writer) error {
+	suite := junitTestSuite{Name: "helm-schema"}
+	index := make(map[string]int)
+
+	testCaseFor := func(chart Chart) *junitTestCase {
+		key := chart.ChartPath
+		if i, ok := index[key]; ok {
+			return &suite.TestCases[i]
+		}
+		name := chart.Name
+		if name == "" {
+			name = "helm-schema"
+		}
+		index[key] = len(suite.TestCases)
+		suite.TestCases = append(suite.TestCases, junitTestCase{Name: name, ClassName: chart.ChartPath})
+		return &suite.TestCases[len(suite.TestCases)-1]
+	}
+
+	for _, chart := range r.Charts() {
+		testCaseFor(chart)
+	}
+	for _, entry := range r.Entries() {
+		testCase := testCaseFor(entry.Chart)
+		text := entry.Message
+		if entry.Line > 0 {
+			text = fmt.Sprintf("%s:%d: %s", entry.location(), entry.Line, entry.Message)
+		}
+		if entry.Severity == SeverityError {
+			testCase.Failures = append(testCase.Failures, junitFailure{
+				Message: entry.Message,
+				Type:    entry.Category,
+				Text:    text,
+			})
+		} else {
+			testCase.SystemOut += fmt.Sprintf("%s: %s\n", entry.Severity, text)
+		}
+	}
+
+	for _, testCase := range suite.TestCases {
+		if len(testCase.Failures) > 0 {
+			suite.Failures++
+		}
+	}
+	suite.Tests = len(suite.TestCases)
+
+	suites := junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+func (r *Report) writeSARIF(w io.Writer) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "helm-schema",
+			Version:        r.ToolVersion,
+			InformationURI: "https://github.com/dadav/helm-schema",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	seenRules := make(map[string]bool)
+	for _, entry := range r.Entries() {
+		if !seenRules[entry.Category] {
+			seenRules[entry.Category] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               entry.Category,
+				ShortDescription: sarifMessage{Text: categoryDescriptions[entry.Category]},
+			})
+		}
+
+		message := entry.Message
+		if entry.Name != "" {
+			message = fmt.Sprintf("%s (chart %s)", entry.Message, entry.Name)
+		}
+		result := sarifResult{
+			RuleID:  entry.Category,
+			Level:   string(entry.Severity),
+			Message: sarifMessage{Text: message},
+		}
+		if location := entry.location(); location != "" {
+			physical := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: location}}
+			if entry.Line > 0 {
+				physical.Region = &sarifRegion{StartLine: entry.Line}
+			}
+			result.Locations = []sarifLocation{{PhysicalLocation: physical}}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// writeGitHub writes GitHub Actions workflow commands, which are turned into
+// annotations of the workflow run.
+// See: https://docs.github.com/en/actions/reference/workflow-commands-for-github-actions
+func (r *Report) writeGitHub(w io.Writer) error {
+	for _, entry := range r.Entries() {
+		var properties []string
+		if location := entry.location(); location != "" {
+			properties = append(properties, "file="+escapeGitHubProperty(location))
+		}
+		if entry.Line > 0 {
+			properties = append(properties, fmt.Sprintf("line=%d", entry.Line))
+		}
+		title := "helm-schema"
+		if entry.Name != "" {
+			title = fmt.Sprintf("helm-schema (%s)", entry.Name)
+		}
+		properties = append(properties, "title="+escapeGitHubProperty(title))
+
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", entry.Severity, strings.Join(properties, ","), escapeGitHubData(entry.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
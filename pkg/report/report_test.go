@@ -0,0 +1,107 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testReport() *Report {
+	r := New("1.2.3")
+	chart := Chart{Name: "foo", ChartPath: "foo/Chart.yaml", ValuesPath: "foo/values.yaml"}
+	r.AddChart(chart)
+	r.AddChart(Chart{Name: "bar", ChartPath: "bar/Chart.yaml", ValuesPath: "bar/values.yaml"})
+	r.Add(Entry{Chart: chart, Severity: SeverityError, Category: CategoryValues, Message: "error validating schema of key a: unsupported type x", Line: 3})
+	r.Add(Entry{Chart: chart, Severity: SeverityWarning, Category: CategoryDependency, Message: "dependency dep specified but no schema found"})
+	return r
+}
+
+func TestNilReport(t *testing.T) {
+	var r *Report
+	r.AddChart(Chart{Name: "foo"})
+	r.Add(Entry{Message: "ignored"})
+	assert.Empty(t, r.Entries())
+	assert.Empty(t, r.Charts())
+}
+
+func TestValidateFormat(t *testing.T) {
+	for _, format := range Formats {
+		assert.NoError(t, ValidateFormat(format))
+	}
+	assert.Error(t, ValidateFormat("html"))
+	assert.Error(t, New("").Write(&bytes.Buffer{}, "html"))
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, testReport().Write(&out, FormatJSON))
+
+	var decoded struct {
+		Version string  `json:"version"`
+		Success bool    `json:"success"`
+		Charts  []Chart `json:"charts"`
+		Entries []Entry `json:"entries"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, "1.2.3", decoded.Version)
+	assert.False(t, decoded.Success)
+	assert.Equal(t, []string{"bar/Chart.yaml", "foo/Chart.yaml"}, []string{decoded.Charts[0].ChartPath, decoded.Charts[1].ChartPath})
+	assert.Len(t, decoded.Entries, 2)
+	assert.Equal(t, "foo", decoded.Entries[0].Name)
+	assert.Equal(t, "foo/values.yaml", decoded.Entries[0].ValuesPath)
+	assert.Equal(t, 3, decoded.Entries[0].Line)
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, testReport().Write(&out, FormatJUnit))
+
+	var decoded junitTestSuites
+	assert.NoError(t, xml.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, 2, decoded.Tests)
+	assert.Equal(t, 1, decoded.Failures)
+
+	cases := decoded.Suites[0].TestCases
+	assert.Equal(t, "bar", cases[0].Name)
+	assert.Empty(t, cases[0].Failures)
+	assert.Equal(t, "foo", cases[1].Name)
+	if assert.Len(t, cases[1].Failures, 1) {
+		assert.Equal(t, CategoryValues, cases[1].Failures[0].Type)
+		assert.Equal(t, "foo/values.yaml:3: error validating schema of key a: unsupported type x", cases[1].Failures[0].Text)
+	}
+	assert.Contains(t, cases[1].SystemOut, "warning: dependency dep specified but no schema found")
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, testReport().Write(&out, FormatSARIF))
+
+	var decoded sarifLog
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, "2.1.0", decoded.Version)
+	run := decoded.Runs[0]
+	assert.Len(t, run.Tool.Driver.Rules, 2)
+	if assert.Len(t, run.Results, 2) {
+		assert.Equal(t, "error", run.Results[0].Level)
+		assert.Equal(t, "foo/values.yaml", run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+		assert.Equal(t, 3, run.Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+		assert.Equal(t, "warning", run.Results[1].Level)
+		assert.Nil(t, run.Results[1].Locations[0].PhysicalLocation.Region)
+	}
+}
+
+func TestWriteGitHub(t *testing.T) {
+	var out bytes.Buffer
+	r := testReport()
+	r.Add(Entry{Severity: SeverityError, Category: CategorySearch, Message: "line1\nline2 100%"})
+	assert.NoError(t, r.Write(&out, FormatGitHub))
+
+	assert.Equal(t,
+		"::error file=foo/values.yaml,line=3,title=helm-schema (foo)::error validating schema of key a: unsupported type x\n"+
+			"::warning file=foo/values.yaml,title=helm-schema (foo)::dependency dep specified but no schema found\n"+
+			"::error title=helm-schema::line1%0Aline2 100%25\n",
+		out.String())
+}
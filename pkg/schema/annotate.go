@@ -1,10 +1,16 @@
 package schema
 
 import (
+	"bytes"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -47,83 +53,381 @@ func typeAnnotationFromTag(tag string) string {
 	}
 }
 
+// possibleAnnotateKeywords are the keywords annotate mode can write
+var possibleAnnotateKeywords = []string{"type", "format", "description", "additionalProperties", "required", "items"}
+
+// Presets of annotate keywords
+const (
+	AnnotatePresetMinimal = "minimal"
+	AnnotatePresetRich    = "rich"
+)
+
+// AnnotateConfig selects the keywords annotate mode writes into the @schema blocks
+type AnnotateConfig struct {
+	Type, Format, Description, AdditionalProperties, Required, Items bool
+}
+
+// MinimalAnnotateConfig only writes the type of the keys
+var MinimalAnnotateConfig = &AnnotateConfig{Type: true}
+
+// NewAnnotateConfig creates the config from a list of keywords. The presets
+// "minimal" (only type) and "rich" (every keyword but required) may be used
+// as well. Required is opt-in, it makes keys mandatory which have a default.
+func NewAnnotateConfig(keywords []string) (*AnnotateConfig, error) {
+	var config AnnotateConfig
+
+	var invalidKeywords []string
+
+	for _, keyword := range keywords {
+		switch keyword {
+		case AnnotatePresetMinimal:
+			config.Type = true
+		case AnnotatePresetRich:
+			config = AnnotateConfig{Type: true, Format: true, Description: true, AdditionalProperties: true, Items: true}
+		case "type":
+			config.Type = true
+		case "format":
+			config.Format = true
+		case "description":
+			config.Description = true
+		case "additionalProperties":
+			config.AdditionalProperties = true
+		case "required":
+			config.Required = true
+		case "items":
+			config.Items = true
+		default:
+			invalidKeywords = append(invalidKeywords, keyword)
+		}
+	}
+
+	if len(invalidKeywords) != 0 {
+		return nil, fmt.Errorf("unsupported annotate keywords '%s', use presets (%s, %s) or keywords (%s)",
+			strings.Join(invalidKeywords, "', '"), AnnotatePresetMinimal, AnnotatePresetRich, strings.Join(possibleAnnotateKeywords, ", "))
+	}
+
+	return &config, nil
+}
+
+// needsSchema reports if the keywords are taken from the generated schema
+func (c *AnnotateConfig) needsSchema() bool {
+	return c.Description || c.Items
+}
+
 // InsertionPoint represents where to insert an annotation block in the file.
 type InsertionPoint struct {
 	Line    int    // 1-based line number of the key node
 	Indent  string // indentation string (spaces) derived from keyNode.Column
 	TypeStr string // type annotation value
+	// Keywords are the lines of the annotation block without the comment prefix
+	Keywords []string
+	// Root inserts a @schema.root block instead of a @schema block
+	Root bool
 }
 
 // collectInsertionPoints walks the yaml.Node tree and collects InsertionPoints
-// for keys that don't already have @schema annotations.
-func collectInsertionPoints(node *yaml.Node) []InsertionPoint {
+// for keys that don't already have @schema annotations. The keywords besides
+// type and format are taken from generated, the schema YamlToSchema
+// generates for node, which may be nil for the minimal config.
+func collectInsertionPoints(node *yaml.Node, generated *Schema, config *AnnotateConfig) ([]InsertionPoint, error) {
 	var points []InsertionPoint
-	collectInsertionPointsRecursive(node, &points)
-	return points
+	if err := collectInsertionPointsRecursive(node, generated, config, &points); err != nil {
+		return nil, err
+	}
+	return points, nil
 }
 
-func collectInsertionPointsRecursive(node *yaml.Node, points *[]InsertionPoint) {
+func collectInsertionPointsRecursive(node *yaml.Node, generated *Schema, config *AnnotateConfig, points *[]InsertionPoint) error {
 	if node == nil {
-		return
+		return nil
 	}
 
 	switch node.Kind {
 	case yaml.DocumentNode:
 		for _, child := range node.Content {
-			collectInsertionPointsRecursive(child, points)
+			if err := collectInsertionPointsRecursive(child, generated, config, points); err != nil {
+				return err
+			}
 		}
 	case yaml.MappingNode:
 		for i := 0; i < len(node.Content)-1; i += 2 {
 			keyNode := node.Content[i]
 			valueNode := node.Content[i+1]
 
+			var keySchema *Schema
+			if generated != nil {
+				keySchema = generated.Properties[keyNode.Value]
+			}
+
 			if !HasSchemaAnnotation(keyNode.HeadComment) {
 				typeStr := typeAnnotationFromTag(valueNode.Tag)
 				if typeStr != "" {
-					indent := strings.Repeat(" ", keyNode.Column-1)
-					*points = append(*points, InsertionPoint{
-						Line:    keyNode.Line,
-						Indent:  indent,
-						TypeStr: typeStr,
-					})
+					keywords, err := annotationKeywords(valueNode, keySchema, config)
+					if err != nil {
+						return &LineError{Line: keyNode.Line, Err: fmt.Errorf("error annotating key %s: %w", keyNode.Value, err)}
+					}
+					if len(keywords) > 0 {
+						indent := strings.Repeat(" ", keyNode.Column-1)
+						*points = append(*points, InsertionPoint{
+							Line:     keyNode.Line,
+							Indent:   indent,
+							TypeStr:  typeStr,
+							Keywords: keywords,
+						})
+					}
 				}
 			}
 
 			// Recurse into mapping values for nested keys
 			if valueNode.Kind == yaml.MappingNode {
-				collectInsertionPointsRecursive(valueNode, points)
+				if err := collectInsertionPointsRecursive(valueNode, keySchema, config, points); err != nil {
+					return err
+				}
 			}
 
 			// Handle alias nodes that point to mappings
 			if valueNode.Kind == yaml.AliasNode && valueNode.Alias != nil && valueNode.Alias.Kind == yaml.MappingNode {
-				collectInsertionPointsRecursive(valueNode.Alias, points)
+				if err := collectInsertionPointsRecursive(valueNode.Alias, keySchema, config, points); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// annotationKeywords returns the lines of the annotation block of a key. The
+// keywords are what YamlToSchema infers for an unannotated key.
+func annotationKeywords(valueNode *yaml.Node, keySchema *Schema, config *AnnotateConfig) ([]string, error) {
+	if config == nil {
+		config = MinimalAnnotateConfig
+	}
+	if valueNode.Kind == yaml.AliasNode && valueNode.Alias != nil {
+		valueNode = valueNode.Alias
+	}
+
+	block := &yaml.Node{Kind: yaml.MappingNode}
+	if config.Type {
+		schemaType, err := typeFromTag(valueNode.Tag)
+		if err != nil {
+			return nil, err
+		}
+		addKeyword(block, "type", typeNode(schemaType))
+	}
+	if config.Format && valueNode.Kind == yaml.ScalarNode && (valueNode.Tag == strTag || valueNode.Tag == timestampTag) {
+		if format := detectFormat(valueNode.Value); format != "" {
+			addKeyword(block, "format", scalarNode(format))
+		}
+	}
+	if config.Description && keySchema != nil && keySchema.Description != "" {
+		addKeyword(block, "description", scalarNode(keySchema.Description))
+	}
+	if config.AdditionalProperties && valueNode.Kind == yaml.MappingNode {
+		addKeyword(block, "additionalProperties", scalarNode(false))
+	}
+	if config.Required {
+		// Keeps the key required like an unannotated one, annotated keys are optional otherwise
+		addKeyword(block, "required", scalarNode(true))
+	}
+	if config.Items && valueNode.Kind == yaml.SequenceNode && keySchema != nil && keySchema.Items != nil {
+		if items := itemsNode(keySchema.Items, config); items != nil {
+			addKeyword(block, "items", items)
+		}
+	}
+
+	if len(block.Content) == 0 {
+		return nil, nil
+	}
+	return encodeCommentBlock(block)
+}
+
+// itemsNode returns the items schema inferred for a sequence. YamlToSchema
+// adds a subschema per item to anyOf, equal subschemas are written once.
+func itemsNode(items *Schema, config *AnnotateConfig) *yaml.Node {
+	if len(items.AnyOf) == 0 {
+		return nil
+	}
+
+	var unique []*yaml.Node
+	var seen []string
+	for _, item := range items.AnyOf {
+		node := subschemaNode(item, config)
+		encoded, err := yaml.Marshal(node)
+		if err != nil || slices.Contains(seen, string(encoded)) {
+			continue
+		}
+		seen = append(seen, string(encoded))
+		unique = append(unique, node)
+	}
+
+	if len(unique) == 1 {
+		return unique[0]
+	}
+	anyOf := &yaml.Node{Kind: yaml.SequenceNode, Content: unique}
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	addKeyword(node, "anyOf", anyOf)
+	return node
+}
+
+// subschemaNode returns the keywords of an item schema selected by the config,
+// including the shape of objects.
+func subschemaNode(s *Schema, config *AnnotateConfig) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	if config.Type && len(s.Type) > 0 {
+		addKeyword(node, "type", typeNode(s.Type))
+	}
+	if config.Format && s.Type.Matches("string") {
+		if value, ok := s.Default.(string); ok {
+			if format := detectFormat(value); format != "" {
+				addKeyword(node, "format", scalarNode(format))
 			}
 		}
 	}
+	if config.Description && s.Description != "" {
+		addKeyword(node, "description", scalarNode(s.Description))
+	}
+	if config.AdditionalProperties {
+		// Inferred item objects forbid additional properties
+		if additional, ok := s.AdditionalProperties.(*bool); ok && additional != nil {
+			addKeyword(node, "additionalProperties", scalarNode(*additional))
+		}
+	}
+	if config.Required && len(s.Required.Strings) > 0 {
+		addKeyword(node, "required", scalarNode(s.Required.Strings))
+	}
+	if len(s.Properties) > 0 {
+		properties := &yaml.Node{Kind: yaml.MappingNode}
+		for _, name := range sortedKeys(s.Properties) {
+			addKeyword(properties, name, subschemaNode(s.Properties[name], config))
+		}
+		addKeyword(node, "properties", properties)
+	}
+	if config.Items && s.Items != nil {
+		if items := itemsNode(s.Items, config); items != nil {
+			addKeyword(node, "items", items)
+		}
+	}
+	return node
+}
+
+func addKeyword(mapping *yaml.Node, key string, value *yaml.Node) {
+	mapping.Content = append(mapping.Content, scalarNode(key), value)
+}
+
+func scalarNode(value any) *yaml.Node {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: nullTag, Value: "null"}
+	}
+	return &node
+}
+
+// typeNode writes single types as string and multiple types as list
+func typeNode(schemaType StringOrArrayOfString) *yaml.Node {
+	if len(schemaType) == 1 {
+		return scalarNode(schemaType[0])
+	}
+	return scalarNode([]string(schemaType))
+}
+
+// encodeCommentBlock encodes the mapping as YAML lines
+func encodeCommentBlock(mapping *yaml.Node) ([]string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(mapping); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"), nil
+}
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+var emailRegex = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// detectFormat returns the format of a string value, if it has an obvious one
+func detectFormat(value string) string {
+	if _, err := time.Parse(time.RFC3339, value); err == nil {
+		return FormatDateTime
+	}
+	if _, err := time.Parse(time.DateOnly, value); err == nil {
+		return FormatDate
+	}
+	if ip := net.ParseIP(value); ip != nil {
+		if strings.Contains(value, ":") {
+			return FormatIPv6
+		}
+		return FormatIPv4
+	}
+	if uuidRegex.MatchString(value) {
+		return FormatUUID
+	}
+	if emailRegex.MatchString(value) {
+		return FormatEmail
+	}
+	if parsed, err := url.Parse(value); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		return FormatURI
+	}
+	return ""
 }
 
 // AnnotateContent parses YAML content, collects insertion points for keys
 // that lack @schema annotations, and inserts type annotation blocks.
 // Returns the modified content.
 func AnnotateContent(content []byte) ([]byte, error) {
+	return AnnotateContentWithConfig(content, "", MinimalAnnotateConfig)
+}
+
+// AnnotateContentWithConfig works like AnnotateContent, but writes the
+// keywords selected by config. Keywords besides type and format are taken
+// from the schema YamlToSchema generates for the content, valuesPath is used
+// to resolve relative $refs of existing annotations.
+func AnnotateContentWithConfig(content []byte, valuesPath string, config *AnnotateConfig) ([]byte, error) {
 	var doc yaml.Node
 	if err := yaml.Unmarshal(content, &doc); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	points := collectInsertionPoints(&doc)
+	var generated *Schema
+	if config.needsSchema() && doc.Kind != 0 {
+		var err error
+		generated, err = YamlToSchema(valuesPath, &doc, false, false, false, true, &SkipAutoGenerationConfig{}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate schema: %w", err)
+		}
+	}
+
+	points, err := collectInsertionPoints(&doc, generated, config)
+	if err != nil {
+		return nil, err
+	}
 	if len(points) == 0 {
 		return content, nil
 	}
 
+	return insertAnnotations(content, points), nil
+}
+
+// insertAnnotations inserts the annotation blocks of the points above the
+// comments of their keys. Points of the same line are inserted in order, each
+// above the previous one.
+func insertAnnotations(content []byte, points []InsertionPoint) []byte {
 	lines := strings.Split(string(content), "\n")
 
 	// Sort by line number descending so insertions don't shift earlier line numbers
-	sort.Slice(points, func(i, j int) bool {
+	sort.SliceStable(points, func(i, j int) bool {
 		return points[i].Line > points[j].Line
 	})
 
 	for _, pt := range points {
+		marker := SchemaPrefix
+		if pt.Root {
+			marker = SchemaRootPrefix
+		}
+
 		// pt.Line is 1-based; convert to 0-based index
 		targetIdx := pt.Line - 1
 		if targetIdx < 0 || targetIdx >= len(lines) {
@@ -142,27 +446,42 @@ func AnnotateContent(content []byte) ([]byte, error) {
 			}
 		}
 
-		// Build the 3 annotation lines
-		annotationLines := []string{
-			pt.Indent + "# @schema",
-			pt.Indent + "# type: " + pt.TypeStr,
-			pt.Indent + "# @schema",
+		// Build the annotation lines
+		annotationLines := make([]string, 0, len(pt.Keywords)+2)
+		annotationLines = append(annotationLines, pt.Indent+marker)
+		for _, keyword := range pt.Keywords {
+			annotationLines = append(annotationLines, strings.TrimRight(pt.Indent+"# "+keyword, " "))
 		}
+		annotationLines = append(annotationLines, pt.Indent+marker)
 
 		// Insert at insertIdx
-		newLines := make([]string, 0, len(lines)+3)
+		newLines := make([]string, 0, len(lines)+len(annotationLines))
 		newLines = append(newLines, lines[:insertIdx]...)
 		newLines = append(newLines, annotationLines...)
 		newLines = append(newLines, lines[insertIdx:]...)
 		lines = newLines
 	}
 
-	return []byte(strings.Join(lines, "\n")), nil
+	return []byte(strings.Join(lines, "\n"))
 }
 
 // AnnotateValuesFile reads a values.yaml file, annotates unannotated keys
 // with @schema type blocks, and writes the result back (or prints to stdout if dryRun).
 func AnnotateValuesFile(valuesPath string, dryRun bool) error {
+	return RewriteValuesFile(valuesPath, dryRun, AnnotateContent)
+}
+
+// AnnotateRewrite returns a rewrite for RewriteValuesFile which annotates the
+// values file with the keywords selected by config.
+func AnnotateRewrite(valuesPath string, config *AnnotateConfig) func([]byte) ([]byte, error) {
+	return func(content []byte) ([]byte, error) {
+		return AnnotateContentWithConfig(content, valuesPath, config)
+	}
+}
+
+// RewriteValuesFile reads a values.yaml file, applies the rewrites in order,
+// and writes the result back (or prints to stdout if dryRun).
+func RewriteValuesFile(valuesPath string, dryRun bool, rewrites ...func([]byte) ([]byte, error)) error {
 	fileInfo, err := os.Stat(valuesPath)
 	if err != nil {
 		return fmt.Errorf("failed to stat %s: %w", valuesPath, err)
@@ -174,21 +493,23 @@ func AnnotateValuesFile(valuesPath string, dryRun bool) error {
 		return fmt.Errorf("failed to read %s: %w", valuesPath, err)
 	}
 
-	annotated, err := AnnotateContent(content)
-	if err != nil {
-		return fmt.Errorf("failed to annotate %s: %w", valuesPath, err)
+	for _, rewrite := range rewrites {
+		content, err = rewrite(content)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite %s: %w", valuesPath, err)
+		}
 	}
 
 	if dryRun {
-		log.Infof("Annotated values for %s", valuesPath)
-		fmt.Print(string(annotated))
+		log.Infof("Rewritten values for %s", valuesPath)
+		fmt.Print(string(content))
 		return nil
 	}
 
-	if err := os.WriteFile(valuesPath, annotated, perm); err != nil {
+	if err := os.WriteFile(valuesPath, content, perm); err != nil {
 		return fmt.Errorf("failed to write %s: %w", valuesPath, err)
 	}
 
-	log.Infof("Annotated %s", valuesPath)
+	log.Infof("Rewrote %s", valuesPath)
 	return nil
 }
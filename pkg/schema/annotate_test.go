@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"slices"
 	"strings"
 	"testing"
 
@@ -132,7 +133,10 @@ func TestCollectInsertionPoints(t *testing.T) {
 			if err := yaml.Unmarshal([]byte(tt.yaml), &doc); err != nil {
 				t.Fatalf("failed to parse YAML: %v", err)
 			}
-			points := collectInsertionPoints(&doc)
+			points, err := collectInsertionPoints(&doc, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to collect insertion points: %v", err)
+			}
 			if len(points) != tt.wantCount {
 				t.Errorf("got %d insertion points, want %d", len(points), tt.wantCount)
 				for _, p := range points {
@@ -246,3 +250,210 @@ func TestAnnotateContent(t *testing.T) {
 		})
 	}
 }
+
+func TestNewAnnotateConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		keywords []string
+		want     AnnotateConfig
+		wantErr  bool
+	}{
+		{
+			name:     "minimal preset",
+			keywords: []string{"minimal"},
+			want:     AnnotateConfig{Type: true},
+		},
+		{
+			name:     "rich preset",
+			keywords: []string{"rich"},
+			want:     AnnotateConfig{Type: true, Format: true, Description: true, AdditionalProperties: true, Items: true},
+		},
+		{
+			name:     "single keywords",
+			keywords: []string{"type", "items", "required"},
+			want:     AnnotateConfig{Type: true, Items: true, Required: true},
+		},
+		{
+			name:     "invalid keyword",
+			keywords: []string{"type", "pattern"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewAnnotateConfig(tt.keywords)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("NewAnnotateConfig() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnotateContentWithConfig(t *testing.T) {
+	rich := &AnnotateConfig{Type: true, Format: true, Description: true, AdditionalProperties: true, Items: true}
+
+	tests := []struct {
+		name   string
+		config *AnnotateConfig
+		input  string
+		want   string
+	}{
+		{
+			name:   "description from the comment",
+			config: &AnnotateConfig{Type: true, Description: true},
+			input:  "# Number of replicas\nreplicas: 1\n",
+			want:   "# @schema\n# type: integer\n# description: Number of replicas\n# @schema\n# Number of replicas\nreplicas: 1\n",
+		},
+		{
+			name:   "objects",
+			config: rich,
+			input:  "image:\n  repository: nginx\n",
+			want:   "# @schema\n# type: object\n# additionalProperties: false\n# @schema\nimage:\n  # @schema\n  # type: string\n  # @schema\n  repository: nginx\n",
+		},
+		{
+			name:   "required",
+			config: &AnnotateConfig{Type: true, Required: true},
+			input:  "image:\n  repository: nginx\n",
+			want:   "# @schema\n# type: object\n# required: true\n# @schema\nimage:\n  # @schema\n  # type: string\n  # required: true\n  # @schema\n  repository: nginx\n",
+		},
+		{
+			name:   "formats",
+			config: &AnnotateConfig{Format: true},
+			input:  "createdAt: 2024-01-02T10:00:00Z\nday: \"2024-01-02\"\nip: 10.0.0.1\nurl: https://example.com\nname: nginx\n",
+			want:   "# @schema\n# format: date-time\n# @schema\ncreatedAt: 2024-01-02T10:00:00Z\n# @schema\n# format: date\n# @schema\nday: \"2024-01-02\"\n# @schema\n# format: ipv4\n# @schema\nip: 10.0.0.1\n# @schema\n# format: uri\n# @schema\nurl: https://example.com\nname: nginx\n",
+		},
+		{
+			name:   "items of equal scalars",
+			config: &AnnotateConfig{Type: true, Items: true},
+			input:  "tags: [a, b]\n",
+			want:   "# @schema\n# type: array\n# items:\n#   type: string\n# @schema\ntags: [a, b]\n",
+		},
+		{
+			name:   "items of different scalars",
+			config: &AnnotateConfig{Type: true, Items: true},
+			input:  "mixed: [a, 1]\n",
+			want:   "# @schema\n# type: array\n# items:\n#   anyOf:\n#     - type: string\n#     - type: integer\n# @schema\nmixed: [a, 1]\n",
+		},
+		{
+			name:   "items with object shapes",
+			config: rich,
+			input:  "hosts:\n  - name: a\n    url: https://a.example.com\n  - name: b\n    url: https://b.example.com\n",
+			want: "# @schema\n# type: array\n# items:\n#   type: object\n#   additionalProperties: false\n" +
+				"#   properties:\n#     name:\n#       type: string\n#     url:\n#       type: string\n#       format: uri\n# @schema\n" +
+				"hosts:\n  - name: a\n    url: https://a.example.com\n  - name: b\n    url: https://b.example.com\n",
+		},
+		{
+			name:   "empty arrays have no items",
+			config: &AnnotateConfig{Type: true, Items: true},
+			input:  "empty: []\n",
+			want:   "# @schema\n# type: array\n# @schema\nempty: []\n",
+		},
+		{
+			name:   "annotated keys are skipped",
+			config: rich,
+			input:  "# @schema\n# type: string\n# @schema\nname: x\n",
+			want:   "# @schema\n# type: string\n# @schema\nname: x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AnnotateContentWithConfig([]byte(tt.input), "", tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("AnnotateContentWithConfig() mismatch\ngot:\n%s\nwant:\n%s", string(got), tt.want)
+			}
+		})
+	}
+}
+
+// Annotations with required must keep the keys required, which the others don't
+func TestAnnotateContentWithConfig_KeepsRequired(t *testing.T) {
+	config := &AnnotateConfig{Type: true, Description: true, Required: true}
+
+	annotated, err := AnnotateContentWithConfig([]byte("# The image\nimage:\n  repository: nginx\nreplicas: 1\n"), "", config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(annotated, &doc); err != nil {
+		t.Fatalf("failed to parse YAML: %v", err)
+	}
+	s, err := YamlToSchema("", &doc, false, false, false, true, &SkipAutoGenerationConfig{}, nil)
+	if err != nil {
+		t.Fatalf("failed to generate schema: %v", err)
+	}
+	if !slices.Equal(s.Required.Strings, []string{"image", "replicas"}) {
+		t.Errorf("required = %v, want [image replicas]", s.Required.Strings)
+	}
+	image := s.Properties["image"]
+	if !slices.Equal(image.Required.Strings, []string{"repository"}) {
+		t.Errorf("image required = %v, want [repository]", image.Required.Strings)
+	}
+	if image.Description != "The image" {
+		t.Errorf("image description = %q, want %q", image.Description, "The image")
+	}
+}
+
+// The rich preset doesn't make the keys of the values mandatory
+func TestAnnotateContentWithConfig_RichAcceptsMinimalValues(t *testing.T) {
+	rich, err := NewAnnotateConfig([]string{AnnotatePresetRich})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	annotated, err := AnnotateContentWithConfig([]byte("# The image\nimage:\n  repository: nginx\nreplicas: 1\nhosts:\n  - name: a\n"), "", rich)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(annotated, &doc); err != nil {
+		t.Fatalf("failed to parse YAML: %v", err)
+	}
+	s, err := YamlToSchema("", &doc, false, false, false, true, &SkipAutoGenerationConfig{}, nil)
+	if err != nil {
+		t.Fatalf("failed to generate schema: %v", err)
+	}
+	for _, values := range []string{`{}`, `{"image": {}}`, `{"hosts": [{}]}`} {
+		if err := validateWithSchema(t, s, values); err != nil {
+			t.Errorf("values %s are invalid: %v", values, err)
+		}
+	}
+	if err := validateWithSchema(t, s, `{"replicas": "1"}`); err == nil {
+		t.Error("expected the type to be validated")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"2024-01-02T10:00:00Z":                 FormatDateTime,
+		"2024-01-02":                           FormatDate,
+		"10.0.0.1":                             FormatIPv4,
+		"::1":                                  FormatIPv6,
+		"123e4567-e89b-12d3-a456-426614174000": FormatUUID,
+		"admin@example.com":                    FormatEmail,
+		"https://example.com/path":             FormatURI,
+		"nginx:1.25":                           "",
+		"ClusterIP":                            "",
+		"":                                     "",
+	}
+
+	for value, want := range tests {
+		if got := detectFormat(value); got != want {
+			t.Errorf("detectFormat(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
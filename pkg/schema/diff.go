@@ -0,0 +1,342 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ChangeKind describes how a part of a schema changed
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "changed"
+)
+
+// Change is a single structural difference between two schemas.
+// Pointer is the json pointer of the changed keyword or subschema within the
+// schema document. For added or removed subschemas, Old or New hold the schema.
+type Change struct {
+	Kind    ChangeKind
+	Pointer string
+	Old     interface{}
+	New     interface{}
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Pointer, changeValueString(c.New))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Pointer, changeValueString(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Pointer, changeValueString(c.Old), changeValueString(c.New))
+	}
+}
+
+func changeValueString(value interface{}) string {
+	if s, ok := value.(schemaDocument); ok {
+		var types []string
+		switch t := s["type"].(type) {
+		case string:
+			types = []string{t}
+		case []interface{}:
+			types = toStringSlice(t)
+		}
+		if len(types) > 0 {
+			return fmt.Sprintf("schema of type %s", strings.Join(types, ", "))
+		}
+		return "schema"
+	}
+	res, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(res)
+}
+
+// schemaDocument is a decoded json schema
+type schemaDocument map[string]interface{}
+
+// subschemaKeywords are the keywords which contain subschemas. They are
+// compared by walking into them instead of comparing their values.
+var subschemaKeywords = []string{
+	"properties", "patternProperties", "definitions", "$defs", "items", "additionalProperties",
+	"additionalItems", "anyOf", "allOf", "oneOf", "not", "if", "then", "else",
+	"contains", "propertyNames", "prefixItems", "dependentSchemas", "unevaluatedProperties",
+	"unevaluatedItems",
+}
+
+// Diff compares two schemas structurally and returns the changes needed to go
+// from the old to the new schema, ordered by json pointer.
+func Diff(old, new *Schema) ([]Change, error) {
+	oldJSON, err := json.Marshal(old)
+	if err != nil {
+		return nil, err
+	}
+	newJSON, err := json.Marshal(new)
+	if err != nil {
+		return nil, err
+	}
+	return DiffJSON(oldJSON, newJSON)
+}
+
+// DiffJSON compares two raw json schemas structurally, like Diff. The
+// schemas may use the keywords of any draft, e.g. the array form of items.
+func DiffJSON(old, new []byte) ([]Change, error) {
+	oldDocument, err := decodeSchemaDocument(old)
+	if err != nil {
+		return nil, err
+	}
+	newDocument, err := decodeSchemaDocument(new)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	diffDocuments("", oldDocument, newDocument, &changes)
+	return changes, nil
+}
+
+func decodeSchemaDocument(raw []byte) (schemaDocument, error) {
+	// Numbers are kept as they are, float64 would round large integers
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var document schemaDocument
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+func diffDocuments(pointer string, old, new schemaDocument, changes *[]Change) {
+	if old == nil && new == nil {
+		return
+	}
+	if old == nil {
+		*changes = append(*changes, Change{Kind: ChangeAdded, Pointer: pointerOrRoot(pointer), New: new})
+		return
+	}
+	if new == nil {
+		*changes = append(*changes, Change{Kind: ChangeRemoved, Pointer: pointerOrRoot(pointer), Old: old})
+		return
+	}
+
+	diffKeywords(pointer, keywordValuesOf(old), keywordValuesOf(new), changes)
+
+	for _, keyword := range []string{"properties", "patternProperties", "definitions", "$defs", "dependentSchemas"} {
+		oldMap, _ := old[keyword].(map[string]interface{})
+		newMap, _ := new[keyword].(map[string]interface{})
+		for _, name := range unionKeys(oldMap, newMap) {
+			diffSubschemas(pointer+"/"+escapePointerToken(keyword)+"/"+escapePointerToken(name), oldMap[name], newMap[name], changes)
+		}
+	}
+
+	for _, keyword := range []string{"items", "not", "if", "then", "else", "contains", "propertyNames"} {
+		if keyword == "items" && (isList(old[keyword]) || isList(new[keyword])) {
+			continue
+		}
+		diffSubschemas(pointer+"/"+keyword, old[keyword], new[keyword], changes)
+	}
+
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf", "prefixItems", "items"} {
+		oldList, _ := old[keyword].([]interface{})
+		newList, _ := new[keyword].([]interface{})
+		if keyword == "items" && !isList(old[keyword]) && !isList(new[keyword]) {
+			continue
+		}
+		if keyword == "items" && (!isList(old[keyword]) || !isList(new[keyword])) {
+			// The form of items changed
+			diffValues(pointer+"/items", old[keyword], new[keyword], changes)
+			continue
+		}
+		for i := 0; i < max(len(oldList), len(newList)); i++ {
+			var oldItem, newItem interface{}
+			if i < len(oldList) {
+				oldItem = oldList[i]
+			}
+			if i < len(newList) {
+				newItem = newList[i]
+			}
+			diffSubschemas(fmt.Sprintf("%s/%s/%d", pointer, keyword, i), oldItem, newItem, changes)
+		}
+	}
+
+	for _, keyword := range []string{"additionalProperties", "additionalItems", "unevaluatedProperties", "unevaluatedItems"} {
+		diffSubschemas(pointer+"/"+keyword, old[keyword], new[keyword], changes)
+	}
+}
+
+// diffSubschemas compares values which hold a schema or a boolean. Schemas
+// are compared structurally, everything else by value.
+func diffSubschemas(pointer string, old, new interface{}, changes *[]Change) {
+	oldSchema, oldIsSchema := old.(map[string]interface{})
+	newSchema, newIsSchema := new.(map[string]interface{})
+	if oldIsSchema && newIsSchema {
+		diffDocuments(pointer, oldSchema, newSchema, changes)
+		return
+	}
+	if oldIsSchema {
+		old = schemaDocument(oldSchema)
+	}
+	if newIsSchema {
+		new = schemaDocument(newSchema)
+	}
+	diffValues(pointer, old, new, changes)
+}
+
+func diffValues(pointer string, old, new interface{}, changes *[]Change) {
+	switch {
+	case old == nil && new == nil:
+	case old == nil:
+		*changes = append(*changes, Change{Kind: ChangeAdded, Pointer: pointer, New: new})
+	case new == nil:
+		*changes = append(*changes, Change{Kind: ChangeRemoved, Pointer: pointer, Old: old})
+	case !reflect.DeepEqual(old, new):
+		*changes = append(*changes, Change{Kind: ChangeModified, Pointer: pointer, Old: old, New: new})
+	}
+}
+
+func isList(value interface{}) bool {
+	_, ok := value.([]interface{})
+	return ok
+}
+
+// keywordValuesOf returns the keywords of the decoded schema which don't
+// contain subschemas.
+func keywordValuesOf(s schemaDocument) map[string]interface{} {
+	values := make(map[string]interface{}, len(s))
+	for keyword, value := range s {
+		if !slices.Contains(subschemaKeywords, keyword) {
+			values[keyword] = value
+		}
+	}
+	// An empty required list is the same as no required list at all
+	if required, ok := values["required"].([]interface{}); ok && len(required) == 0 {
+		delete(values, "required")
+	}
+	return values
+}
+
+// normalizeSchemaOrBool returns either the plain json value or the schema
+// stored in a SchemaOrBool field.
+func normalizeSchemaOrBool(value SchemaOrBool) (interface{}, *Schema, error) {
+	if value == nil {
+		return nil, nil, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(raw) > 0 && raw[0] == '{' {
+		var s Schema
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, nil, err
+		}
+		return nil, &s, nil
+	}
+	var plain interface{}
+	if err := json.Unmarshal(raw, &plain); err != nil {
+		return nil, nil, err
+	}
+	return plain, nil, nil
+}
+
+// keywordValues returns the serialized keywords of the schema which don't
+// contain subschemas.
+func keywordValues(s *Schema) (map[string]interface{}, error) {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	for _, keyword := range subschemaKeywords {
+		delete(values, keyword)
+	}
+	// An empty required list is the same as no required list at all
+	if required, ok := values["required"].([]interface{}); ok && len(required) == 0 {
+		delete(values, "required")
+	}
+	return values, nil
+}
+
+func diffKeywords(pointer string, old, new map[string]interface{}, changes *[]Change) {
+	for _, keyword := range unionKeys(old, new) {
+		oldValue, inOld := old[keyword]
+		newValue, inNew := new[keyword]
+		keywordPointer := pointer + "/" + escapePointerToken(keyword)
+
+		// Report changes of required entries one by one
+		if keyword == "required" {
+			oldRequired := toStringSlice(oldValue)
+			newRequired := toStringSlice(newValue)
+			for _, name := range newRequired {
+				if !slices.Contains(oldRequired, name) {
+					*changes = append(*changes, Change{Kind: ChangeAdded, Pointer: keywordPointer, New: name})
+				}
+			}
+			for _, name := range oldRequired {
+				if !slices.Contains(newRequired, name) {
+					*changes = append(*changes, Change{Kind: ChangeRemoved, Pointer: keywordPointer, Old: name})
+				}
+			}
+			continue
+		}
+
+		switch {
+		case !inOld:
+			*changes = append(*changes, Change{Kind: ChangeAdded, Pointer: keywordPointer, New: newValue})
+		case !inNew:
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Pointer: keywordPointer, Old: oldValue})
+		case !reflect.DeepEqual(oldValue, newValue):
+			*changes = append(*changes, Change{Kind: ChangeModified, Pointer: keywordPointer, Old: oldValue, New: newValue})
+		}
+	}
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func toStringSlice(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func escapePointerToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func pointerOrRoot(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+	return pointer
+}
@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	oldJSON := `{
+  "type": "object",
+  "required": ["replicas", "legacy"],
+  "additionalProperties": false,
+  "properties": {
+    "replicas": {"type": "integer", "default": 1},
+    "legacy": {"type": "string"},
+    "image": {
+      "type": "object",
+      "properties": {"tag": {"type": "string"}}
+    }
+  }
+}`
+	newJSON := `{
+  "type": "object",
+  "required": ["replicas", "name"],
+  "additionalProperties": true,
+  "properties": {
+    "replicas": {"type": ["integer", "null"], "default": 2},
+    "name": {"type": "string"},
+    "image": {
+      "type": "object",
+      "properties": {"tag": {"type": "string", "description": "The tag"}}
+    }
+  }
+}`
+
+	var oldSchema, newSchema Schema
+	assert.NoError(t, json.Unmarshal([]byte(oldJSON), &oldSchema))
+	assert.NoError(t, json.Unmarshal([]byte(newJSON), &newSchema))
+
+	changes, err := Diff(&oldSchema, &newSchema)
+	assert.NoError(t, err)
+
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	assert.Equal(t, []string{
+		`+ /required: "name"`,
+		`- /required: "legacy"`,
+		`+ /properties/image/properties/tag/description: "The tag"`,
+		`- /properties/legacy: schema of type string`,
+		`+ /properties/name: schema of type string`,
+		`~ /properties/replicas/default: 1 -> 2`,
+		`~ /properties/replicas/type: "integer" -> ["integer","null"]`,
+		`~ /additionalProperties: false -> true`,
+	}, lines)
+}
+
+func TestDiff_Equal(t *testing.T) {
+	generated := &Schema{
+		Type:                 []string{"object"},
+		AdditionalProperties: new(bool),
+		Properties: map[string]*Schema{
+			"count": {Type: []string{"integer"}, Default: 3},
+		},
+		Required: NewBoolOrArrayOfString([]string{}, false),
+	}
+
+	// A schema read back from disk must not differ from the generated one
+	raw, err := generated.ToJson()
+	assert.NoError(t, err)
+	var fromDisk Schema
+	assert.NoError(t, json.Unmarshal(raw, &fromDisk))
+
+	changes, err := Diff(&fromDisk, generated)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestDiffJSON_Drafts(t *testing.T) {
+	oldJSON := `{
+  "$schema": "https://json-schema.org/draft/2019-09/schema",
+  "$defs": {"port": {"type": "integer"}},
+  "properties": {
+    "pair": {"items": [{"type": "string"}, {"type": "integer"}], "additionalItems": false},
+    "list": {"items": {"type": "string"}}
+  }
+}`
+	newJSON := `{
+  "$schema": "https://json-schema.org/draft/2019-09/schema",
+  "$defs": {"port": {"type": "integer", "minimum": 1}},
+  "properties": {
+    "pair": {"items": [{"type": "string"}, {"type": "number"}], "additionalItems": false},
+    "list": {"items": [{"type": "string"}]}
+  }
+}`
+
+	changes, err := DiffJSON([]byte(oldJSON), []byte(newJSON))
+	assert.NoError(t, err)
+
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	assert.Equal(t, []string{
+		`~ /properties/list/items: {"type":"string"} -> [{"type":"string"}]`,
+		`~ /properties/pair/items/1/type: "integer" -> "number"`,
+		`+ /$defs/port/minimum: 1`,
+	}, lines)
+
+	changes, err = DiffJSON([]byte(newJSON), []byte(newJSON))
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
@@ -0,0 +1,245 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Supported JSON Schema drafts of the generated schemas
+const (
+	Draft7      = "draft-07"
+	Draft201909 = "2019-09"
+	Draft202012 = "2020-12"
+)
+
+// Drafts lists all supported drafts
+var Drafts = []string{Draft7, Draft201909, Draft202012}
+
+var draftURLs = map[string]string{
+	Draft7:      "http://json-schema.org/draft-07/schema#",
+	Draft201909: "https://json-schema.org/draft/2019-09/schema",
+	Draft202012: "https://json-schema.org/draft/2020-12/schema",
+}
+
+// ValidateDraft returns an error if the draft is not supported
+func ValidateDraft(draft string) error {
+	if !slices.Contains(Drafts, draft) {
+		return fmt.Errorf("unsupported draft %s, must be one of (%s)", draft, strings.Join(Drafts, ", "))
+	}
+	return nil
+}
+
+// Keywords whose values are schemas, lists of schemas or maps of schemas
+var (
+	draftSchemaKeywords = []string{
+		"additionalProperties", "additionalItems", "items", "contains", "propertyNames",
+		"if", "then", "else", "not", "unevaluatedProperties", "unevaluatedItems",
+	}
+	draftSchemaListKeywords = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+	draftSchemaMapKeywords  = []string{"properties", "patternProperties", "definitions", "$defs", "dependentSchemas"}
+)
+
+// ToJsonForDraft converts the schema to raw json of the given draft. The
+// schema itself uses draft-07 keywords plus prefixItems, dependentRequired,
+// dependentSchemas and the unevaluated keywords, which are translated:
+//
+//   - definitions become $defs and their references are rewritten (2019-09+)
+//   - dependencies are split into dependentRequired and dependentSchemas
+//     (2019-09+) or the other way around (draft-07)
+//   - prefixItems become an items array and items become additionalItems
+//     (draft-07, 2019-09), additionalItems become items (2020-12)
+//   - unevaluatedProperties and unevaluatedItems require 2019-09+
+func (s Schema) ToJsonForDraft(draft string) ([]byte, error) {
+	if err := ValidateDraft(draft); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(&s)
+	if err != nil {
+		return nil, err
+	}
+	// Numbers are kept as they are, float64 would round large integers
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var root map[string]any
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+
+	if err := convertDraft(root, draft); err != nil {
+		return nil, err
+	}
+	if _, ok := root["$schema"]; ok {
+		root["$schema"] = draftURLs[draft]
+	}
+
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// convertDraft translates the keywords of the decoded json schema and its
+// subschemas to the draft
+func convertDraft(s map[string]any, draft string) error {
+	for _, keyword := range draftSchemaKeywords {
+		if subSchema, ok := s[keyword].(map[string]any); ok {
+			if err := convertDraft(subSchema, draft); err != nil {
+				return fmt.Errorf("%s: %w", keyword, err)
+			}
+		}
+	}
+	for _, keyword := range draftSchemaListKeywords {
+		list, _ := s[keyword].([]any)
+		for i, value := range list {
+			if subSchema, ok := value.(map[string]any); ok {
+				if err := convertDraft(subSchema, draft); err != nil {
+					return fmt.Errorf("%s[%d]: %w", keyword, i, err)
+				}
+			}
+		}
+	}
+	for _, keyword := range draftSchemaMapKeywords {
+		schemas, _ := s[keyword].(map[string]any)
+		for name, value := range schemas {
+			if subSchema, ok := value.(map[string]any); ok {
+				if err := convertDraft(subSchema, draft); err != nil {
+					return fmt.Errorf("%s[%s]: %w", keyword, name, err)
+				}
+			}
+		}
+	}
+	if dependencies, ok := s["dependencies"].(map[string]any); ok {
+		for name, value := range dependencies {
+			if subSchema, ok := value.(map[string]any); ok {
+				if err := convertDraft(subSchema, draft); err != nil {
+					return fmt.Errorf("dependencies[%s]: %w", name, err)
+				}
+			}
+		}
+	}
+
+	if draft == Draft7 {
+		return convertToDraft7(s)
+	}
+	return convertToDraft2019(s, draft)
+}
+
+func convertToDraft7(s map[string]any) error {
+	for _, keyword := range []string{"unevaluatedProperties", "unevaluatedItems"} {
+		if _, ok := s[keyword]; ok {
+			return fmt.Errorf("%s requires --draft %s or %s", keyword, Draft201909, Draft202012)
+		}
+	}
+
+	if err := mergeDependencies(s); err != nil {
+		return err
+	}
+	return prefixItemsToItemsArray(s)
+}
+
+func convertToDraft2019(s map[string]any, draft string) error {
+	if definitions, ok := s["definitions"]; ok {
+		delete(s, "definitions")
+		s["$defs"] = definitions
+	}
+	if ref, ok := s["$ref"].(string); ok && strings.HasPrefix(ref, "#/definitions/") {
+		s["$ref"] = "#/$defs/" + strings.TrimPrefix(ref, "#/definitions/")
+	}
+
+	if err := splitDependencies(s); err != nil {
+		return err
+	}
+
+	if draft == Draft201909 {
+		return prefixItemsToItemsArray(s)
+	}
+
+	// 2020-12 replaced additionalItems by items, which apply to the items
+	// after prefixItems
+	additionalItems, ok := s["additionalItems"]
+	if !ok {
+		return nil
+	}
+	delete(s, "additionalItems")
+	if _, ok := s["prefixItems"]; !ok {
+		// additionalItems only apply to an items array, which is prefixItems now
+		return nil
+	}
+	if _, ok := s["items"]; ok {
+		return errors.New("items and additionalItems can't be combined with prefixItems")
+	}
+	s["items"] = additionalItems
+	return nil
+}
+
+// prefixItemsToItemsArray turns prefixItems into an items array, the items
+// after them are additionalItems
+func prefixItemsToItemsArray(s map[string]any) error {
+	prefixItems, ok := s["prefixItems"]
+	if !ok {
+		return nil
+	}
+	delete(s, "prefixItems")
+	if items, ok := s["items"]; ok {
+		if _, ok := s["additionalItems"]; ok {
+			return errors.New("items and additionalItems can't be combined with prefixItems")
+		}
+		s["additionalItems"] = items
+	}
+	s["items"] = prefixItems
+	return nil
+}
+
+// splitDependencies turns dependencies into dependentRequired (lists of
+// properties) and dependentSchemas
+func splitDependencies(s map[string]any) error {
+	dependencies, ok := s["dependencies"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	delete(s, "dependencies")
+
+	for name, value := range dependencies {
+		keyword := "dependentSchemas"
+		if _, isList := value.([]any); isList {
+			keyword = "dependentRequired"
+		}
+		target, _ := s[keyword].(map[string]any)
+		if target == nil {
+			target = make(map[string]any)
+			s[keyword] = target
+		}
+		if _, exists := target[name]; exists {
+			return fmt.Errorf("property %s is set in dependencies and %s", name, keyword)
+		}
+		target[name] = value
+	}
+	return nil
+}
+
+// mergeDependencies turns dependentRequired and dependentSchemas into
+// dependencies
+func mergeDependencies(s map[string]any) error {
+	for _, keyword := range []string{"dependentRequired", "dependentSchemas"} {
+		values, ok := s[keyword].(map[string]any)
+		if !ok {
+			continue
+		}
+		delete(s, keyword)
+
+		dependencies, _ := s["dependencies"].(map[string]any)
+		if dependencies == nil {
+			dependencies = make(map[string]any)
+			s["dependencies"] = dependencies
+		}
+		for name, value := range values {
+			if _, exists := dependencies[name]; exists {
+				return fmt.Errorf("property %s is set in dependencies and %s", name, keyword)
+			}
+			dependencies[name] = value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestToJsonForDraft(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		draft    string
+		expected string
+		err      string
+	}{
+		{
+			name:     "draft-07 keeps the schema",
+			schema:   `{"$schema": "http://json-schema.org/draft-07/schema#", "type": "object", "definitions": {"port": {"type": "integer"}}, "properties": {"port": {"$ref": "#/definitions/port"}}}`,
+			draft:    Draft7,
+			expected: `{"$schema": "http://json-schema.org/draft-07/schema#", "type": "object", "required": [], "definitions": {"port": {"type": "integer"}}, "properties": {"port": {"$ref": "#/definitions/port", "required": []}}}`,
+		},
+		{
+			name:     "definitions become $defs",
+			schema:   `{"$schema": "http://json-schema.org/draft-07/schema#", "type": "object", "definitions": {"port": {"type": "integer"}}, "properties": {"ports": {"type": "array", "items": {"$ref": "#/definitions/port"}}}}`,
+			draft:    Draft202012,
+			expected: `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "object", "required": [], "$defs": {"port": {"type": "integer"}}, "properties": {"ports": {"type": "array", "items": {"$ref": "#/$defs/port", "required": []}}}}`,
+		},
+		{
+			name:     "dependencies are split",
+			schema:   `{"type": "object", "dependencies": {"tls": ["cert"], "auth": {"required": ["user"]}}}`,
+			draft:    Draft201909,
+			expected: `{"type": "object", "required": [], "dependentRequired": {"tls": ["cert"]}, "dependentSchemas": {"auth": {"required": ["user"]}}}`,
+		},
+		{
+			name:     "dependent keywords are merged for draft-07",
+			schema:   `{"type": "object", "dependentRequired": {"tls": ["cert"]}, "dependentSchemas": {"auth": {"required": ["user"]}}}`,
+			draft:    Draft7,
+			expected: `{"type": "object", "required": [], "dependencies": {"tls": ["cert"], "auth": {"required": ["user"]}}}`,
+		},
+		{
+			name:     "prefixItems become an items array",
+			schema:   `{"type": "array", "prefixItems": [{"type": "string"}, {"type": "integer"}], "items": {"type": "boolean"}}`,
+			draft:    Draft7,
+			expected: `{"type": "array", "items": [{"type": "string"}, {"type": "integer"}], "additionalItems": {"type": "boolean"}}`,
+		},
+		{
+			name:     "prefixItems are kept for 2020-12",
+			schema:   `{"type": "array", "prefixItems": [{"type": "string"}], "additionalItems": {"type": "integer"}}`,
+			draft:    Draft202012,
+			expected: `{"type": "array", "prefixItems": [{"type": "string"}], "items": {"type": "integer"}}`,
+		},
+		{
+			name:     "nested subschemas are converted",
+			schema:   `{"type": "object", "properties": {"list": {"type": "array", "prefixItems": [{"type": "string"}]}}}`,
+			draft:    Draft201909,
+			expected: `{"type": "object", "required": [], "properties": {"list": {"type": "array", "items": [{"type": "string"}]}}}`,
+		},
+		{
+			name:     "unevaluatedProperties are kept for 2019-09",
+			schema:   `{"type": "object", "required": ["name"], "allOf": [{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}], "unevaluatedProperties": false}`,
+			draft:    Draft201909,
+			expected: `{"type": "object", "required": ["name"], "allOf": [{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}], "unevaluatedProperties": false}`,
+		},
+		{
+			name:   "unevaluatedProperties require 2019-09",
+			schema: `{"type": "object", "properties": {"sub": {"type": "object", "unevaluatedProperties": false}}}`,
+			draft:  Draft7,
+			err:    "properties[sub]: unevaluatedProperties requires --draft 2019-09 or 2020-12",
+		},
+		{
+			name:   "items can't be combined with prefixItems and additionalItems",
+			schema: `{"type": "array", "prefixItems": [{"type": "string"}], "items": {"type": "string"}, "additionalItems": false}`,
+			draft:  Draft201909,
+			err:    "items and additionalItems can't be combined with prefixItems",
+		},
+		{
+			name:   "unknown draft",
+			schema: `{"type": "object"}`,
+			draft:  "draft-04",
+			err:    "unsupported draft draft-04, must be one of (draft-07, 2019-09, 2020-12)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s Schema
+			assert.NoError(t, json.Unmarshal([]byte(tt.schema), &s))
+
+			result, err := s.ToJsonForDraft(tt.draft)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestToJsonForDraft_SameAsToJson(t *testing.T) {
+	var s Schema
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "object", "properties": {"big": {"type": "integer", "default": 9007199254740993}, "html": {"type": "string", "default": "<a>&</a>"}}}`), &s))
+
+	expected, err := s.ToJson()
+	assert.NoError(t, err)
+	result, err := s.ToJsonForDraft(Draft7)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(result))
+}
+
+func TestUnmarshalYAML_DraftKeywords(t *testing.T) {
+	var s Schema
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+type: array
+prefixItems:
+  - $ref: "#/$defs/name"
+unevaluatedItems: false
+$defs:
+  name:
+    type: string
+`), &s))
+
+	assert.Len(t, s.PrefixItems, 1)
+	assert.Equal(t, "#/definitions/name", s.PrefixItems[0].Ref)
+	assert.Equal(t, "string", s.Definitions["name"].Type[0])
+	assert.Equal(t, false, s.UnevaluatedItems)
+}
+
+func TestYamlToSchema_UnevaluatedPropertiesReplaceAdditionalProperties(t *testing.T) {
+	var doc yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(`# @schema
+# allOf:
+#   - properties:
+#       name:
+#         type: string
+# unevaluatedProperties: false
+# @schema
+service:
+  name: web
+other:
+  key: value
+`), &doc))
+
+	s, err := YamlToSchema("values.yaml", &doc, false, false, false, true, &SkipAutoGenerationConfig{}, nil)
+	assert.NoError(t, err)
+
+	service := s.Properties["service"]
+	assert.Nil(t, service.AdditionalProperties)
+	assert.Equal(t, false, service.UnevaluatedProperties)
+	assert.Equal(t, new(bool), s.Properties["other"].AdditionalProperties)
+}
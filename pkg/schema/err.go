@@ -1,7 +1,29 @@
 package schema
 
+import "errors"
+
 type CircularError struct {
 	msg string
 }
 
 func (e *CircularError) Error() string { return e.msg }
+
+// LineError annotates an error with the line of the values file it occurred at
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string { return e.Err.Error() }
+
+func (e *LineError) Unwrap() error { return e.Err }
+
+// ErrorLine returns the line of the values file an error occurred at,
+// or 0 if it is unknown.
+func ErrorLine(err error) int {
+	var lineErr *LineError
+	if errors.As(err, &lineErr) {
+		return lineErr.Line
+	}
+	return 0
+}
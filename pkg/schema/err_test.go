@@ -1,6 +1,10 @@
 package schema
 
-import "testing"
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
 
 func TestCircularError(t *testing.T) {
 	tests := []struct {
@@ -29,3 +33,22 @@ func TestCircularError(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorLine(t *testing.T) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte("foo: bar\n# @schema\n# type: doesnotexist\n# @schema\nbaz: 1\n"), &node); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := YamlToSchema("values.yaml", &node, false, false, false, false, nil, nil)
+	if err == nil {
+		t.Fatal("expected an error for an invalid type")
+	}
+	if got := ErrorLine(err); got != 5 {
+		t.Errorf("ErrorLine() = %v, want %v", got, 5)
+	}
+
+	if got := ErrorLine(&CircularError{msg: "no line"}); got != 0 {
+		t.Errorf("ErrorLine() = %v, want %v", got, 0)
+	}
+}
@@ -0,0 +1,174 @@
+package schema
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExampleValues returns a values file for the schema. Every key gets its
+// default, or its const, first enum value or first example, or else a
+// placeholder of its type. Descriptions are written as comments and the
+// keywords which can't be inferred from the values as @schema blocks, so
+// generating the schema of the values yields an equivalent schema. With
+// minimal, only the required keys are written.
+func ExampleValues(s *Schema, minimal bool) ([]byte, error) {
+	e := &exampleBuilder{root: s, minimal: minimal}
+	node, written := e.mapping(s, nil)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	// Only the written keys are annotated, the others would be reported as
+	// missing in the values
+	return AnnotateFromSchema(buf.Bytes(), "values.yaml", written)
+}
+
+type exampleBuilder struct {
+	root    *Schema
+	minimal bool
+}
+
+// definition returns the definition a local reference points to
+func (e *exampleBuilder) definition(ref string) *Schema {
+	for _, prefix := range []string{"#/definitions/", "#/$defs/"} {
+		if name, ok := strings.CutPrefix(ref, prefix); ok {
+			return e.root.Definitions[name]
+		}
+	}
+	return nil
+}
+
+// resolve follows local references and picks the first branch of an anyOf
+// or oneOf without type. The seen references are extended by the followed
+// ones, nil is returned for a recursive reference.
+func (e *exampleBuilder) resolve(s *Schema, seen []string) (*Schema, []string) {
+	for s.Ref != "" {
+		if slices.Contains(seen, s.Ref) {
+			return nil, seen
+		}
+		definition := e.definition(s.Ref)
+		if definition == nil {
+			return s, seen
+		}
+		seen = append(slices.Clone(seen), s.Ref)
+		s = definition
+	}
+	if len(s.Type) == 0 && len(s.Properties) == 0 {
+		for _, branches := range [][]*Schema{s.AnyOf, s.OneOf} {
+			if len(branches) > 0 && branches[0] != nil {
+				return e.resolve(branches[0], seen)
+			}
+		}
+	}
+	return s, seen
+}
+
+// mapping returns the mapping of the properties of the schema and the schema
+// with only the written properties
+func (e *exampleBuilder) mapping(s *Schema, seen []string) (*yaml.Node, *Schema) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	written := *s
+	written.Properties = map[string]*Schema{}
+
+	for _, key := range sortedKeys(s.Properties) {
+		property := s.Properties[key]
+		if property == nil {
+			continue
+		}
+		required := slices.Contains(s.Required.Strings, key)
+		if e.minimal && !required {
+			continue
+		}
+		resolved, propertySeen := e.resolve(property, seen)
+		if resolved == nil && !required {
+			// Optional recursive keys end the recursion
+			continue
+		}
+
+		valueNode, writtenProperty := e.value(property, resolved, propertySeen)
+		description := property.Description
+		if description == "" && resolved != nil {
+			description = resolved.Description
+		}
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+		if lines := strings.Split(strings.TrimSpace(description), "\n"); description != "" {
+			for i, line := range lines {
+				lines[i] = strings.TrimRight("# "+line, " ")
+			}
+			keyNode.HeadComment = strings.Join(lines, "\n")
+		}
+
+		node.Content = append(node.Content, keyNode, valueNode)
+		written.Properties[key] = writtenProperty
+	}
+	return node, &written
+}
+
+// value returns the node of the value of a property and the property with
+// only the written keys
+func (e *exampleBuilder) value(property, resolved *Schema, seen []string) (*yaml.Node, *Schema) {
+	if resolved == nil {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, property
+	}
+
+	isObject := len(resolved.Properties) > 0 && (len(resolved.Type) == 0 || resolved.Type.Matches("object"))
+	if isObject && property.Default == nil {
+		node, written := e.mapping(resolved, seen)
+		if resolved != property {
+			// References and branches are annotated as they are
+			return node, property
+		}
+		return node, written
+	}
+
+	var node yaml.Node
+	if err := node.Encode(e.exampleValue(property, resolved)); err != nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, property
+	}
+	return &node, property
+}
+
+// exampleValue returns the default, const, first enum value or first example
+// of the schema, or a placeholder of its type
+func (e *exampleBuilder) exampleValue(property, resolved *Schema) interface{} {
+	for _, s := range []*Schema{property, resolved} {
+		if s.Default != nil {
+			return s.Default
+		}
+		if s.Const != nil {
+			return s.Const
+		}
+		if len(s.Enum) > 0 {
+			return s.Enum[0]
+		}
+		if len(s.Examples) > 0 {
+			return s.Examples[0]
+		}
+	}
+
+	for _, t := range resolved.Type {
+		switch t {
+		case "string":
+			return ""
+		case "integer", "number":
+			return 0
+		case "boolean":
+			return false
+		case "array":
+			return []interface{}{}
+		case "object":
+			return map[string]interface{}{}
+		}
+	}
+	return nil
+}
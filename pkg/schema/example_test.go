@@ -0,0 +1,194 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const exampleSchema = `{
+  "type": "object",
+  "required": ["image", "replicas"],
+  "additionalProperties": false,
+  "definitions": {
+    "service": {"type": "object", "description": "The service", "additionalProperties": false, "properties": {"port": {"type": "integer", "default": 80}}},
+    "node": {"type": "object", "additionalProperties": false, "properties": {"child": {"$ref": "#/definitions/node"}}}
+  },
+  "properties": {
+    "replicas": {"type": "integer", "description": "Number of replicas", "default": 1, "minimum": 1},
+    "image": {"type": "object", "required": ["repository"], "additionalProperties": false, "properties": {
+      "repository": {"type": "string", "description": "Image repository\nwithout the tag"},
+      "pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent"]}
+    }},
+    "service": {"$ref": "#/definitions/service"},
+    "tree": {"$ref": "#/definitions/node"},
+    "mode": {"oneOf": [{"const": "fast"}, {"const": "slow"}]}
+  }
+}`
+
+func TestExampleValues(t *testing.T) {
+	var s Schema
+	assert.NoError(t, json.Unmarshal([]byte(exampleSchema), &s))
+
+	values, err := ExampleValues(&s, false)
+	assert.NoError(t, err)
+	assert.Equal(t, `# @schema.root
+# definitions:
+#   node:
+#     type: object
+#     additionalProperties: false
+#     properties:
+#       child:
+#         $ref: '#/definitions/node'
+#   service:
+#     type: object
+#     description: The service
+#     additionalProperties: false
+#     properties:
+#       port:
+#         type: integer
+#         default: 80
+# required:
+#   - image
+#   - replicas
+# @schema.root
+# @schema
+# type: object
+# additionalProperties: false
+# required:
+#   - repository
+# @schema
+image:
+  # @schema
+  # type: string
+  # enum:
+  #   - Always
+  #   - IfNotPresent
+  # @schema
+  pullPolicy: Always
+  # @schema
+  # type: string
+  # @schema
+  # Image repository
+  # without the tag
+  repository: ""
+# @schema
+# oneOf:
+#   - const: fast
+#   - const: slow
+# @schema
+mode: fast
+# @schema
+# type: integer
+# minimum: 1
+# @schema
+# Number of replicas
+replicas: 1
+# @schema
+# $ref: '#/definitions/service'
+# @schema
+# The service
+service:
+  port: 80
+# @schema
+# $ref: '#/definitions/node'
+# @schema
+tree: {}
+`, string(values))
+
+	values, err = ExampleValues(&s, true)
+	assert.NoError(t, err)
+	assert.Equal(t, `# @schema.root
+# definitions:
+#   node:
+#     type: object
+#     additionalProperties: false
+#     properties:
+#       child:
+#         $ref: '#/definitions/node'
+#   service:
+#     type: object
+#     description: The service
+#     additionalProperties: false
+#     properties:
+#       port:
+#         type: integer
+#         default: 80
+# required:
+#   - image
+#   - replicas
+# @schema.root
+# @schema
+# type: object
+# additionalProperties: false
+# required:
+#   - repository
+# @schema
+image:
+  # @schema
+  # type: string
+  # @schema
+  # Image repository
+  # without the tag
+  repository: ""
+# @schema
+# type: integer
+# minimum: 1
+# @schema
+# Number of replicas
+replicas: 1
+`, string(values))
+}
+
+// The example values must be valid and generate an equivalent schema
+func TestExampleValues_Equivalent(t *testing.T) {
+	var s Schema
+	assert.NoError(t, json.Unmarshal([]byte(exampleSchema), &s))
+	values, err := ExampleValues(&s, false)
+	assert.NoError(t, err)
+
+	var doc yaml.Node
+	assert.NoError(t, yaml.Unmarshal(values, &doc))
+	generated, err := YamlToSchema("values.yaml", &doc, false, false, false, true, nil, nil)
+	assert.NoError(t, err)
+	generatedJSON, err := generated.ToJson()
+	assert.NoError(t, err)
+
+	compile := func(content []byte) *jsonschema.Schema {
+		parsed, err := jsonschema.UnmarshalJSON(bytes.NewReader(content))
+		assert.NoError(t, err)
+		c := jsonschema.NewCompiler()
+		assert.NoError(t, c.AddResource("schema.json", parsed))
+		compiled, err := c.Compile("schema.json")
+		assert.NoError(t, err)
+		return compiled
+	}
+	existingCompiled := compile([]byte(exampleSchema))
+	generatedCompiled := compile(generatedJSON)
+
+	var decoded interface{}
+	assert.NoError(t, yaml.Unmarshal(values, &decoded))
+	assert.NoError(t, existingCompiled.Validate(decoded))
+
+	instances := []string{
+		`{"replicas": 1, "image": {"repository": "nginx"}}`,
+		`{"replicas": 0, "image": {"repository": "nginx"}}`,
+		`{"replicas": 1, "image": {"repository": "nginx", "pullPolicy": "Never"}}`,
+		`{"replicas": 1, "image": {}}`,
+		`{"replicas": 1, "image": {"repository": "nginx"}, "service": {"port": "80"}}`,
+		`{"replicas": 1, "image": {"repository": "nginx"}, "tree": {"child": {"child": {}}}}`,
+		`{"replicas": 1, "image": {"repository": "nginx"}, "tree": {"child": {"other": {}}}}`,
+		`{"replicas": 1, "image": {"repository": "nginx"}, "mode": "medium"}`,
+	}
+	for _, instance := range instances {
+		value, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(instance)))
+		assert.NoError(t, err)
+		existingErr := existingCompiled.Validate(value)
+		generatedErr := generatedCompiled.Validate(value)
+		assert.Equal(t, existingErr == nil, generatedErr == nil, "validation of %s differs: existing %v, generated %v", instance, existingErr, generatedErr)
+	}
+}
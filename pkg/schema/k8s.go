@@ -0,0 +1,228 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// K8sRefScheme is the scheme of $refs to definitions of the kubernetes
+// catalog, e.g. k8s://io.k8s.api.core.v1.ResourceRequirements
+const K8sRefScheme = "k8s://"
+
+// k8sDefinitionFiles are the files of a catalog version which contain the
+// definitions, the swagger.json of kubernetes or the _definitions.json of
+// kubernetes-json-schema
+var k8sDefinitionFiles = []string{"swagger.json", "_definitions.json"}
+
+// K8sCatalog is a local directory of kubernetes OpenAPI definitions with a
+// directory per kubernetes version, e.g. v1.29.0/swagger.json
+type K8sCatalog struct {
+	dir string
+	// version is the kubernetes version set by the user, if empty the version
+	// is chosen by the kubeVersion of the chart
+	version     string
+	mu          sync.Mutex
+	definitions map[string]map[string]json.RawMessage
+}
+
+// NewK8sCatalog returns the catalog of the directory. If version is empty,
+// the latest version of the catalog which satisfies the kubeVersion of a
+// chart is used.
+func NewK8sCatalog(dir, version string) (*K8sCatalog, error) {
+	if version != "" {
+		if _, err := semver.NewVersion(version); err != nil {
+			return nil, fmt.Errorf("invalid kubernetes version %s: %w", version, err)
+		}
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open kubernetes catalog: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("kubernetes catalog %s is not a directory", dir)
+	}
+	return &K8sCatalog{dir: dir, version: version, definitions: map[string]map[string]json.RawMessage{}}, nil
+}
+
+// ResolveRefs replaces the k8s:// refs of the schema by refs to its
+// definitions and adds the referenced definitions of the catalog, including
+// those they reference. The catalog may be nil if the schema has no k8s://
+// refs.
+func (c *K8sCatalog) ResolveRefs(s *Schema, kubeVersion string) error {
+	var refs []*Schema
+	var collect func(s *Schema)
+	collect = func(s *Schema) {
+		if strings.HasPrefix(s.Ref, K8sRefScheme) {
+			refs = append(refs, s)
+		}
+		for _, subSchema := range s.Subschemas() {
+			collect(subSchema)
+		}
+	}
+	collect(s)
+	if len(refs) == 0 {
+		return nil
+	}
+	if c == nil {
+		return fmt.Errorf("failed to resolve $ref %s: no kubernetes catalog configured", refs[0].Ref)
+	}
+
+	versionDir, err := c.versionDir(kubeVersion)
+	if err != nil {
+		return err
+	}
+	definitions, err := c.load(versionDir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, ref := range refs {
+		name := strings.TrimPrefix(ref.Ref, K8sRefScheme)
+		if _, ok := definitions[name]; !ok {
+			return fmt.Errorf("failed to resolve $ref %s: definition %s not found in %s", ref.Ref, name, versionDir)
+		}
+		ref.Ref = "#/definitions/" + name
+		names = append(names, name)
+	}
+
+	if s.Definitions == nil {
+		s.Definitions = map[string]*Schema{}
+	}
+	for len(names) > 0 {
+		name := names[0]
+		names = names[1:]
+		if _, ok := s.Definitions[name]; ok {
+			continue
+		}
+		raw, ok := definitions[name]
+		if !ok {
+			return fmt.Errorf("definition %s referenced in %s not found", name, versionDir)
+		}
+		var definition Schema
+		if err := json.Unmarshal(raw, &definition); err != nil {
+			return fmt.Errorf("failed to unmarshal definition %s of %s: %w", name, versionDir, err)
+		}
+		err := normalizeK8sDefinition(&definition, func(ref string) {
+			names = append(names, strings.TrimPrefix(ref, "#/definitions/"))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to normalize definition %s of %s: %w", name, versionDir, err)
+		}
+		s.Definitions[name] = &definition
+	}
+	return nil
+}
+
+// normalizeK8sDefinition replaces the OpenAPI formats without JSON schema
+// equivalent and reports the refs to other definitions
+func normalizeK8sDefinition(s *Schema, ref func(string)) error {
+	if strings.HasPrefix(s.Ref, "#/definitions/") {
+		ref(s.Ref)
+	}
+	if s.Format == "int-or-string" {
+		s.Format = ""
+		s.Type = StringOrArrayOfString{"integer", "string"}
+	}
+	// Unmarshaled schemas of additionalProperties are maps
+	if s.AdditionalProperties != nil {
+		if _, additional, err := normalizeSchemaOrBool(s.AdditionalProperties); err != nil {
+			return err
+		} else if additional != nil {
+			s.AdditionalProperties = additional
+		}
+	}
+	for _, subSchema := range s.Subschemas() {
+		if err := normalizeK8sDefinition(subSchema, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionDir returns the directory of the kubernetes version set by the
+// user, or else of the latest version which satisfies the kubeVersion
+// constraint of the chart
+func (c *K8sCatalog) versionDir(kubeVersion string) (string, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read kubernetes catalog: %w", err)
+	}
+	versions := map[*semver.Version]string{}
+	var available []*semver.Version
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		version, err := semver.NewVersion(entry.Name())
+		if err != nil {
+			continue
+		}
+		versions[version] = entry.Name()
+		available = append(available, version)
+	}
+	if len(available) == 0 {
+		return "", fmt.Errorf("kubernetes catalog %s contains no versions", c.dir)
+	}
+	slices.SortFunc(available, func(a, b *semver.Version) int { return b.Compare(a) })
+
+	if c.version != "" {
+		wanted := semver.MustParse(c.version)
+		for _, version := range available {
+			if version.Equal(wanted) {
+				return filepath.Join(c.dir, versions[version]), nil
+			}
+		}
+		return "", fmt.Errorf("kubernetes version %s not found in catalog %s", c.version, c.dir)
+	}
+	if kubeVersion == "" {
+		return filepath.Join(c.dir, versions[available[0]]), nil
+	}
+
+	constraint, err := semver.NewConstraint(kubeVersion)
+	if err != nil {
+		return "", fmt.Errorf("invalid kubeVersion %s: %w", kubeVersion, err)
+	}
+	for _, version := range available {
+		if constraint.Check(version) {
+			return filepath.Join(c.dir, versions[version]), nil
+		}
+	}
+	return "", fmt.Errorf("no kubernetes version of catalog %s satisfies kubeVersion %s", c.dir, kubeVersion)
+}
+
+// load returns the definitions of a version directory, they are read once
+func (c *K8sCatalog) load(versionDir string) (map[string]json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if definitions, ok := c.definitions[versionDir]; ok {
+		return definitions, nil
+	}
+
+	for _, name := range k8sDefinitionFiles {
+		content, err := os.ReadFile(filepath.Join(versionDir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubernetes definitions: %w", err)
+		}
+		var document struct {
+			Definitions map[string]json.RawMessage `json:"definitions"`
+		}
+		if err := json.Unmarshal(content, &document); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal kubernetes definitions of %s: %w", versionDir, err)
+		}
+		c.definitions[versionDir] = document.Definitions
+		return document.Definitions, nil
+	}
+	return nil, fmt.Errorf("no kubernetes definitions (%s) found in %s", strings.Join(k8sDefinitionFiles, ", "), versionDir)
+}
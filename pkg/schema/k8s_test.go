@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeK8sCatalog(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"v1.29.0/swagger.json": `{"swagger": "2.0", "definitions": {
+			"io.k8s.api.core.v1.ResourceRequirements": {"description": "Compute resources", "type": "object", "properties": {
+				"limits": {"type": "object", "additionalProperties": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.api.resource.Quantity"}}
+			}},
+			"io.k8s.apimachinery.pkg.api.resource.Quantity": {"type": "string", "format": "int-or-string"},
+			"io.k8s.api.core.v1.Toleration": {"type": "object", "properties": {"key": {"type": "string"}}}
+		}}`,
+		"v1.28.0/_definitions.json": `{"definitions": {
+			"io.k8s.api.core.v1.Toleration": {"type": "object", "description": "1.28"}
+		}}`,
+		"README.md": "not a version",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestK8sCatalog_ResolveRefs(t *testing.T) {
+	catalog, err := NewK8sCatalog(writeK8sCatalog(t), "")
+	assert.NoError(t, err)
+
+	s := &Schema{Properties: map[string]*Schema{
+		"resources":   {Ref: "k8s://io.k8s.api.core.v1.ResourceRequirements"},
+		"tolerations": {Type: StringOrArrayOfString{"array"}, Items: &Schema{Ref: "k8s://io.k8s.api.core.v1.Toleration"}},
+	}}
+	assert.NoError(t, catalog.ResolveRefs(s, ">=1.29.0-0"))
+
+	assert.Equal(t, "#/definitions/io.k8s.api.core.v1.ResourceRequirements", s.Properties["resources"].Ref)
+	assert.Equal(t, "#/definitions/io.k8s.api.core.v1.Toleration", s.Properties["tolerations"].Items.Ref)
+	assert.Equal(t, []string{
+		"io.k8s.api.core.v1.ResourceRequirements",
+		"io.k8s.api.core.v1.Toleration",
+		"io.k8s.apimachinery.pkg.api.resource.Quantity",
+	}, sortedKeys(s.Definitions))
+	assert.Equal(t, "Compute resources", s.Definitions["io.k8s.api.core.v1.ResourceRequirements"].Description)
+
+	// Schemas of additionalProperties are followed and int-or-string becomes a type
+	limits := s.Definitions["io.k8s.api.core.v1.ResourceRequirements"].Properties["limits"]
+	if additional, ok := limits.AdditionalProperties.(*Schema); assert.True(t, ok) {
+		assert.Equal(t, "#/definitions/io.k8s.apimachinery.pkg.api.resource.Quantity", additional.Ref)
+	}
+	quantity := s.Definitions["io.k8s.apimachinery.pkg.api.resource.Quantity"]
+	assert.Equal(t, StringOrArrayOfString{"integer", "string"}, quantity.Type)
+	assert.Empty(t, quantity.Format)
+}
+
+func TestK8sCatalog_Version(t *testing.T) {
+	dir := writeK8sCatalog(t)
+	toleration := func(catalog *K8sCatalog, kubeVersion string) (*Schema, error) {
+		s := &Schema{Properties: map[string]*Schema{"toleration": {Ref: "k8s://io.k8s.api.core.v1.Toleration"}}}
+		if err := catalog.ResolveRefs(s, kubeVersion); err != nil {
+			return nil, err
+		}
+		return s.Definitions["io.k8s.api.core.v1.Toleration"], nil
+	}
+
+	catalog, err := NewK8sCatalog(dir, "")
+	assert.NoError(t, err)
+
+	// The latest version without kubeVersion
+	definition, err := toleration(catalog, "")
+	assert.NoError(t, err)
+	assert.Empty(t, definition.Description)
+
+	// The latest version which satisfies kubeVersion
+	definition, err = toleration(catalog, "<1.29.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.28", definition.Description)
+
+	_, err = toleration(catalog, ">=1.30.0")
+	assert.EqualError(t, err, "no kubernetes version of catalog "+dir+" satisfies kubeVersion >=1.30.0")
+
+	// The version set by the user wins
+	catalog, err = NewK8sCatalog(dir, "1.28")
+	assert.NoError(t, err)
+	definition, err = toleration(catalog, ">=1.29.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.28", definition.Description)
+
+	catalog, err = NewK8sCatalog(dir, "1.27")
+	assert.NoError(t, err)
+	_, err = toleration(catalog, "")
+	assert.EqualError(t, err, "kubernetes version 1.27 not found in catalog "+dir)
+}
+
+func TestK8sCatalog_Errors(t *testing.T) {
+	dir := writeK8sCatalog(t)
+
+	_, err := NewK8sCatalog(dir, "latest")
+	assert.ErrorContains(t, err, "invalid kubernetes version latest")
+	_, err = NewK8sCatalog(filepath.Join(dir, "README.md"), "")
+	assert.EqualError(t, err, "kubernetes catalog "+filepath.Join(dir, "README.md")+" is not a directory")
+
+	// Schemas without k8s:// refs don't need a catalog
+	var catalog *K8sCatalog
+	assert.NoError(t, catalog.ResolveRefs(&Schema{Ref: "#/definitions/foo"}, ""))
+	assert.EqualError(t, catalog.ResolveRefs(&Schema{Ref: "k8s://io.k8s.api.core.v1.Pod"}, ""),
+		"failed to resolve $ref k8s://io.k8s.api.core.v1.Pod: no kubernetes catalog configured")
+
+	catalog, err = NewK8sCatalog(dir, "")
+	assert.NoError(t, err)
+	assert.EqualError(t, catalog.ResolveRefs(&Schema{Ref: "k8s://io.k8s.api.core.v1.Pod"}, ""),
+		"failed to resolve $ref k8s://io.k8s.api.core.v1.Pod: definition io.k8s.api.core.v1.Pod not found in "+filepath.Join(dir, "v1.29.0"))
+}
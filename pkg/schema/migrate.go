@@ -0,0 +1,245 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/norwoodj/helm-docs/pkg/helm"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const helmDocsPrefix = "# --"
+
+// helmDocsKeyDescriptionRegex matches the old helm-docs comments which name the
+// documented key, like "# image.tag -- the image tag". It is the same
+// expression helm-docs uses.
+var helmDocsKeyDescriptionRegex = regexp.MustCompile(`^\s*#\s*(.*)\s+--\s*(.*)$`)
+
+// migrationKeyword is a keyword of the @schema block created from a helm-docs comment
+type migrationKeyword struct {
+	name  string
+	value any
+	// set reports if the keyword is already set in an existing @schema block
+	set func(s *Schema) bool
+}
+
+// collectKeyNodes returns the key nodes of all mappings of the document,
+// including mappings nested in sequences.
+func collectKeyNodes(node *yaml.Node, keys *[]*yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			collectKeyNodes(child, keys)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			*keys = append(*keys, node.Content[i])
+			collectKeyNodes(node.Content[i+1], keys)
+		}
+	}
+}
+
+// MigrateHelmDocsContent rewrites the helm-docs comments of YAML content into
+// @schema blocks. The (type), @default and description of a "# --" comment
+// become the type, default and description keywords, the helm-docs comment is
+// removed. Keywords already set by an existing @schema block of the key are
+// kept. The generated schema is the same as in helm-docs compatibility mode.
+func MigrateHelmDocsContent(content []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	var keys []*yaml.Node
+	collectKeyNodes(&doc, &keys)
+	if len(keys) == 0 {
+		return content, nil
+	}
+
+	// Sort by line number descending so changes don't shift earlier line numbers
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Line > keys[j].Line
+	})
+
+	lines := strings.Split(string(content), "\n")
+	lastLine := 0
+	for _, keyNode := range keys {
+		// Keys of flow mappings may share a line
+		if keyNode.Line == lastLine {
+			continue
+		}
+		lastLine = keyNode.Line
+
+		migrated, err := migrateKeyComment(lines, keyNode)
+		if err != nil {
+			return nil, &LineError{Line: keyNode.Line, Err: fmt.Errorf("error migrating comment of key %s: %w", keyNode.Value, err)}
+		}
+		lines = migrated
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// migrateKeyComment replaces the helm-docs comment above the given key with
+// @schema keywords and returns the changed lines.
+func migrateKeyComment(lines []string, keyNode *yaml.Node) ([]string, error) {
+	keyIdx := keyNode.Line - 1
+	if keyIdx < 0 || keyIdx >= len(lines) {
+		return lines, nil
+	}
+
+	commentStart := keyIdx
+	for commentStart > 0 && strings.HasPrefix(strings.TrimSpace(lines[commentStart-1]), CommentPrefix) {
+		commentStart--
+	}
+	if commentStart == keyIdx {
+		return lines, nil
+	}
+
+	// Lines of @schema and @schema.root blocks are kept, the others may
+	// belong to the helm-docs comment
+	var candidates []int
+	var blockStart, blockEnd int
+	hasBlock := false
+	inBlock, inRootBlock := false, false
+	for i := commentStart; i < keyIdx; i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case !inBlock && strings.HasPrefix(trimmed, SchemaRootPrefix):
+			inRootBlock = !inRootBlock
+		case !inRootBlock && strings.HasPrefix(trimmed, SchemaPrefix):
+			if !inBlock {
+				blockStart = i
+			} else {
+				blockEnd = i
+				hasBlock = true
+			}
+			inBlock = !inBlock
+		case !inBlock && !inRootBlock:
+			candidates = append(candidates, i)
+		}
+	}
+	if inBlock || inRootBlock {
+		return nil, fmt.Errorf("unclosed @schema block")
+	}
+
+	// Like helm-docs, only the last comment starting with "# --" is used.
+	// Without one, the first comment naming a key is used.
+	docStart := -1
+	for i, idx := range candidates {
+		trimmed := strings.TrimSpace(lines[idx])
+		if strings.HasPrefix(trimmed, helmDocsPrefix) {
+			docStart = i
+		} else if docStart == -1 && helmDocsKeyDescriptionRegex.MatchString(trimmed) {
+			docStart = i
+		}
+	}
+	if docStart == -1 {
+		return lines, nil
+	}
+
+	docLines := make([]string, 0, len(candidates)-docStart)
+	removed := make(map[int]bool)
+	for _, idx := range candidates[docStart:] {
+		docLines = append(docLines, strings.TrimSpace(lines[idx]))
+		removed[idx] = true
+	}
+	_, helmDocsValue := helm.ParseComment(docLines)
+
+	var keywords []migrationKeyword
+	if helmDocsValue.ValueType != "" {
+		helmDocsType, err := helmDocsTypeToSchemaType(helmDocsValue.ValueType)
+		if err != nil {
+			log.Warnf("Not migrating type of key %s: %s", keyNode.Value, err)
+		} else {
+			var value any = []string(helmDocsType)
+			if len(helmDocsType) == 1 {
+				value = helmDocsType[0]
+			}
+			keywords = append(keywords, migrationKeyword{"type", value, func(s *Schema) bool { return len(s.Type) > 0 }})
+		}
+	}
+	if helmDocsValue.Default != "" {
+		keywords = append(keywords, migrationKeyword{"default", helmDocsValue.Default, func(s *Schema) bool { return s.Default != nil }})
+	}
+	if helmDocsValue.Description != "" {
+		keywords = append(keywords, migrationKeyword{"description", helmDocsValue.Description, func(s *Schema) bool { return s.Description != "" }})
+	}
+
+	if hasBlock {
+		// Explicit annotations take precedence over the helm-docs comment
+		blockLines := make([]string, 0, blockEnd-blockStart+1)
+		for _, line := range lines[blockStart : blockEnd+1] {
+			blockLines = append(blockLines, strings.TrimSpace(line))
+		}
+		existing, _, err := GetSchemaFromComment(strings.Join(blockLines, "\n"))
+		if err != nil {
+			return nil, err
+		}
+		keywords = withoutSetKeywords(keywords, &existing, keyNode.Value)
+	}
+
+	indent := strings.Repeat(" ", keyNode.Column-1)
+	keywordLines, err := keywordCommentLines(keywords, indent)
+	if err != nil {
+		return nil, err
+	}
+
+	region := make([]string, 0, keyIdx-commentStart+len(keywordLines)+2)
+	for i := commentStart; i < keyIdx; i++ {
+		if !hasBlock && i == candidates[docStart] && len(keywordLines) > 0 {
+			region = append(region, indent+SchemaPrefix)
+			region = append(region, keywordLines...)
+			region = append(region, indent+SchemaPrefix)
+		}
+		if hasBlock && i == blockEnd {
+			region = append(region, keywordLines...)
+		}
+		if !removed[i] {
+			region = append(region, lines[i])
+		}
+	}
+
+	result := make([]string, 0, len(lines)-(keyIdx-commentStart)+len(region))
+	result = append(result, lines[:commentStart]...)
+	result = append(result, region...)
+	result = append(result, lines[keyIdx:]...)
+	return result, nil
+}
+
+// withoutSetKeywords removes the keywords already set in the existing schema
+func withoutSetKeywords(keywords []migrationKeyword, existing *Schema, key string) []migrationKeyword {
+	result := keywords[:0]
+	for _, keyword := range keywords {
+		if keyword.set(existing) {
+			log.Warnf("Not migrating helm-docs %s of key %s, it is already set by the @schema block", keyword.name, key)
+			continue
+		}
+		result = append(result, keyword)
+	}
+	return result
+}
+
+// keywordCommentLines encodes the keywords as commented YAML lines
+func keywordCommentLines(keywords []migrationKeyword, indent string) ([]string, error) {
+	var lines []string
+	for _, keyword := range keywords {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(map[string]any{keyword.name: keyword.value}); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			lines = append(lines, strings.TrimRight(indent+CommentPrefix+" "+line, " "))
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,134 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrateHelmDocsContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "description only",
+			input: "# -- Number of replicas\nreplicas: 1\n",
+			want:  "# @schema\n# description: Number of replicas\n# @schema\nreplicas: 1\n",
+		},
+		{
+			name:  "type, default and multi-line description",
+			input: "# -- (int) Number of replicas,\n# scaled by the HPA\n# @default -- 3 in production\nreplicas: 1\n",
+			want:  "# @schema\n# type: integer\n# default: 3 in production\n# description: Number of replicas, scaled by the HPA\n# @schema\nreplicas: 1\n",
+		},
+		{
+			name:  "multiple types",
+			input: "# -- (string,int) The port\nport: 80\n",
+			want:  "# @schema\n# type:\n#   - string\n#   - integer\n# description: The port\n# @schema\nport: 80\n",
+		},
+		{
+			name:  "raw description",
+			input: "# -- Arguments\n# @raw\n# - first\n# - second\nargs: []\n",
+			want:  "# @schema\n# description: |-\n#   Arguments\n#   - first\n#   - second\n# @schema\nargs: []\n",
+		},
+		{
+			name:  "nested keys keep their indentation",
+			input: "image:\n  # -- (tpl) The image tag\n  tag: \"\"\n",
+			want:  "image:\n  # @schema\n  # type: string\n  # description: The image tag\n  # @schema\n  tag: \"\"\n",
+		},
+		{
+			name:  "only the last helm-docs comment is used",
+			input: "# A note for maintainers\n# -- ignored\n# -- The name\nname: x\n",
+			want:  "# A note for maintainers\n# -- ignored\n# @schema\n# description: The name\n# @schema\nname: x\n",
+		},
+		{
+			name:  "old style comment naming the key",
+			input: "# pullPolicy -- The pull policy\npullPolicy: Always\n",
+			want:  "# @schema\n# description: The pull policy\n# @schema\npullPolicy: Always\n",
+		},
+		{
+			name:  "keys in lists",
+			input: "hosts:\n  - # the host\n    # -- The host name\n    name: a\n",
+			want:  "hosts:\n  - # the host\n    # @schema\n    # description: The host name\n    # @schema\n    name: a\n",
+		},
+		{
+			name:  "existing block is extended",
+			input: "# @schema\n# minLength: 1\n# @schema\n# -- (string) The name\nname: x\n",
+			want:  "# @schema\n# minLength: 1\n# type: string\n# description: The name\n# @schema\nname: x\n",
+		},
+		{
+			name:  "existing block takes precedence",
+			input: "# -- (string) The name\n# @schema\n# type: [string, \"null\"]\n# @schema\nname: x\n",
+			want:  "# @schema\n# type: [string, \"null\"]\n# description: The name\n# @schema\nname: x\n",
+		},
+		{
+			name:  "untranslatable type is dropped",
+			input: "# -- (quantity) The memory limit\nmemory: 1Gi\n",
+			want:  "# @schema\n# description: The memory limit\n# @schema\nmemory: 1Gi\n",
+		},
+		{
+			name:  "plain comments are kept",
+			input: "# The name\nname: x\n",
+			want:  "# The name\nname: x\n",
+		},
+		{
+			name:  "empty file",
+			input: "",
+			want:  "",
+		},
+		{
+			name:    "unclosed block",
+			input:   "# @schema\n# type: string\n# -- The name\nname: x\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MigrateHelmDocsContent([]byte(tt.input))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+// The migrated file without helm-docs compatibility mode must generate the
+// same schema as the original file with it.
+func TestMigrateHelmDocsContent_SameSchema(t *testing.T) {
+	input := `# -- (int) Number of replicas
+# @default -- 3 in production
+replicas: 1
+image:
+  # -- The image repository
+  repository: nginx
+  # @schema
+  # minLength: 1
+  # @schema
+  # -- (string) The image tag, defaults
+  # to the appVersion
+  tag: ""
+# Not documented
+enabled: true
+`
+	toSchema := func(content string, helmDocsCompatibilityMode bool) string {
+		var node yaml.Node
+		assert.NoError(t, yaml.Unmarshal([]byte(content), &node))
+		s, err := YamlToSchema("values.yaml", &node, false, helmDocsCompatibilityMode, false, false, &SkipAutoGenerationConfig{}, nil)
+		assert.NoError(t, err)
+		jsonStr, err := s.ToJson()
+		assert.NoError(t, err)
+		return string(jsonStr)
+	}
+
+	migrated, err := MigrateHelmDocsContent([]byte(input))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(migrated), "# --")
+	assert.Equal(t, toSchema(input, true), toSchema(string(migrated), false))
+}
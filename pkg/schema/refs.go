@@ -0,0 +1,635 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dadav/go-jsonpointer"
+	"github.com/dadav/helm-schema/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Subschemas returns the direct subschemas of the schema, properties and
+// definitions sorted by name
+func (s *Schema) Subschemas() []*Schema {
+	var result []*Schema
+	for _, name := range sortedKeys(s.Properties) {
+		result = append(result, s.Properties[name])
+	}
+	for _, pattern := range sortedKeys(s.PatternProperties) {
+		result = append(result, s.PatternProperties[pattern])
+	}
+	for _, name := range sortedKeys(s.Definitions) {
+		result = append(result, s.Definitions[name])
+	}
+	for _, name := range sortedKeys(s.DependentSchemas) {
+		result = append(result, s.DependentSchemas[name])
+	}
+	result = append(result, s.PrefixItems...)
+	result = append(result, s.Items, s.Contains, s.PropertyNames, s.If, s.Then, s.Else, s.Not)
+	result = append(result, s.AllOf...)
+	result = append(result, s.AnyOf...)
+	result = append(result, s.OneOf...)
+	for _, value := range []SchemaOrBool{s.AdditionalProperties, s.AdditionalItems, s.UnevaluatedProperties, s.UnevaluatedItems} {
+		if subSchema, ok := value.(*Schema); ok {
+			result = append(result, subSchema)
+		}
+	}
+
+	return slices.DeleteFunc(result, func(subSchema *Schema) bool { return subSchema == nil })
+}
+
+func sortedKeys(m map[string]*Schema) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// ReferencedFiles returns the files referenced via relative $ref in the
+// @schema annotations of the given values file, including the files which
+// are referenced by those files in turn. Referenced files don't have to
+// exist. Invalid annotations are ignored, they are reported when the schema
+// is generated.
+func ReferencedFiles(valuesPath string) ([]string, error) {
+	content, err := os.ReadFile(valuesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+
+	refs := newFileRefResolver(valuesPath)
+	refs.countAnnotations(&root, true)
+	return refs.files, nil
+}
+
+// handleSchemaRefs processes and resolves JSON Schema references ($ref) within a schema.
+// It walks every subschema position (properties, items, combinators, definitions, ...).
+// For each reference:
+//   - If it's a relative file path, it attempts to load and parse the referenced schema
+//   - If it includes a JSON pointer (#/path/to/schema), it extracts the specific schema section
+//   - The resolved schema replaces the original reference
+//   - Refs within the resolved schema are resolved relative to the file containing them,
+//     local refs (#/path/to/schema) point into that file
+//   - Targets which are referenced more than once in the values file, or recursively,
+//     are added to the definitions instead, the refs point to them
+//   - Refs which only point back to a schema that is being resolved are reported as circular
+//
+// Parameters:
+//   - schema: Pointer to the Schema object containing the references to resolve
+//   - refs: The resolver of the values file, whose annotations were counted before
+//
+// Returns:
+//   - Whether the schema still refers to another schema instead of being replaced by it
+//   - An error if the reference cannot be resolved, or nil on success
+func handleSchemaRefs(schema *Schema, refs *fileRefResolver) (bool, error) {
+	// The refs of this annotation are checked on their own, so errors are
+	// reported for the key they belong to
+	check := newFileRefResolver(refs.valuesPath)
+	check.documents = refs.documents
+	if err := check.count(schema, refs.valuesPath, false); err != nil {
+		return false, err
+	}
+	return refs.resolve(schema, refs.valuesPath)
+}
+
+// fileRefResolver resolves the relative file refs of the annotations of a
+// values file
+type fileRefResolver struct {
+	valuesPath string
+	// stack contains the targets which are being counted, to detect cycles
+	stack []refFrame
+	// inlining contains the targets which are being inlined
+	inlining []string
+	// resolved counts the resolved refs
+	resolved int
+	// documents are the parsed referenced files by path
+	documents map[string]interface{}
+	// files are the referenced files in the order they were found
+	files []string
+	// counts are the number of refs to each target
+	counts map[string]int
+	// names are the definition names of the shared targets
+	names map[string]string
+	// definitions are the shared targets by definition name
+	definitions map[string]*Schema
+	// declared are the names of the definitions declared in the annotations,
+	// the shared targets don't take them
+	declared map[string]bool
+}
+
+// refFrame is a target which is being counted
+type refFrame struct {
+	key string
+	// root is set if the ref to the target is the root of the schema
+	// containing it
+	root bool
+}
+
+func newFileRefResolver(valuesPath string) *fileRefResolver {
+	return &fileRefResolver{
+		valuesPath:  valuesPath,
+		documents:   map[string]interface{}{},
+		counts:      map[string]int{},
+		names:       map[string]string{},
+		definitions: map[string]*Schema{},
+		declared:    map[string]bool{},
+	}
+}
+
+// countAnnotations counts the refs of all annotations below the node, so
+// targets which are referenced by several keys are shared, and collects the
+// names of the definitions they declare. Errors are ignored, they are
+// reported when the annotation is resolved.
+func (r *fileRefResolver) countAnnotations(node *yaml.Node, keepFullComment bool) {
+	if node.HeadComment != "" {
+		comment := node.HeadComment
+		if rootSchema, remainingComment, err := GetRootSchemaFromComment(comment); err == nil {
+			if rootSchema.HasData {
+				r.declare(&rootSchema)
+				_ = r.count(&rootSchema, r.valuesPath, false)
+			}
+			comment = remainingComment
+		}
+		if !keepFullComment {
+			comment = leadingCommentsRemover.ReplaceAllString(comment, "")
+		}
+		if keySchema, _, err := GetSchemaFromComment(comment); err == nil && keySchema.HasData {
+			r.declare(&keySchema)
+			_ = r.count(&keySchema, r.valuesPath, false)
+		}
+	}
+	for _, child := range node.Content {
+		r.countAnnotations(child, keepFullComment)
+	}
+}
+
+// declare collects the names of the definitions of the schema and its
+// subschemas, they are all hoisted to the root of the values schema
+func (r *fileRefResolver) declare(schema *Schema) {
+	for name := range schema.Definitions {
+		r.declared[name] = true
+	}
+	for _, subSchema := range schema.Subschemas() {
+		r.declare(subSchema)
+	}
+}
+
+// addDefinitions adds the shared targets to the definitions of the schema,
+// their names differ from those of the declared definitions
+func (r *fileRefResolver) addDefinitions(schema *Schema) {
+	if len(r.definitions) == 0 {
+		return
+	}
+	if schema.Definitions == nil {
+		schema.Definitions = map[string]*Schema{}
+	}
+	maps.Copy(schema.Definitions, r.definitions)
+}
+
+// fileRefTarget is the location a file ref points to
+type fileRefTarget struct {
+	path    string
+	pointer string
+}
+
+func (t fileRefTarget) key() string {
+	return t.path + "#" + t.pointer
+}
+
+// target returns the target of the ref of a schema in the base file. Refs
+// which aren't relative file paths, or local refs of the values file, have no
+// target.
+func (r *fileRefResolver) target(ref, base string) (fileRefTarget, bool, error) {
+	fileRef, pointer, _ := strings.Cut(ref, "#")
+	if fileRef == "" {
+		if base == r.valuesPath {
+			return fileRefTarget{}, false, nil
+		}
+		return fileRefTarget{path: base, pointer: pointer}, true, nil
+	}
+	if strings.Contains(fileRef, "://") {
+		return fileRefTarget{}, false, nil
+	}
+
+	relFilePath, err := util.IsRelativeFile(base, fileRef)
+	if err != nil {
+		if relFilePath != "" && !slices.Contains(r.files, relFilePath) {
+			// A missing file is kept as well, so it can be waited for
+			r.files = append(r.files, relFilePath)
+		}
+		if base != r.valuesPath && relFilePath != "" {
+			return fileRefTarget{}, false, fmt.Errorf("failed to resolve $ref %s in %s: %w", ref, base, err)
+		}
+		// Not a relative file path, may be handled elsewhere
+		log.Debug(err)
+		return fileRefTarget{}, false, nil
+	}
+	if !slices.Contains(r.files, relFilePath) {
+		r.files = append(r.files, relFilePath)
+	}
+	return fileRefTarget{path: relFilePath, pointer: pointer}, true, nil
+}
+
+// count counts the refs to each target of the schema and the targets it
+// references transitively. root is set if the schema is the root of a target.
+func (r *fileRefResolver) count(schema *Schema, base string, root bool) error {
+	if schema.Ref != "" {
+		target, ok, err := r.target(schema.Ref, base)
+		if err != nil {
+			return err
+		}
+		if ok {
+			key := target.key()
+			if i := slices.IndexFunc(r.stack, func(frame refFrame) bool { return frame.key == key }); i >= 0 {
+				// A target which is reached again from one of its subschemas is
+				// shared, so the refs can point to its definition. If it is only
+				// reached through refs at the root of the targets, nothing
+				// remains to be defined.
+				cycle := append(slices.Clone(r.stack[i+1:]), refFrame{key: key, root: root})
+				if !slices.ContainsFunc(cycle, func(frame refFrame) bool { return !frame.root }) {
+					keys := []string{}
+					for _, frame := range r.stack {
+						keys = append(keys, frame.key)
+					}
+					return fmt.Errorf("circular $ref %s: %s", schema.Ref, strings.Join(append(keys, key), " -> "))
+				}
+				r.counts[key]++
+			} else {
+				r.counts[key]++
+				if r.counts[key] == 1 {
+					targetSchema, err := r.load(target)
+					if err != nil {
+						return err
+					}
+					r.stack = append(r.stack, refFrame{key: key, root: root})
+					err = r.count(targetSchema, target.path, true)
+					r.stack = r.stack[:len(r.stack)-1]
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	for _, subSchema := range schema.Subschemas() {
+		if err := r.count(subSchema, base, false); err != nil {
+			return err
+		}
+	}
+	for _, value := range []SchemaOrBool{schema.AdditionalProperties, schema.AdditionalItems, schema.UnevaluatedProperties, schema.UnevaluatedItems} {
+		if _, ok := value.(*Schema); ok {
+			continue
+		}
+		if _, subSchema, err := normalizeSchemaOrBool(value); err == nil && subSchema != nil {
+			if err := r.count(subSchema, base, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve resolves the file refs of the schema and its subschemas, the
+// schema belongs to the base file. It returns whether the schema still refers
+// to another schema.
+func (r *fileRefResolver) resolve(schema *Schema, base string) (bool, error) {
+	// The subschemas next to a $ref are resolved first, they are kept when the
+	// ref is inlined
+	for _, subSchema := range schema.Subschemas() {
+		if _, err := r.resolve(subSchema, base); err != nil {
+			return false, err
+		}
+	}
+
+	// Schemas of these keywords are plain maps when they come from annotations
+	for _, value := range []*SchemaOrBool{&schema.AdditionalProperties, &schema.AdditionalItems, &schema.UnevaluatedProperties, &schema.UnevaluatedItems} {
+		if _, ok := (*value).(*Schema); ok || *value == nil {
+			continue
+		}
+		_, subSchema, err := normalizeSchemaOrBool(*value)
+		if err != nil || subSchema == nil {
+			continue
+		}
+		resolved := r.resolved
+		if _, err := r.resolve(subSchema, base); err != nil {
+			return false, err
+		}
+		if r.resolved > resolved {
+			*value = subSchema
+		}
+	}
+
+	if schema.Ref == "" {
+		return false, nil
+	}
+	return r.resolveRef(schema, base)
+}
+
+// resolveRef replaces the schema by the schema its ref points to, if the
+// ref is a relative file path or a local ref of a referenced file. The
+// keywords next to the ref are merged into it. Shared targets are added to
+// the definitions and the ref points to them instead. It returns whether
+// the schema still refers to another schema.
+func (r *fileRefResolver) resolveRef(schema *Schema, base string) (bool, error) {
+	target, ok, err := r.target(schema.Ref, base)
+	if err != nil || !ok {
+		return err == nil, err
+	}
+	r.resolved++
+
+	key := target.key()
+	var relSchema *Schema
+	referenced := true
+	if r.counts[key] > 1 || slices.Contains(r.inlining, key) {
+		name, err := r.define(target)
+		if err != nil {
+			return false, err
+		}
+		relSchema = &Schema{Ref: "#/definitions/" + pointerTokenEscaper.Replace(name), HasData: true}
+	} else {
+		relSchema, err = r.load(target)
+		if err != nil {
+			return false, err
+		}
+		r.inlining = append(r.inlining, key)
+		referenced, err = r.resolve(relSchema, target.path)
+		r.inlining = r.inlining[:len(r.inlining)-1]
+		if err != nil {
+			return false, err
+		}
+	}
+
+	merged, err := mergeRefSiblings(schema, relSchema)
+	if err != nil {
+		return false, fmt.Errorf("failed to merge the keywords next to $ref %s: %w", schema.Ref, err)
+	}
+	*schema = *merged
+	schema.HasData = true
+	return referenced, nil
+}
+
+// mergeRefSiblings returns the referenced schema with the keywords of the
+// schema containing the ref. Annotations (title, description, default, ...)
+// override those of the referenced schema, a required list is joined and the
+// other keywords are added as allOf, so both schemas apply. If the
+// referenced schema is a $ref itself, it is moved to the allOf as well,
+// because keywords next to a $ref are ignored before draft 2019-09.
+func mergeRefSiblings(local, referenced *Schema) (*Schema, error) {
+	merged := *referenced
+
+	if local.Title != "" {
+		merged.Title = local.Title
+	}
+	if local.Description != "" {
+		merged.Description = local.Description
+	}
+	if local.Comment != "" {
+		merged.Comment = local.Comment
+	}
+	if local.Default != nil {
+		merged.Default = local.Default
+	}
+	if len(local.Examples) > 0 {
+		merged.Examples = local.Examples
+	}
+	merged.Deprecated = merged.Deprecated || local.Deprecated
+	merged.ReadOnly = merged.ReadOnly || local.ReadOnly
+	merged.WriteOnly = merged.WriteOnly || local.WriteOnly
+	merged.ConstFromValue = local.ConstFromValue
+	if len(local.CustomAnnotations) > 0 {
+		annotations := make(map[string]interface{}, len(merged.CustomAnnotations)+len(local.CustomAnnotations))
+		maps.Copy(annotations, merged.CustomAnnotations)
+		maps.Copy(annotations, local.CustomAnnotations)
+		merged.CustomAnnotations = annotations
+	}
+
+	// required: true marks the key as required in its parent
+	merged.Required = BoolOrArrayOfString{Strings: slices.Clone(referenced.Required.Strings), Bool: local.Required.Bool}
+	for _, name := range local.Required.Strings {
+		if !slices.Contains(merged.Required.Strings, name) {
+			merged.Required.Strings = append(merged.Required.Strings, name)
+		}
+	}
+
+	if len(local.Definitions) > 0 {
+		definitions := make(map[string]*Schema, len(merged.Definitions)+len(local.Definitions))
+		maps.Copy(definitions, merged.Definitions)
+		maps.Copy(definitions, local.Definitions)
+		merged.Definitions = definitions
+	}
+
+	// The remaining keywords constrain the value in addition to the ref
+	constraints := *local
+	constraints.Ref = ""
+	constraints.Title = ""
+	constraints.Description = ""
+	constraints.Comment = ""
+	constraints.Default = nil
+	constraints.Examples = nil
+	constraints.Deprecated = false
+	constraints.ReadOnly = false
+	constraints.WriteOnly = false
+	constraints.ConstFromValue = false
+	constraints.CustomAnnotations = nil
+	constraints.Required = BoolOrArrayOfString{}
+	constraints.Definitions = nil
+	values, err := keywordValues(&constraints)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) > 0 || len(constraints.Subschemas()) > 0 || constraints.AdditionalProperties != nil ||
+		constraints.AdditionalItems != nil || constraints.UnevaluatedProperties != nil || constraints.UnevaluatedItems != nil {
+		constraints.HasData = true
+		merged.AllOf = slices.Clone(merged.AllOf)
+		if merged.Ref != "" {
+			merged.AllOf = append([]*Schema{{Ref: merged.Ref, HasData: true}}, merged.AllOf...)
+			merged.Ref = ""
+		}
+		merged.AllOf = append(merged.AllOf, &constraints)
+	}
+
+	return &merged, nil
+}
+
+// define adds the target to the definitions once and returns its name
+func (r *fileRefResolver) define(target fileRefTarget) (string, error) {
+	if name, ok := r.names[target.key()]; ok {
+		return name, nil
+	}
+
+	relPath, err := filepath.Rel(filepath.Dir(r.valuesPath), target.path)
+	if err != nil {
+		relPath = filepath.Base(target.path)
+	}
+	base := remoteNameRemover.ReplaceAllString(filepath.ToSlash(relPath), "_")
+	tokens := strings.Split(strings.TrimPrefix(target.pointer, "/"), "/")
+	if len(tokens) > 1 && (tokens[0] == "definitions" || tokens[0] == "$defs") {
+		tokens = tokens[1:]
+	}
+	if target.pointer != "" {
+		for _, token := range tokens {
+			base += "." + pointerTokenUnescaper.Replace(token)
+		}
+	}
+	name := base
+	for i := 2; r.definitions[name] != nil || r.declared[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	r.names[target.key()] = name
+
+	definition, err := r.load(target)
+	if err != nil {
+		return "", err
+	}
+	r.definitions[name] = definition
+	if _, err := r.resolve(definition, target.path); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// load returns a copy of the schema the target points to. The definitions
+// of a whole file are dropped, the local refs pointing to them are resolved.
+func (r *fileRefResolver) load(target fileRefTarget) (*Schema, error) {
+	document, ok := r.documents[target.path]
+	if !ok {
+		var err error
+		document, err = readRefDocument(target.path)
+		if err != nil {
+			return nil, err
+		}
+		r.documents[target.path] = document
+	}
+
+	var relSchema Schema
+	if node, ok := document.(*yaml.Node); ok {
+		// YAML files are decoded like annotations
+		value, err := getRefPointer(target.pointer, func(pointer string) (interface{}, error) {
+			return yamlPointerGet(node, pointer)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve JSON pointer %s in %s: %w", target.pointer, target.path, err)
+		}
+		if err := value.(*yaml.Node).Decode(&relSchema); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal schema from %s: %w", target.path, err)
+		}
+	} else {
+		value, err := getRefPointer(target.pointer, func(pointer string) (interface{}, error) {
+			if pointer == "" {
+				return document, nil
+			}
+			return jsonpointer.Get(document, pointer)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve JSON pointer %s in %s: %w", target.pointer, target.path, err)
+		}
+		marshaled, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal JSON pointer result from %s: %w", target.path, err)
+		}
+		if err := json.Unmarshal(marshaled, &relSchema); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal schema from %s: %w", target.path, err)
+		}
+	}
+	if target.pointer == "" {
+		relSchema.Definitions = nil
+	}
+	return &relSchema, nil
+}
+
+// readRefDocument parses a referenced file. YAML files (.yaml, .yml) are
+// returned as *yaml.Node, JSON files as plain values.
+func readRefDocument(path string) (interface{}, error) {
+	byteValue, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read referenced schema file %s: %w", path, err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var node yaml.Node
+		if err := yaml.Unmarshal(byteValue, &node); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal YAML from %s: %w", path, err)
+		}
+		return &node, nil
+	default:
+		var document interface{}
+		if err := json.Unmarshal(byteValue, &document); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON from %s: %w", path, err)
+		}
+		return document, nil
+	}
+}
+
+// getRefPointer resolves the JSON pointer with get. Pointers to definitions
+// fall back to $defs, because local refs to $defs were rewritten to
+// definitions on unmarshal.
+func getRefPointer(pointer string, get func(string) (interface{}, error)) (interface{}, error) {
+	value, err := get(pointer)
+	if err != nil && strings.HasPrefix(pointer, "/definitions/") {
+		if defsValue, defsErr := get("/$defs/" + strings.TrimPrefix(pointer, "/definitions/")); defsErr == nil {
+			return defsValue, nil
+		}
+	}
+	return value, err
+}
+
+// yamlPointerGet returns the node the JSON pointer points to
+func yamlPointerGet(node *yaml.Node, pointer string) (*yaml.Node, error) {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if pointer == "" {
+		return node, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %s", pointer)
+	}
+
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = pointerTokenUnescaper.Replace(token)
+		for node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+		switch node.Kind {
+		case yaml.MappingNode:
+			var value *yaml.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == token {
+					value = node.Content[i+1]
+					break
+				}
+			}
+			if value == nil {
+				return nil, fmt.Errorf("key %s not found", token)
+			}
+			node = value
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node.Content) {
+				return nil, fmt.Errorf("invalid index %s", token)
+			}
+			node = node.Content[index]
+		default:
+			return nil, fmt.Errorf("can't resolve token %s in a scalar", token)
+		}
+	}
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node, nil
+}
@@ -0,0 +1,474 @@
+package schema
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestReferencedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "schemas"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "schemas", "image.json"), []byte(`{
+		"definitions": {"image": {"properties": {"tag": {"$ref": "tag.yaml"}}}}
+	}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "schemas", "tag.yaml"), []byte("type: string\n"), 0o644))
+	err := os.WriteFile(valuesPath, []byte(`# @schema
+# $ref: ./schemas/image.json#/definitions/image
+# @schema
+image: {}
+nested:
+  # @schema
+  # type: object
+  # patternProperties:
+  #   "^x-":
+  #     $ref: missing.json
+  # @schema
+  extra: {}
+  # @schema
+  # $ref: https://example.com/schema.json
+  # @schema
+  remote: {}
+  # @schema
+  # $ref: "#/definitions/local"
+  # @schema
+  local: {}
+  # @schema
+  # $ref: ./schemas/image.json
+  # @schema
+  duplicate: {}
+  # @schema
+  # type: object
+  # additionalProperties:
+  #   $ref: ./schemas/label.json
+  # @schema
+  labels: {}
+`), 0o644)
+	assert.NoError(t, err)
+
+	files, err := ReferencedFiles(valuesPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(tmpDir, "schemas", "image.json"),
+		// Files referenced by referenced files are watched as well
+		filepath.Join(tmpDir, "schemas", "tag.yaml"),
+		filepath.Join(tmpDir, "missing.json"),
+		filepath.Join(tmpDir, "schemas", "label.json"),
+	}, files)
+
+	_, err = ReferencedFiles(filepath.Join(tmpDir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+// yamlToSchemaFromFile generates the schema of the values file
+func yamlToSchemaFromFile(t *testing.T, valuesPath string) (*Schema, error) {
+	t.Helper()
+	content, err := os.ReadFile(valuesPath)
+	assert.NoError(t, err)
+	var node yaml.Node
+	assert.NoError(t, yaml.Unmarshal(content, &node))
+	return YamlToSchema(valuesPath, &node, false, false, false, true, &SkipAutoGenerationConfig{}, nil)
+}
+
+// validateWithSchema validates the instance with the final schema
+func validateWithSchema(t *testing.T, s *Schema, instance string) error {
+	t.Helper()
+	s.HoistDefinitions()
+	content, err := s.ToJson()
+	assert.NoError(t, err)
+	parsed, err := jsonschema.UnmarshalJSON(bytes.NewReader(content))
+	assert.NoError(t, err)
+	c := jsonschema.NewCompiler()
+	assert.NoError(t, c.AddResource("schema.json", parsed))
+	compiled, err := c.Compile("schema.json")
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	value, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(instance)))
+	assert.NoError(t, err)
+	return compiled.Validate(value)
+}
+
+func TestHandleSchemaRefs_AllPositions(t *testing.T) {
+	tmpDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "port.json"), []byte(`{"type": "integer", "minimum": 1}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "defs.json"), []byte(`{"definitions": {"port": {"$ref": "port.json"}}}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "service.json"), []byte(`{
+		"type": "object",
+		"properties": {"port": {"$ref": "port.json"}}
+	}`), 0o644))
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	assert.NoError(t, os.WriteFile(valuesPath, []byte(`# @schema
+# type: object
+# properties:
+#   port:
+#     $ref: port.json
+# additionalProperties:
+#   $ref: port.json
+# @schema
+ports: {}
+# @schema
+# type: array
+# items:
+#   $ref: service.json
+# @schema
+services: []
+# @schema
+# anyOf:
+#   - $ref: port.json
+#   - type: string
+# not:
+#   $ref: defs.json#/definitions/port
+# if:
+#   $ref: port.json
+# then:
+#   $ref: port.json
+# else:
+#   $ref: port.json
+# definitions:
+#   port:
+#     $ref: port.json
+# @schema
+port: 80
+# @schema
+# type: array
+# contains:
+#   $ref: port.json
+# @schema
+list: []
+`), 0o644))
+
+	s, err := yamlToSchemaFromFile(t, valuesPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Targets referenced more than once in the values file are shared
+	port := func(subSchema *Schema) {
+		t.Helper()
+		if assert.NotNil(t, subSchema) {
+			assert.Equal(t, "#/definitions/port.json", subSchema.Ref)
+		}
+	}
+	if assert.Contains(t, s.Definitions, "port.json") {
+		assert.Equal(t, StringOrArrayOfString{"integer"}, s.Definitions["port.json"].Type)
+	}
+	ports := s.Properties["ports"]
+	port(ports.Properties["port"])
+	additional, ok := ports.AdditionalProperties.(*Schema)
+	if assert.True(t, ok) {
+		port(additional)
+	}
+	// Refs within the referenced file are resolved as well
+	port(s.Properties["services"].Items.Properties["port"])
+	p := s.Properties["port"]
+	port(p.AnyOf[0])
+	port(p.If)
+	port(p.Then)
+	port(p.Else)
+	port(p.Definitions["port"])
+	port(p.Not)
+	port(s.Properties["list"].Contains)
+}
+
+func TestHandleSchemaRefs_Circular(t *testing.T) {
+	tmpDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "tree.json"), []byte(`{
+		"type": "object",
+		"properties": {"name": {"type": "string"}, "children": {"type": "array", "items": {"$ref": "#"}}}
+	}`), 0o644))
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	assert.NoError(t, os.WriteFile(valuesPath, []byte(`# @schema
+# $ref: tree.json
+# @schema
+tree: {}
+`), 0o644))
+
+	// Recursive targets are added to the definitions
+	s, err := yamlToSchemaFromFile(t, valuesPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "#/definitions/tree.json", s.Properties["tree"].Ref)
+	if assert.Contains(t, s.Definitions, "tree.json") {
+		assert.Equal(t, "#/definitions/tree.json", s.Definitions["tree.json"].Properties["children"].Items.Ref)
+	}
+	assert.NoError(t, validateWithSchema(t, s, `{"tree": {"children": [{"name": "a", "children": [{"name": "b"}]}]}}`))
+	assert.Error(t, validateWithSchema(t, s, `{"tree": {"children": [{"children": [{"name": 1}]}]}}`))
+
+	// Refs which only point to each other can't be defined
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.json"), []byte(`{"$ref": "b.json"}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "b.json"), []byte(`{"$ref": "a.json"}`), 0o644))
+	assert.NoError(t, os.WriteFile(valuesPath, []byte(`# @schema
+# $ref: a.json
+# @schema
+a: {}
+`), 0o644))
+	_, err = yamlToSchemaFromFile(t, valuesPath)
+	aPath, bPath := filepath.Join(tmpDir, "a.json"), filepath.Join(tmpDir, "b.json")
+	assert.ErrorContains(t, err, "circular $ref a.json: "+aPath+"# -> "+bPath+"# -> "+aPath+"#")
+}
+
+func TestHandleSchemaRefs_Transitive(t *testing.T) {
+	tmpDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "schemas", "common"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "schemas", "ingress.json"), []byte(`{
+		"type": "object",
+		"properties": {
+			"host": {"$ref": "#/$defs/host"},
+			"tls": {"$ref": "./tls.json"},
+			"defaultTls": {"$ref": "./tls.json"}
+		},
+		"$defs": {"host": {"$ref": "common/host.json#/definitions/hostname"}}
+	}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "schemas", "tls.json"), []byte(`{
+		"type": "object",
+		"properties": {"secretName": {"type": "string"}}
+	}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "schemas", "common", "host.json"), []byte(`{
+		"definitions": {"hostname": {"type": "string", "format": "hostname"}}
+	}`), 0o644))
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	assert.NoError(t, os.WriteFile(valuesPath, []byte(`# @schema
+# $ref: schemas/ingress.json
+# @schema
+ingress: {}
+`), 0o644))
+
+	s, err := yamlToSchemaFromFile(t, valuesPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ingress := s.Properties["ingress"]
+	assert.Equal(t, StringOrArrayOfString{"object"}, ingress.Type)
+	// Local refs and refs relative to the referenced files are inlined
+	assert.Empty(t, ingress.Properties["host"].Ref)
+	assert.Equal(t, "hostname", ingress.Properties["host"].Format)
+	// Shared targets are added to the definitions once
+	assert.Equal(t, "#/definitions/schemas_tls.json", ingress.Properties["tls"].Ref)
+	assert.Equal(t, "#/definitions/schemas_tls.json", ingress.Properties["defaultTls"].Ref)
+	assert.Empty(t, ingress.Definitions)
+	assert.Equal(t, []string{"schemas_tls.json"}, sortedKeys(s.Definitions))
+	assert.Equal(t, StringOrArrayOfString{"string"}, s.Definitions["schemas_tls.json"].Properties["secretName"].Type)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "schemas", "tls.json"), []byte(`{"$ref": "missing.json"}`), 0o644))
+	_, err = yamlToSchemaFromFile(t, valuesPath)
+	assert.ErrorContains(t, err, "failed to resolve $ref missing.json in "+filepath.Join(tmpDir, "schemas", "tls.json"))
+}
+
+func TestHandleSchemaRefs_YAML(t *testing.T) {
+	tmpDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "image.yaml"), []byte(`# The image of a container
+type: object
+x-group: images
+properties:
+  repository:
+    type: string
+  tag:
+    $ref: "#/$defs/tag"
+  pullPolicy:
+    $ref: policies.yml#/$defs/pullPolicy
+$defs:
+  tag:
+    type: string
+    pattern: ^v
+`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "policies.yml"), []byte(`$defs:
+  pullPolicy:
+    enum: [Always, IfNotPresent]
+`), 0o644))
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	assert.NoError(t, os.WriteFile(valuesPath, []byte(`# @schema
+# $ref: image.yaml
+# @schema
+image: {}
+# @schema
+# $ref: image.yaml#/properties/repository
+# @schema
+repository: nginx
+`), 0o644))
+
+	s, err := yamlToSchemaFromFile(t, valuesPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	image := s.Properties["image"]
+	assert.Equal(t, StringOrArrayOfString{"object"}, image.Type)
+	assert.Equal(t, map[string]interface{}{"x-group": "images"}, image.CustomAnnotations)
+	assert.Equal(t, "^v", image.Properties["tag"].Pattern)
+	assert.Equal(t, []interface{}{"Always", "IfNotPresent"}, image.Properties["pullPolicy"].Enum)
+	assert.Empty(t, image.Definitions)
+	assert.Equal(t, StringOrArrayOfString{"string"}, s.Properties["repository"].Type)
+
+	assert.NoError(t, os.WriteFile(valuesPath, []byte(`# @schema
+# $ref: image.yaml#/properties/digest
+# @schema
+image: {}
+`), 0o644))
+	_, err = yamlToSchemaFromFile(t, valuesPath)
+	assert.ErrorContains(t, err, "failed to resolve JSON pointer /properties/digest in "+filepath.Join(tmpDir, "image.yaml")+": key digest not found")
+}
+
+func TestHandleSchemaRefs_MergesSiblings(t *testing.T) {
+	tmpDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "image.json"), []byte(`{
+		"type": "object",
+		"title": "Image",
+		"description": "A container image",
+		"properties": {"repository": {"type": "string"}, "tag": {"type": "string"}},
+		"required": ["repository"]
+	}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "sidecar.json"), []byte(`{
+		"type": "object",
+		"title": "Sidecar",
+		"description": "A sidecar image"
+	}`), 0o644))
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	assert.NoError(t, os.WriteFile(valuesPath, []byte(`# @schema
+# $ref: image.json
+# description: The image of the web server
+# default:
+#   repository: nginx
+# required: true
+# x-group: web
+# properties:
+#   tag:
+#     pattern: ^v
+# @schema
+image:
+  repository: nginx
+# @schema
+# $ref: sidecar.json
+# @schema
+# The image of the sidecar
+sidecarImage:
+  repository: busybox
+# @schema
+# $ref: https://example.com/schemas/port.json
+# @schema
+# The port of the web server
+port: 80
+`), 0o644))
+
+	s, err := yamlToSchemaFromFile(t, valuesPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	image := s.Properties["image"]
+	assert.Empty(t, image.Ref)
+	// Annotations override the referenced schema
+	assert.Equal(t, "Image", image.Title)
+	assert.Equal(t, "The image of the web server", image.Description)
+	assert.Equal(t, map[string]interface{}{"repository": "nginx"}, image.Default)
+	assert.Equal(t, map[string]interface{}{"x-group": "web"}, image.CustomAnnotations)
+	assert.Equal(t, []string{"repository"}, image.Required.Strings)
+	assert.Contains(t, s.Required.Strings, "image")
+	// Other keywords apply in addition to the referenced schema
+	assert.Equal(t, StringOrArrayOfString{"object"}, image.Type)
+	assert.Equal(t, StringOrArrayOfString{"string"}, image.Properties["tag"].Type)
+	if assert.Len(t, image.AllOf, 1) {
+		assert.Equal(t, "^v", image.AllOf[0].Properties["tag"].Pattern)
+		assert.Empty(t, image.AllOf[0].Description)
+	}
+
+	// Without local keywords, the referenced schema is used as it is
+	sidecarImage := s.Properties["sidecarImage"]
+	assert.Equal(t, "A sidecar image", sidecarImage.Description)
+	assert.Empty(t, sidecarImage.AllOf)
+
+	// Keys whose ref is kept don't get inferred annotations
+	port := s.Properties["port"]
+	assert.Equal(t, "https://example.com/schemas/port.json", port.Ref)
+	assert.Empty(t, port.Title)
+	assert.Empty(t, port.Description)
+	assert.Nil(t, port.Default)
+}
+
+func TestHandleSchemaRefs_SharedTargets(t *testing.T) {
+	tmpDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "tls.json"), []byte(`{
+		"type": "object",
+		"properties": {"secretName": {"type": "string"}}
+	}`), 0o644))
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	assert.NoError(t, os.WriteFile(valuesPath, []byte(`# @schema
+# $ref: ./tls.json
+# description: The TLS of the ingress
+# minProperties: 1
+# @schema
+a: {}
+# @schema
+# $ref: ./tls.json
+# @schema
+b: {}
+`), 0o644))
+
+	s, err := yamlToSchemaFromFile(t, valuesPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Keys of the values file referencing the same file share its definition
+	assert.Equal(t, []string{"tls.json"}, sortedKeys(s.Definitions))
+	assert.Equal(t, "#/definitions/tls.json", s.Properties["b"].Ref)
+	a := s.Properties["a"]
+	assert.Empty(t, a.Ref)
+	assert.Equal(t, "The TLS of the ingress", a.Description)
+	if assert.Len(t, a.AllOf, 2) {
+		assert.Equal(t, "#/definitions/tls.json", a.AllOf[0].Ref)
+		assert.Equal(t, 1, *a.AllOf[1].MinProperties)
+	}
+
+	// The keywords next to a shared ref apply
+	assert.NoError(t, validateWithSchema(t, s, `{"a": {"secretName": "tls"}, "b": {}}`))
+	assert.Error(t, validateWithSchema(t, s, `{"a": {}, "b": {}}`))
+	assert.Error(t, validateWithSchema(t, s, `{"a": {"secretName": 1}, "b": {}}`))
+}
+
+func TestHandleSchemaRefs_DeclaredDefinitions(t *testing.T) {
+	tmpDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "tls.json"), []byte(`{
+		"type": "object",
+		"properties": {"secretName": {"type": "string"}}
+	}`), 0o644))
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	assert.NoError(t, os.WriteFile(valuesPath, []byte(`# @schema.root
+# definitions:
+#   tls.json:
+#     type: string
+# @schema.root
+# @schema
+# $ref: ./tls.json
+# @schema
+a: {}
+# @schema
+# $ref: ./tls.json
+# @schema
+b: {}
+# @schema
+# $ref: "#/definitions/tls.json"
+# @schema
+c: ""
+`), 0o644))
+
+	s, err := yamlToSchemaFromFile(t, valuesPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The shared target doesn't replace the declared definition of the same name
+	assert.Equal(t, []string{"tls.json", "tls.json_2"}, sortedKeys(s.Definitions))
+	assert.Equal(t, "#/definitions/tls.json_2", s.Properties["a"].Ref)
+	assert.Equal(t, "#/definitions/tls.json_2", s.Properties["b"].Ref)
+	assert.NoError(t, validateWithSchema(t, s, `{"a": {"secretName": "tls"}, "b": {}, "c": "tls"}`))
+	assert.Error(t, validateWithSchema(t, s, `{"a": {}, "b": {}, "c": {}}`))
+}
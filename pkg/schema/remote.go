@@ -0,0 +1,390 @@
+package schema
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dadav/go-jsonpointer"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+var (
+	remoteNameRemover     = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	pointerTokenEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerTokenUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// remoteIndexFile maps the urls to the hashes of their cached documents
+const remoteIndexFile = "index.json"
+
+// RemoteRefs resolves http(s) $refs. The documents are stored in a cache
+// directory by the sha256 of their content, an index maps the urls to them.
+type RemoteRefs struct {
+	cacheDir string
+	// offline only uses the cache
+	offline bool
+	// bundle inlines the remote refs into the definitions
+	bundle bool
+	client *http.Client
+
+	// mu guards documents, fetches and the cache index
+	mu        sync.Mutex
+	documents map[string][]byte
+	// fetches are the fetches in flight by url, so concurrent fetches of the
+	// same url download it once
+	fetches map[string]*remoteFetch
+}
+
+// remoteFetch is a fetch of a document
+type remoteFetch struct {
+	once     sync.Once
+	document []byte
+	err      error
+}
+
+// NewRemoteRefs returns a resolver of remote refs which caches the documents
+// in the directory. With offline, documents which aren't cached can't be
+// resolved. With bundle, ResolveRefs inlines the remote documents, so the
+// schema is self-contained.
+func NewRemoteRefs(cacheDir string, offline, bundle bool) (*RemoteRefs, error) {
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the cache directory: %w", err)
+		}
+		cacheDir = filepath.Join(userCacheDir, "helm-schema", "refs")
+	}
+	return &RemoteRefs{
+		cacheDir:  cacheDir,
+		offline:   offline,
+		bundle:    bundle,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		documents: map[string][]byte{},
+		fetches:   map[string]*remoteFetch{},
+	}, nil
+}
+
+// IsRemoteRef reports whether the ref points to a http(s) url
+func IsRemoteRef(ref string) bool {
+	return strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://")
+}
+
+// Fetch returns the document of the url from the cache, or downloads and
+// caches it unless the resolver is offline
+func (r *RemoteRefs) Fetch(documentURL string) ([]byte, error) {
+	r.mu.Lock()
+	if document, ok := r.documents[documentURL]; ok {
+		r.mu.Unlock()
+		return document, nil
+	}
+	fetch, ok := r.fetches[documentURL]
+	if !ok {
+		fetch = &remoteFetch{}
+		r.fetches[documentURL] = fetch
+	}
+	r.mu.Unlock()
+
+	fetch.once.Do(func() {
+		fetch.document, fetch.err = r.fetch(documentURL)
+	})
+
+	// Failed fetches are retried by the next call
+	r.mu.Lock()
+	if r.fetches[documentURL] == fetch {
+		delete(r.fetches, documentURL)
+	}
+	r.mu.Unlock()
+	return fetch.document, fetch.err
+}
+
+// fetch reads the document of the url from the cache or downloads it. The
+// lock is only held to access the index, not while downloading.
+func (r *RemoteRefs) fetch(documentURL string) ([]byte, error) {
+	r.mu.Lock()
+	index, err := r.readIndex()
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if hash, ok := index[documentURL]; ok {
+		document, err := os.ReadFile(filepath.Join(r.cacheDir, hash+".json"))
+		if err == nil {
+			r.mu.Lock()
+			r.documents[documentURL] = document
+			r.mu.Unlock()
+			return document, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read cached document of %s: %w", documentURL, err)
+		}
+	}
+	if r.offline {
+		return nil, fmt.Errorf("document %s is not cached in %s and offline mode is enabled", documentURL, r.cacheDir)
+	}
+
+	document, err := r.download(documentURL)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(document)
+	hash := hex.EncodeToString(sum[:])
+	if err := os.MkdirAll(r.cacheDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(r.cacheDir, hash+".json"), document); err != nil {
+		return nil, fmt.Errorf("failed to cache document of %s: %w", documentURL, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// The index is read again, other documents may have been added meanwhile
+	index, err = r.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	index[documentURL] = hash
+	encodedIndex, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(filepath.Join(r.cacheDir, remoteIndexFile), encodedIndex); err != nil {
+		return nil, fmt.Errorf("failed to update cache index: %w", err)
+	}
+
+	r.documents[documentURL] = document
+	return document, nil
+}
+
+func (r *RemoteRefs) readIndex() (map[string]string, error) {
+	index := map[string]string{}
+	content, err := os.ReadFile(filepath.Join(r.cacheDir, remoteIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache index: %w", err)
+	}
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cache index %s: %w", filepath.Join(r.cacheDir, remoteIndexFile), err)
+	}
+	return index, nil
+}
+
+func (r *RemoteRefs) download(documentURL string) ([]byte, error) {
+	response, err := r.client.Get(documentURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", documentURL, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", documentURL, response.Status)
+	}
+	document, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", documentURL, err)
+	}
+	if !json.Valid(document) {
+		return nil, fmt.Errorf("document %s is not valid JSON", documentURL)
+	}
+	return document, nil
+}
+
+// writeFileAtomic replaces the file, so concurrent readers never see partial
+// content
+func writeFileAtomic(path string, content []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Load implements jsonschema.URLLoader, so schemas are compiled against the
+// fetched documents
+func (r *RemoteRefs) Load(documentURL string) (any, error) {
+	if !IsRemoteRef(documentURL) {
+		return nil, fmt.Errorf("unsupported url %s", documentURL)
+	}
+	document, err := r.Fetch(documentURL)
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(document))
+}
+
+// ResolveRefs checks that the remote refs of the schema can be resolved,
+// including their JSON pointer fragments. With bundle, the remote documents
+// are added to the definitions of the schema and the refs point to them.
+// The resolver may be nil, remote refs are kept as they are then.
+func (r *RemoteRefs) ResolveRefs(s *Schema) error {
+	if r == nil {
+		return nil
+	}
+	b := &remoteBundle{refs: r, root: s, names: map[string]string{}}
+	return b.resolve(s, "")
+}
+
+// remoteBundle resolves the remote refs of a schema
+type remoteBundle struct {
+	refs *RemoteRefs
+	root *Schema
+	// names are the definition names of the bundled documents by url
+	names map[string]string
+}
+
+// resolve resolves the remote refs of the schema, relative refs are resolved
+// against the base url of the document the schema belongs to
+func (b *remoteBundle) resolve(s *Schema, baseURL string) error {
+	if s.Ref != "" && (IsRemoteRef(s.Ref) || (baseURL != "" && !strings.HasPrefix(s.Ref, "#"))) {
+		ref := s.Ref
+		if baseURL != "" {
+			base, err := url.Parse(baseURL)
+			if err != nil {
+				return err
+			}
+			relative, err := url.Parse(s.Ref)
+			if err != nil {
+				return fmt.Errorf("invalid $ref %s in %s: %w", s.Ref, baseURL, err)
+			}
+			ref = base.ResolveReference(relative).String()
+		}
+		if IsRemoteRef(ref) {
+			resolved, err := b.resolveRef(ref)
+			if err != nil {
+				return err
+			}
+			s.Ref = resolved
+		}
+	} else if baseURL != "" && strings.HasPrefix(s.Ref, "#") && b.refs.bundle {
+		// Refs within a bundled document point into its definition
+		s.Ref = b.localRef(b.names[baseURL], strings.TrimPrefix(s.Ref, "#"))
+	}
+
+	for _, subSchema := range s.Subschemas() {
+		if err := b.resolve(subSchema, baseURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveRef fetches the document of a remote ref and checks its fragment.
+// It returns the ref to use, which points to the definitions with bundle.
+func (b *remoteBundle) resolveRef(ref string) (string, error) {
+	documentURL, fragment, _ := strings.Cut(ref, "#")
+	if fragment != "" && !strings.HasPrefix(fragment, "/") {
+		return "", fmt.Errorf("failed to resolve $ref %s: only JSON pointer fragments are supported", ref)
+	}
+
+	content, err := b.refs.Fetch(documentURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve $ref %s: %w", ref, err)
+	}
+	var document interface{}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return "", fmt.Errorf("failed to unmarshal %s: %w", documentURL, err)
+	}
+	if fragment != "" {
+		if _, err := jsonpointer.Get(document, fragment); err != nil {
+			return "", fmt.Errorf("failed to resolve JSON pointer %s in %s: %w", fragment, documentURL, err)
+		}
+	}
+	if !b.refs.bundle {
+		return ref, nil
+	}
+
+	if _, ok := b.names[documentURL]; !ok {
+		if err := b.bundle(documentURL, content); err != nil {
+			return "", err
+		}
+	}
+	return b.localRef(b.names[documentURL], fragment), nil
+}
+
+// bundle adds the document to the definitions of the root schema. Its own
+// definitions become definitions of the root as well, named after the
+// document.
+func (b *remoteBundle) bundle(documentURL string, content []byte) error {
+	name := b.definitionName(documentURL)
+	b.names[documentURL] = name
+
+	var document Schema
+	if err := json.Unmarshal(content, &document); err != nil {
+		return fmt.Errorf("failed to unmarshal schema from %s: %w", documentURL, err)
+	}
+	document.Id = ""
+	document.Schema = ""
+	definitions := document.Definitions
+	document.Definitions = nil
+
+	if b.root.Definitions == nil {
+		b.root.Definitions = map[string]*Schema{}
+	}
+	b.root.Definitions[name] = &document
+	for definitionName, definition := range definitions {
+		if definition != nil {
+			b.root.Definitions[name+"."+definitionName] = definition
+		}
+	}
+
+	if err := b.resolve(&document, documentURL); err != nil {
+		return err
+	}
+	for _, definitionName := range sortedKeys(definitions) {
+		if definition := definitions[definitionName]; definition != nil {
+			if err := b.resolve(definition, documentURL); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// localRef returns the ref to the location of the pointer in the bundled
+// document
+func (b *remoteBundle) localRef(name, pointer string) string {
+	for _, prefix := range []string{"/definitions/", "/$defs/"} {
+		if rest, ok := strings.CutPrefix(pointer, prefix); ok {
+			token, remainder, _ := strings.Cut(rest, "/")
+			if remainder != "" {
+				remainder = "/" + remainder
+			}
+			definitionName := pointerTokenUnescaper.Replace(token)
+			return "#/definitions/" + pointerTokenEscaper.Replace(name+"."+definitionName) + remainder
+		}
+	}
+	return "#/definitions/" + name + pointer
+}
+
+// definitionName returns a unique definition name for the document, derived
+// from its url
+func (b *remoteBundle) definitionName(documentURL string) string {
+	base := remoteNameRemover.ReplaceAllString(strings.TrimPrefix(strings.TrimPrefix(documentURL, "https://"), "http://"), "_")
+	name := base
+	for i := 2; b.root.Definitions[name] != nil; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	return name
+}
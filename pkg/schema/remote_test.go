@@ -0,0 +1,219 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRemoteServer serves the documents and counts the requests
+func newRemoteServer(t *testing.T, documents map[string]string) (*httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		document, ok := documents[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(document))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRemoteRefs_Fetch(t *testing.T) {
+	server, requests := newRemoteServer(t, map[string]string{
+		"/port.json":    `{"type": "integer"}`,
+		"/invalid.json": `not json`,
+	})
+	cacheDir := t.TempDir()
+
+	remote, err := NewRemoteRefs(cacheDir, false, false)
+	assert.NoError(t, err)
+	document, err := remote.Fetch(server.URL + "/port.json")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type": "integer"}`, string(document))
+
+	// The document is stored by the hash of its content
+	index, err := os.ReadFile(filepath.Join(cacheDir, "index.json"))
+	assert.NoError(t, err)
+	var hashes map[string]string
+	assert.NoError(t, json.Unmarshal(index, &hashes))
+	assert.FileExists(t, filepath.Join(cacheDir, hashes[server.URL+"/port.json"]+".json"))
+
+	// Offline, only the cache is used
+	offline, err := NewRemoteRefs(cacheDir, true, false)
+	assert.NoError(t, err)
+	document, err = offline.Fetch(server.URL + "/port.json")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type": "integer"}`, string(document))
+	assert.Equal(t, 1, *requests)
+
+	_, err = offline.Fetch(server.URL + "/other.json")
+	assert.EqualError(t, err, "document "+server.URL+"/other.json is not cached in "+cacheDir+" and offline mode is enabled")
+
+	_, err = remote.Fetch(server.URL + "/missing.json")
+	assert.EqualError(t, err, "failed to download "+server.URL+"/missing.json: 404 Not Found")
+	_, err = remote.Fetch(server.URL + "/invalid.json")
+	assert.EqualError(t, err, "document "+server.URL+"/invalid.json is not valid JSON")
+}
+
+func TestRemoteRefs_FetchConcurrently(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var slowRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.json" {
+			if slowRequests.Add(1) == 1 {
+				close(started)
+			}
+			<-release
+		}
+		_, _ = w.Write([]byte(`{"type": "string"}`))
+	}))
+	t.Cleanup(server.Close)
+	remote, err := NewRemoteRefs(t.TempDir(), false, false)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			document, err := remote.Fetch(server.URL + "/slow.json")
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"type": "string"}`, string(document))
+		})
+	}
+	<-started
+
+	// Other documents are fetched while a download is in flight
+	fetched := make(chan error)
+	go func() {
+		_, err := remote.Fetch(server.URL + "/fast.json")
+		fetched <- err
+	}()
+	select {
+	case err := <-fetched:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Error("fetch waited for the download of another document")
+	}
+
+	// Concurrent fetches of the same url download it once
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), slowRequests.Load())
+}
+
+func TestRemoteRefs_ResolveRefs(t *testing.T) {
+	server, _ := newRemoteServer(t, map[string]string{
+		"/common.json": `{"definitions": {"port": {"type": "integer"}}}`,
+	})
+	remote, err := NewRemoteRefs(t.TempDir(), false, false)
+	assert.NoError(t, err)
+
+	s := &Schema{Properties: map[string]*Schema{
+		"port":  {Ref: server.URL + "/common.json#/definitions/port"},
+		"local": {Ref: "#/definitions/local"},
+	}}
+	assert.NoError(t, remote.ResolveRefs(s))
+	// Without bundle, the refs are kept
+	assert.Equal(t, server.URL+"/common.json#/definitions/port", s.Properties["port"].Ref)
+	assert.Empty(t, s.Definitions)
+
+	// Typos are reported
+	err = remote.ResolveRefs(&Schema{Properties: map[string]*Schema{"port": {Ref: server.URL + "/common.json#/definitions/prot"}}})
+	assert.ErrorContains(t, err, "failed to resolve JSON pointer /definitions/prot in "+server.URL+"/common.json")
+	err = remote.ResolveRefs(&Schema{Properties: map[string]*Schema{"port": {Ref: server.URL + "/commons.json"}}})
+	assert.EqualError(t, err, "failed to resolve $ref "+server.URL+"/commons.json: failed to download "+server.URL+"/commons.json: 404 Not Found")
+	err = remote.ResolveRefs(&Schema{Ref: server.URL + "/common.json#port"})
+	assert.EqualError(t, err, "failed to resolve $ref "+server.URL+"/common.json#port: only JSON pointer fragments are supported")
+
+	// Without resolver, remote refs are kept
+	var none *RemoteRefs
+	assert.NoError(t, none.ResolveRefs(s))
+}
+
+func TestRemoteRefs_Bundle(t *testing.T) {
+	server, _ := newRemoteServer(t, map[string]string{
+		"/schemas/service.json": `{
+			"$id": "https://example.com/service.json",
+			"type": "object",
+			"properties": {
+				"port": {"$ref": "#/definitions/port"},
+				"labels": {"$ref": "labels.json"}
+			},
+			"definitions": {"port": {"type": "integer", "minimum": 1}}
+		}`,
+		"/schemas/labels.json": `{"type": "object", "additionalProperties": {"type": "string"}}`,
+	})
+	remote, err := NewRemoteRefs(t.TempDir(), false, true)
+	assert.NoError(t, err)
+
+	s := &Schema{
+		Type: StringOrArrayOfString{"object"},
+		Properties: map[string]*Schema{
+			"service": {Ref: server.URL + "/schemas/service.json"},
+			"port":    {Ref: server.URL + "/schemas/service.json#/definitions/port"},
+		},
+	}
+	assert.NoError(t, remote.ResolveRefs(s))
+
+	name := remoteNameRemover.ReplaceAllString(server.URL[len("http://"):], "_") + "_schemas_"
+	assert.Equal(t, "#/definitions/"+name+"service.json", s.Properties["service"].Ref)
+	assert.Equal(t, "#/definitions/"+name+"service.json.port", s.Properties["port"].Ref)
+	assert.Equal(t, []string{name + "labels.json", name + "service.json", name + "service.json.port"}, sortedKeys(s.Definitions))
+
+	service := s.Definitions[name+"service.json"]
+	assert.Empty(t, service.Id)
+	assert.Empty(t, service.Definitions)
+	assert.Equal(t, "#/definitions/"+name+"service.json.port", service.Properties["port"].Ref)
+	assert.Equal(t, "#/definitions/"+name+"labels.json", service.Properties["labels"].Ref)
+
+	// The bundled schema is self-contained
+	jsonStr, err := s.ToJson()
+	assert.NoError(t, err)
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(jsonStr))
+	assert.NoError(t, err)
+	compiler := jsonschema.NewCompiler()
+	compiler.UseLoader(jsonschema.SchemeURLLoader{})
+	assert.NoError(t, compiler.AddResource("values.schema.json", doc))
+	compiled, err := compiler.Compile("values.schema.json")
+	if assert.NoError(t, err) {
+		assert.NoError(t, compiled.Validate(map[string]interface{}{
+			"service": map[string]interface{}{"port": 80, "labels": map[string]interface{}{"app": "web"}},
+		}))
+		var validationErr *jsonschema.ValidationError
+		assert.True(t, errors.As(compiled.Validate(map[string]interface{}{"port": 0}), &validationErr))
+	}
+}
+
+func TestRemoteRefs_Load(t *testing.T) {
+	server, _ := newRemoteServer(t, map[string]string{
+		"/port.json": `{"type": "integer", "minimum": 1}`,
+	})
+	remote, err := NewRemoteRefs(t.TempDir(), false, false)
+	assert.NoError(t, err)
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(`{"properties": {"port": {"$ref": "` + server.URL + `/port.json"}}}`)))
+	assert.NoError(t, err)
+	compiler := jsonschema.NewCompiler()
+	compiler.UseLoader(remote)
+	assert.NoError(t, compiler.AddResource("values.schema.json", doc))
+	compiled, err := compiler.Compile("values.schema.json")
+	if assert.NoError(t, err) {
+		assert.NoError(t, compiled.Validate(map[string]interface{}{"port": 80}))
+		assert.Error(t, compiled.Validate(map[string]interface{}{"port": 0}))
+	}
+}
@@ -0,0 +1,317 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// rootAnnotationKeywords are the keywords of a schema which can be set by a
+// @schema.root block, see applyRootSchemaProperties
+var rootAnnotationKeywords = []string{
+	"title", "description", "$ref", "examples", "deprecated", "readOnly", "writeOnly", "additionalProperties",
+	"required", "patternProperties", "definitions", "allOf", "anyOf", "oneOf", "not",
+}
+
+// schemaKeywordsFirst are written before the other keywords of a schema
+var schemaKeywordsFirst = []string{"type", "title", "description"}
+
+// ReadSchemaFile reads a json schema file
+func ReadSchemaFile(path string) (*Schema, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Schema
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// AnnotateFromSchema turns an existing schema of the values into @schema
+// annotations, so that generating the schema of the annotated values yields an
+// equivalent schema. Only the parts which can't be inferred from the values
+// are written. Every key known to the schema gets a block, keys which already
+// have one are left unchanged. The required properties are written as lists
+// by the parents and the @schema.root block.
+func AnnotateFromSchema(content []byte, valuesPath string, existing *Schema) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode || len(doc.Content[0].Content) == 0 {
+		return content, nil
+	}
+	root := doc.Content[0]
+
+	generated, err := YamlToSchema(valuesPath, &doc, false, false, false, true, &SkipAutoGenerationConfig{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema: %w", err)
+	}
+
+	var points []InsertionPoint
+	if err := collectReversePoints(root, existing, generated, &points); err != nil {
+		return nil, err
+	}
+
+	for name := range existing.Properties {
+		// The generator adds global itself
+		if name == "global" {
+			continue
+		}
+		if !slices.ContainsFunc(root.Content, func(n *yaml.Node) bool { return n.Value == name }) {
+			log.Warnf("Property %s of the schema has no key in the values and can't be annotated", name)
+		}
+	}
+
+	firstKey := root.Content[0]
+	if !strings.Contains(firstKey.HeadComment, SchemaRootPrefix) && !strings.Contains(doc.HeadComment, SchemaRootPrefix) {
+		keywords, err := rootKeywords(existing)
+		if err != nil {
+			return nil, err
+		}
+		if len(keywords) > 0 {
+			points = append(points, InsertionPoint{
+				Line:     firstKey.Line,
+				Indent:   strings.Repeat(" ", firstKey.Column-1),
+				Keywords: keywords,
+				Root:     true,
+			})
+		}
+	}
+
+	if len(points) == 0 {
+		return content, nil
+	}
+	return insertAnnotations(content, points), nil
+}
+
+// AnnotateFromSchemaRewrite returns a rewrite for RewriteValuesFile which
+// annotates the values file with the parts of the existing schema.
+func AnnotateFromSchemaRewrite(valuesPath string, existing *Schema) func([]byte) ([]byte, error) {
+	return func(content []byte) ([]byte, error) {
+		return AnnotateFromSchema(content, valuesPath, existing)
+	}
+}
+
+func collectReversePoints(node *yaml.Node, existing, generated *Schema, points *[]InsertionPoint) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+		if valueNode.Kind == yaml.AliasNode && valueNode.Alias != nil {
+			valueNode = valueNode.Alias
+		}
+
+		property := existing.Properties[keyNode.Value]
+		if property == nil {
+			continue
+		}
+		var generatedProperty *Schema
+		if generated != nil {
+			generatedProperty = generated.Properties[keyNode.Value]
+		}
+
+		// Children are annotated themselves if the values contain every property
+		annotateChildren := valueNode.Kind == yaml.MappingNode && property.Ref == "" && len(property.Properties) > 0
+		for name := range property.Properties {
+			if !slices.ContainsFunc(valueNode.Content, func(n *yaml.Node) bool { return n.Value == name }) {
+				annotateChildren = false
+				break
+			}
+		}
+
+		if !HasSchemaAnnotation(keyNode.HeadComment) {
+			keywords, err := reverseKeywords(property, generatedProperty, valueNode, annotateChildren)
+			if err != nil {
+				return &LineError{Line: keyNode.Line, Err: fmt.Errorf("error annotating key %s: %w", keyNode.Value, err)}
+			}
+			if len(keywords) > 0 {
+				*points = append(*points, InsertionPoint{
+					Line:     keyNode.Line,
+					Indent:   strings.Repeat(" ", keyNode.Column-1),
+					Keywords: keywords,
+				})
+			}
+		}
+
+		if annotateChildren {
+			if err := collectReversePoints(valueNode, property, generatedProperty, points); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reverseKeywords returns the lines of the annotation block of a key, the
+// keywords of the property which would differ if they were inferred.
+func reverseKeywords(property, generated *Schema, valueNode *yaml.Node, annotateChildren bool) ([]string, error) {
+	encoded, err := json.Marshal(property)
+	if err != nil {
+		return nil, err
+	}
+	var keywords map[string]any
+	if err := json.Unmarshal(encoded, &keywords); err != nil {
+		return nil, err
+	}
+
+	if annotateChildren {
+		delete(keywords, "properties")
+	}
+	dropEmptyRequired(keywords)
+
+	if generated != nil {
+		// Inferred annotations only need to be written if they differ
+		inferred, err := json.Marshal(generated)
+		if err != nil {
+			return nil, err
+		}
+		var inferredKeywords map[string]any
+		if err := json.Unmarshal(inferred, &inferredKeywords); err != nil {
+			return nil, err
+		}
+		for _, keyword := range []string{"title", "description", "default"} {
+			if value, ok := keywords[keyword]; ok && jsonEqual(value, inferredKeywords[keyword]) {
+				delete(keywords, keyword)
+			}
+		}
+	}
+
+	if property.Ref == "" {
+		// Without these, they would be inferred from the values
+		if _, ok := keywords["additionalProperties"]; !ok && valueNode.Kind == yaml.MappingNode {
+			keywords["additionalProperties"] = true
+		}
+		if _, ok := keywords["properties"]; !ok && !annotateChildren && valueNode.Kind == yaml.MappingNode && len(valueNode.Content) > 0 {
+			keywords["properties"] = map[string]any{}
+		}
+		if _, ok := keywords["items"]; !ok && valueNode.Kind == yaml.SequenceNode {
+			keywords["items"] = map[string]any{}
+		}
+	}
+
+	if len(keywords) == 0 {
+		return nil, nil
+	}
+	return encodeKeywords(keywords, schemaKeywordsFirst)
+}
+
+// rootKeywords returns the lines of the @schema.root block
+func rootKeywords(existing *Schema) ([]string, error) {
+	encoded, err := json.Marshal(existing)
+	if err != nil {
+		return nil, err
+	}
+	var keywords map[string]any
+	if err := json.Unmarshal(encoded, &keywords); err != nil {
+		return nil, err
+	}
+
+	for keyword := range keywords {
+		if !slices.Contains(rootAnnotationKeywords, keyword) && !strings.HasPrefix(keyword, CustomAnnotationPrefix) {
+			delete(keywords, keyword)
+		}
+	}
+	dropEmptyRequired(keywords)
+	// The generator forbids additional properties by default
+	if additional, ok := keywords["additionalProperties"]; !ok {
+		keywords["additionalProperties"] = true
+	} else if additional == false {
+		delete(keywords, "additionalProperties")
+	}
+
+	if len(keywords) == 0 {
+		return nil, nil
+	}
+	return encodeKeywords(keywords, schemaKeywordsFirst)
+}
+
+// encodeKeywords encodes the keywords as YAML lines, the first keywords are
+// written first and the others sorted.
+func encodeKeywords(keywords map[string]any, first []string) ([]string, error) {
+	encoded, err := json.Marshal(keywords)
+	if err != nil {
+		return nil, err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(encoded, &node); err != nil {
+		return nil, err
+	}
+	mapping := node.Content[0]
+	formatNode(mapping, first)
+
+	return encodeCommentBlock(mapping)
+}
+
+// formatNode removes the flow style of nodes parsed from json, only empty
+// collections are kept in flow style. json sorts the keys, the first keys of
+// every mapping are moved to the front.
+func formatNode(node *yaml.Node, first []string) {
+	formatNodeRecursive(node, first, true)
+}
+
+func formatNodeRecursive(node *yaml.Node, first []string, isSchema bool) {
+	if len(node.Content) > 0 || node.Kind == yaml.ScalarNode {
+		node.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle
+	}
+	for i, child := range node.Content {
+		// The keys of these mappings are names, their values are schemas
+		childIsSchema := true
+		if node.Kind == yaml.MappingNode && i%2 == 1 {
+			switch node.Content[i-1].Value {
+			case "properties", "patternProperties", "definitions":
+				childIsSchema = !isSchema
+			}
+		}
+		formatNodeRecursive(child, first, childIsSchema)
+	}
+	if node.Kind != yaml.MappingNode || !isSchema {
+		return
+	}
+
+	var front, rest []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !slices.Contains(first, node.Content[i].Value) {
+			rest = append(rest, node.Content[i:i+2]...)
+		}
+	}
+	for _, key := range first {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				front = append(front, node.Content[i:i+2]...)
+			}
+		}
+	}
+	node.Content = append(front, rest...)
+}
+
+// dropEmptyRequired removes the empty required lists, which are written for
+// every schema, from the decoded json of a schema and its subschemas
+func dropEmptyRequired(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		if required, ok := v["required"].([]any); ok && len(required) == 0 {
+			delete(v, "required")
+		}
+		for _, child := range v {
+			dropEmptyRequired(child)
+		}
+	case []any:
+		for _, child := range v {
+			dropEmptyRequired(child)
+		}
+	}
+}
+
+func jsonEqual(a, b any) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
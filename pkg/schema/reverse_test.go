@@ -0,0 +1,166 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAnnotateFromSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   string
+		existing string
+		want     string
+	}{
+		{
+			name:     "inferable keywords are skipped",
+			values:   "# Number of replicas\nreplicas: 1\n",
+			existing: `{"properties": {"replicas": {"type": "integer", "title": "replicas", "description": "Number of replicas", "default": 1, "minimum": 0}}}`,
+			want:     "# @schema.root\n# additionalProperties: true\n# @schema.root\n# @schema\n# type: integer\n# minimum: 0\n# @schema\n# Number of replicas\nreplicas: 1\n",
+		},
+		{
+			name:     "required lists and nested keys",
+			values:   "image:\n  repository: nginx\n  tag: latest\n",
+			existing: `{"additionalProperties": false, "required": ["image"], "properties": {"image": {"type": "object", "required": ["repository"], "additionalProperties": false, "properties": {"repository": {"type": "string", "pattern": "^[a-z]+$"}, "tag": {"type": "string", "enum": ["latest", "stable"]}}}}}`,
+			want: "# @schema.root\n# required:\n#   - image\n# @schema.root\n# @schema\n# type: object\n# additionalProperties: false\n# required:\n#   - repository\n# @schema\nimage:\n" +
+				"  # @schema\n  # type: string\n  # pattern: ^[a-z]+$\n  # @schema\n  repository: nginx\n" +
+				"  # @schema\n  # type: string\n  # enum:\n  #   - latest\n  #   - stable\n  # @schema\n  tag: latest\n",
+		},
+		{
+			name:     "properties missing in the values are written by the parent",
+			values:   "service:\n  port: 80\n",
+			existing: `{"additionalProperties": false, "properties": {"service": {"type": "object", "properties": {"port": {"type": "integer"}, "type": {"type": "string"}}}}}`,
+			want:     "# @schema\n# type: object\n# additionalProperties: true\n# properties:\n#   port:\n#     type: integer\n#   type:\n#     type: string\n# @schema\nservice:\n  port: 80\n",
+		},
+		{
+			name:     "unconstrained arrays and objects",
+			values:   "hosts: [a]\nextra:\n  a: 1\n",
+			existing: `{"additionalProperties": false, "properties": {"hosts": {"type": "array"}, "extra": {"type": "object", "additionalProperties": {"type": "integer"}}}}`,
+			want:     "# @schema\n# type: array\n# items: {}\n# @schema\nhosts: [a]\n# @schema\n# type: object\n# additionalProperties:\n#   type: integer\n# properties: {}\n# @schema\nextra:\n  a: 1\n",
+		},
+		{
+			name:     "definitions and refs",
+			values:   "port: 80\n",
+			existing: `{"additionalProperties": false, "definitions": {"port": {"type": "integer", "maximum": 65535}}, "properties": {"port": {"$ref": "#/definitions/port"}}}`,
+			want:     "# @schema.root\n# definitions:\n#   port:\n#     type: integer\n#     maximum: 65535\n# @schema.root\n# @schema\n# $ref: '#/definitions/port'\n# @schema\nport: 80\n",
+		},
+		{
+			name:     "annotated keys are left unchanged",
+			values:   "# @schema\n# type: string\n# @schema\nname: x\n",
+			existing: `{"additionalProperties": false, "properties": {"name": {"type": "string", "minLength": 1}}}`,
+			want:     "# @schema\n# type: string\n# @schema\nname: x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var existing Schema
+			assert.NoError(t, json.Unmarshal([]byte(tt.existing), &existing))
+
+			got, err := AnnotateFromSchema([]byte(tt.values), "values.yaml", &existing)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+// The schema generated from the annotated values must accept and reject the
+// same values as the existing schema
+func TestAnnotateFromSchema_Equivalent(t *testing.T) {
+	values := `# Number of replicas
+replicas: 1
+image:
+  repository: nginx
+  pullPolicy: IfNotPresent
+service:
+  port: 80
+hosts: []
+mode: fast
+`
+	existing := `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["image", "replicas"],
+  "definitions": {"port": {"type": "integer", "minimum": 1, "maximum": 65535}},
+  "properties": {
+    "replicas": {"type": "integer", "minimum": 0, "description": "Number of replicas"},
+    "image": {"type": "object", "required": ["repository"], "additionalProperties": false, "properties": {
+      "repository": {"type": "string"},
+      "pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent", "Never"]}
+    }},
+    "service": {"type": "object", "properties": {"port": {"$ref": "#/definitions/port"}, "type": {"type": "string"}}},
+    "hosts": {"type": "array", "items": {"type": "string", "format": "hostname"}},
+    "mode": {"oneOf": [{"const": "fast"}, {"const": "slow"}]}
+  }
+}`
+
+	var existingSchema Schema
+	assert.NoError(t, json.Unmarshal([]byte(existing), &existingSchema))
+	annotated, err := AnnotateFromSchema([]byte(values), "values.yaml", &existingSchema)
+	assert.NoError(t, err)
+
+	var doc yaml.Node
+	assert.NoError(t, yaml.Unmarshal(annotated, &doc))
+	generated, err := YamlToSchema("values.yaml", &doc, false, false, false, true, nil, nil)
+	assert.NoError(t, err)
+	generatedJSON, err := generated.ToJson()
+	assert.NoError(t, err)
+
+	compile := func(content []byte) *jsonschema.Schema {
+		parsed, err := jsonschema.UnmarshalJSON(bytes.NewReader(content))
+		assert.NoError(t, err)
+		c := jsonschema.NewCompiler()
+		assert.NoError(t, c.AddResource("schema.json", parsed))
+		compiled, err := c.Compile("schema.json")
+		assert.NoError(t, err)
+		return compiled
+	}
+	existingCompiled := compile([]byte(existing))
+	generatedCompiled := compile(generatedJSON)
+
+	instances := []string{
+		`{"replicas": 1, "image": {"repository": "nginx"}}`,
+		`{"replicas": -1, "image": {"repository": "nginx"}}`,
+		`{"image": {"repository": "nginx"}}`,
+		`{"replicas": 1, "image": {}}`,
+		`{"replicas": 1, "image": {"repository": "nginx", "pullPolicy": "Sometimes"}}`,
+		`{"replicas": 1, "image": {"repository": "nginx", "extra": true}}`,
+		`{"replicas": 1, "image": {"repository": "nginx"}, "service": {"port": 0}}`,
+		`{"replicas": 1, "image": {"repository": "nginx"}, "service": {"type": "ClusterIP", "other": 1}}`,
+		`{"replicas": 1, "image": {"repository": "nginx"}, "hosts": ["a", 1]}`,
+		`{"replicas": 1, "image": {"repository": "nginx"}, "mode": "slow"}`,
+		`{"replicas": 1, "image": {"repository": "nginx"}, "mode": "medium"}`,
+		`{"replicas": 1, "image": {"repository": "nginx"}, "unknown": 1}`,
+	}
+	for _, instance := range instances {
+		value, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(instance)))
+		assert.NoError(t, err)
+		existingErr := existingCompiled.Validate(value)
+		generatedErr := generatedCompiled.Validate(value)
+		assert.Equal(t, existingErr == nil, generatedErr == nil, "validation of %s differs: existing %v, generated %v", instance, existingErr, generatedErr)
+	}
+}
+
+func TestReadSchemaFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "values.schema.json")
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"properties": {"a": {"type": "string"}}}`), 0o644))
+	s, err := ReadSchemaFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, StringOrArrayOfString{"string"}, s.Properties["a"].Type)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{`), 0o644))
+	_, err = ReadSchemaFile(path)
+	assert.Error(t, err)
+
+	_, err = ReadSchemaFile(filepath.Join(tmpDir, "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -5,16 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"os"
 	"reflect"
 	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 
-	"github.com/dadav/go-jsonpointer"
-	"github.com/dadav/helm-schema/pkg/util"
 	"github.com/norwoodj/helm-docs/pkg/helm"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -308,6 +304,12 @@ type Schema struct {
 	PropertyNames        *Schema                `yaml:"propertyNames,omitempty"        json:"propertyNames,omitempty"`
 	Dependencies         map[string]interface{} `yaml:"dependencies,omitempty"         json:"dependencies,omitempty"`
 	constWasSet          bool                   `yaml:"-"                              json:"-"`
+	// Keywords of draft 2019-09 and 2020-12, see ToJsonForDraft
+	PrefixItems           []*Schema           `yaml:"prefixItems,omitempty"           json:"prefixItems,omitempty"`
+	UnevaluatedProperties SchemaOrBool        `yaml:"unevaluatedProperties,omitempty" json:"unevaluatedProperties,omitempty"`
+	UnevaluatedItems      SchemaOrBool        `yaml:"unevaluatedItems,omitempty"      json:"unevaluatedItems,omitempty"`
+	DependentRequired     map[string][]string `yaml:"dependentRequired,omitempty"     json:"dependentRequired,omitempty"`
+	DependentSchemas      map[string]*Schema  `yaml:"dependentSchemas,omitempty"      json:"dependentSchemas,omitempty"`
 }
 
 func NewSchema(schemaType string) *Schema {
@@ -335,6 +337,22 @@ func (s Schema) getJsonKeys() []string {
 	return result
 }
 
+// AnnotationKeywords returns the sorted keywords which can be used in @schema
+// annotations. Custom annotations (prefixed with "x-") are not included.
+func AnnotationKeywords() []string {
+	result := []string{}
+	t := reflect.TypeOf(Schema{})
+
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			result = append(result, name)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
 // UnmarshalYAML implements custom YAML unmarshaling for Schema objects.
 // It handles both standard schema fields and custom annotations (prefixed with "x-").
 // Custom annotations are stored in the CustomAnnotations map while standard fields
@@ -367,6 +385,23 @@ func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
 			alias.constWasSet = true
 		}
 
+		// Handle $defs (Draft 2019-09+) - merge into Definitions
+		if key == "$defs" {
+			var defs map[string]*Schema
+			if err := valueNode.Decode(&defs); err != nil {
+				return fmt.Errorf("failed to decode $defs: %w", err)
+			}
+			if alias.Definitions == nil {
+				alias.Definitions = make(map[string]*Schema)
+			}
+			for k, v := range defs {
+				if _, exists := alias.Definitions[k]; !exists {
+					alias.Definitions[k] = v
+				}
+			}
+			continue
+		}
+
 		if slices.Contains(knownKeys, key) {
 			continue
 		}
@@ -384,6 +419,10 @@ func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
 
 	// Copy alias to the main struct
 	*s = Schema(*alias)
+
+	// Rewrite $ref paths from $defs to definitions for Draft 7 compatibility
+	s.rewriteDefsRefs()
+
 	return nil
 }
 
@@ -518,6 +557,18 @@ func (s *Schema) collectAndHoistDefinitions(rootDefs map[string]*Schema) {
 		}
 	}
 
+	for _, schema := range s.PrefixItems {
+		schema.collectAndHoistDefinitions(rootDefs)
+		if schema.Definitions != nil {
+			for name, def := range schema.Definitions {
+				if _, exists := rootDefs[name]; !exists {
+					rootDefs[name] = def
+				}
+			}
+			schema.Definitions = nil
+		}
+	}
+
 	// Process composition schemas
 	for _, schema := range s.AllOf {
 		schema.collectAndHoistDefinitions(rootDefs)
@@ -598,6 +649,17 @@ func (s *Schema) collectAndHoistDefinitions(rootDefs map[string]*Schema) {
 			s.Not.Definitions = nil
 		}
 	}
+	for _, schema := range s.DependentSchemas {
+		schema.collectAndHoistDefinitions(rootDefs)
+		if schema.Definitions != nil {
+			for name, def := range schema.Definitions {
+				if _, exists := rootDefs[name]; !exists {
+					rootDefs[name] = def
+				}
+			}
+			schema.Definitions = nil
+		}
+	}
 
 	// Process AdditionalProperties when it's a schema
 	if s.AdditionalProperties != nil {
@@ -668,6 +730,12 @@ func (s *Schema) rewriteDefsRefs() {
 	for _, schema := range s.OneOf {
 		schema.rewriteDefsRefs()
 	}
+	for _, schema := range s.PrefixItems {
+		schema.rewriteDefsRefs()
+	}
+	for _, schema := range s.DependentSchemas {
+		schema.rewriteDefsRefs()
+	}
 
 	// Handle AdditionalProperties and AdditionalItems when they are schemas
 	if s.AdditionalProperties != nil {
@@ -690,6 +758,119 @@ func (s *Schema) rewriteDefsRefs() {
 	}
 }
 
+// Namespaced returns a copy of the schema whose root definitions are renamed
+// to "<namespace>.<name>" and whose refs to them are rewritten accordingly, so
+// the copy can be merged into a schema with the definitions given in taken.
+// The namespace gets a numeric suffix if definitions of taken already use it.
+func (s *Schema) Namespaced(namespace string, taken map[string]*Schema) *Schema {
+	prefix := namespace
+	for i := 2; namespaceTaken(taken, prefix); i++ {
+		prefix = fmt.Sprintf("%s_%d", namespace, i)
+	}
+
+	namespaced := s.clone()
+	if len(namespaced.Definitions) == 0 {
+		return namespaced
+	}
+	definitions := make(map[string]*Schema, len(namespaced.Definitions))
+	for name, definition := range namespaced.Definitions {
+		definitions[prefix+"."+name] = definition
+	}
+	namespaced.rewriteDefinitionRefs(s.Definitions, prefix)
+	namespaced.Definitions = definitions
+	return namespaced
+}
+
+func namespaceTaken(definitions map[string]*Schema, namespace string) bool {
+	for name := range definitions {
+		if strings.HasPrefix(name, namespace+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteDefinitionRefs prefixes the refs to the given root definitions
+func (s *Schema) rewriteDefinitionRefs(definitions map[string]*Schema, prefix string) {
+	if rest, ok := strings.CutPrefix(s.Ref, "#/definitions/"); ok {
+		token, remainder, found := strings.Cut(rest, "/")
+		name := pointerTokenUnescaper.Replace(token)
+		if _, ok := definitions[name]; ok {
+			s.Ref = "#/definitions/" + pointerTokenEscaper.Replace(prefix+"."+name)
+			if found {
+				s.Ref += "/" + remainder
+			}
+		}
+	}
+	for _, subSchema := range s.Subschemas() {
+		subSchema.rewriteDefinitionRefs(definitions, prefix)
+	}
+}
+
+// clone returns a copy of the schema with copies of all subschemas, so refs
+// and keywords of the copy can be changed without affecting the original
+func (s *Schema) clone() *Schema {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.Properties = cloneSchemaMap(s.Properties)
+	c.PatternProperties = cloneSchemaMap(s.PatternProperties)
+	c.Definitions = cloneSchemaMap(s.Definitions)
+	c.DependentSchemas = cloneSchemaMap(s.DependentSchemas)
+	c.PrefixItems = cloneSchemaSlice(s.PrefixItems)
+	c.AllOf = cloneSchemaSlice(s.AllOf)
+	c.AnyOf = cloneSchemaSlice(s.AnyOf)
+	c.OneOf = cloneSchemaSlice(s.OneOf)
+	c.Items = s.Items.clone()
+	c.Contains = s.Contains.clone()
+	c.PropertyNames = s.PropertyNames.clone()
+	c.If = s.If.clone()
+	c.Then = s.Then.clone()
+	c.Else = s.Else.clone()
+	c.Not = s.Not.clone()
+	c.AdditionalProperties = cloneSchemaOrBool(s.AdditionalProperties)
+	c.AdditionalItems = cloneSchemaOrBool(s.AdditionalItems)
+	c.UnevaluatedProperties = cloneSchemaOrBool(s.UnevaluatedProperties)
+	c.UnevaluatedItems = cloneSchemaOrBool(s.UnevaluatedItems)
+	return &c
+}
+
+func cloneSchemaMap(schemas map[string]*Schema) map[string]*Schema {
+	if schemas == nil {
+		return nil
+	}
+	result := make(map[string]*Schema, len(schemas))
+	for name, schema := range schemas {
+		result[name] = schema.clone()
+	}
+	return result
+}
+
+func cloneSchemaSlice(schemas []*Schema) []*Schema {
+	if schemas == nil {
+		return nil
+	}
+	result := make([]*Schema, len(schemas))
+	for i, schema := range schemas {
+		result[i] = schema.clone()
+	}
+	return result
+}
+
+func cloneSchemaOrBool(value SchemaOrBool) SchemaOrBool {
+	switch v := value.(type) {
+	case *Schema:
+		return v.clone()
+	case Schema:
+		return *v.clone()
+	case *bool:
+		b := *v
+		return &b
+	}
+	return value
+}
+
 // Set sets the HasData field to true
 func (s *Schema) Set() {
 	s.HasData = true
@@ -774,6 +955,13 @@ func (s *Schema) DisableRequiredProperties() {
 	for _, v := range s.Definitions {
 		v.DisableRequiredProperties()
 	}
+
+	for _, v := range s.PrefixItems {
+		v.DisableRequiredProperties()
+	}
+	for _, v := range s.DependentSchemas {
+		v.DisableRequiredProperties()
+	}
 }
 
 // GetPropertyAtPath navigates a dot-separated path and returns the schema at that location.
@@ -1026,6 +1214,10 @@ func (s Schema) validateArrayConstraints() error {
 		}
 	}
 
+	if len(s.PrefixItems) > 0 && !s.Type.IsEmpty() && !s.Type.Matches("array") {
+		return fmt.Errorf("prefixItems can only be used with array type, got %v", s.Type)
+	}
+
 	if s.MinItems != nil || s.MaxItems != nil {
 		if !s.Type.IsEmpty() && !s.Type.Matches("array") {
 			return fmt.Errorf("minItems/maxItems can only be used with array type, got %v", s.Type)
@@ -1204,8 +1396,8 @@ func (s Schema) validateObjectConstraints() error {
 }
 
 func (s Schema) validateNestedSchemas() error {
-	// Validate combinatorial schemas
-	for _, schemas := range [][]*Schema{s.AllOf, s.AnyOf, s.OneOf} {
+	// Validate combinatorial schemas and tuple items
+	for _, schemas := range [][]*Schema{s.AllOf, s.AnyOf, s.OneOf, s.PrefixItems} {
 		for _, schema := range schemas {
 			if err := schema.Validate(); err != nil {
 				return err
@@ -1231,6 +1423,14 @@ func (s Schema) validateNestedSchemas() error {
 		}
 	}
 
+	for name, depSchema := range s.DependentSchemas {
+		if depSchema != nil {
+			if err := depSchema.Validate(); err != nil {
+				return fmt.Errorf("invalid schema in dependentSchemas[%s]: %w", name, err)
+			}
+		}
+	}
+
 	// Validate nested properties
 	for name, propSchema := range s.Properties {
 		if propSchema != nil {
@@ -1417,7 +1617,7 @@ func FixRequiredProperties(schema *Schema) error {
 
 // applyRootSchemaProperties copies root-level schema properties from source to target.
 // Used for applying @schema.root annotations.
-func (s *Schema) applyRootSchemaProperties(source *Schema, valuesPath string) error {
+func (s *Schema) applyRootSchemaProperties(source *Schema, refs *fileRefResolver) error {
 	if source.Title != "" {
 		s.Title = source.Title
 	}
@@ -1425,7 +1625,7 @@ func (s *Schema) applyRootSchemaProperties(source *Schema, valuesPath string) er
 		s.Description = source.Description
 	}
 	if source.Ref != "" {
-		if err := handleSchemaRefs(source, valuesPath); err != nil {
+		if _, err := handleSchemaRefs(source, refs); err != nil {
 			return err
 		}
 		s.Ref = source.Ref
@@ -1587,6 +1787,40 @@ func YamlToSchema(
 	dontAddGlobal bool,
 	skipAutoGeneration *SkipAutoGenerationConfig,
 	parentRequiredProperties *[]string,
+) (*Schema, error) {
+	// The file refs of all annotations are counted first, so targets which
+	// are referenced by several keys are added to the definitions once
+	refs := newFileRefResolver(valuesPath)
+	refs.countAnnotations(node, keepFullComment)
+
+	schema, err := yamlToSchema(
+		refs,
+		node,
+		keepFullComment,
+		helmDocsCompatibilityMode,
+		dontRemoveHelmDocsPrefix,
+		dontAddGlobal,
+		skipAutoGeneration,
+		parentRequiredProperties,
+	)
+	if err != nil {
+		return nil, err
+	}
+	refs.addDefinitions(schema)
+	return schema, nil
+}
+
+// yamlToSchema generates the schema of the node like YamlToSchema, the file
+// refs are resolved by refs
+func yamlToSchema(
+	refs *fileRefResolver,
+	node *yaml.Node,
+	keepFullComment bool,
+	helmDocsCompatibilityMode bool,
+	dontRemoveHelmDocsPrefix bool,
+	dontAddGlobal bool,
+	skipAutoGeneration *SkipAutoGenerationConfig,
+	parentRequiredProperties *[]string,
 ) (*Schema, error) {
 	if skipAutoGeneration == nil {
 		skipAutoGeneration = &SkipAutoGenerationConfig{}
@@ -1606,7 +1840,7 @@ func YamlToSchema(
 			if docRootSchema, _, err := GetRootSchemaFromComment(node.HeadComment); err != nil {
 				return nil, fmt.Errorf("error parsing root schema from document comment: %w", err)
 			} else if docRootSchema.HasData {
-				if err := schema.applyRootSchemaProperties(&docRootSchema, valuesPath); err != nil {
+				if err := schema.applyRootSchemaProperties(&docRootSchema, refs); err != nil {
 					return nil, fmt.Errorf("error applying root schema from document comment: %w", err)
 				}
 				if err := docRootSchema.Validate(); err != nil {
@@ -1615,8 +1849,8 @@ func YamlToSchema(
 			}
 		}
 
-		childSchema, err := YamlToSchema(
-			valuesPath,
+		childSchema, err := yamlToSchema(
+			refs,
 			node.Content[0],
 			keepFullComment,
 			helmDocsCompatibilityMode,
@@ -1632,7 +1866,7 @@ func YamlToSchema(
 		schema.Properties = childSchema.Properties
 
 		// Apply root schema properties from child if they were set
-		if err := schema.applyRootSchemaProperties(childSchema, valuesPath); err != nil {
+		if err := schema.applyRootSchemaProperties(childSchema, refs); err != nil {
 			return nil, fmt.Errorf("error applying root schema properties from child: %w", err)
 		}
 
@@ -1652,8 +1886,9 @@ func YamlToSchema(
 			}
 		}
 
-		// always disable on top level (unless root schema specifies otherwise)
-		if !skipAutoGeneration.AdditionalProperties && schema.AdditionalProperties == nil {
+		// always disable on top level (unless root schema specifies otherwise),
+		// unevaluatedProperties replace additionalProperties
+		if !skipAutoGeneration.AdditionalProperties && schema.AdditionalProperties == nil && schema.UnevaluatedProperties == nil {
 			schema.AdditionalProperties = new(bool)
 		}
 	case yaml.MappingNode:
@@ -1664,15 +1899,15 @@ func YamlToSchema(
 			// Try to extract root schema annotations (adjacent to first key)
 			rootSchema, remainingComment, err := GetRootSchemaFromComment(firstKeyNode.HeadComment)
 			if err != nil {
-				return nil, fmt.Errorf("error parsing root schema comment: %w", err)
+				return nil, &LineError{Line: firstKeyNode.Line, Err: fmt.Errorf("error parsing root schema comment: %w", err)}
 			}
 
 			if rootSchema.HasData {
-				if err := schema.applyRootSchemaProperties(&rootSchema, valuesPath); err != nil {
-					return nil, fmt.Errorf("error applying root schema: %w", err)
+				if err := schema.applyRootSchemaProperties(&rootSchema, refs); err != nil {
+					return nil, &LineError{Line: firstKeyNode.Line, Err: fmt.Errorf("error applying root schema: %w", err)}
 				}
 				if err := rootSchema.Validate(); err != nil {
-					return nil, fmt.Errorf("error validating root schema: %w", err)
+					return nil, &LineError{Line: firstKeyNode.Line, Err: fmt.Errorf("error validating root schema: %w", err)}
 				}
 				// Update the first key's comment to exclude the root schema annotations
 				firstKeyNode.HeadComment = remainingComment
@@ -1694,7 +1929,7 @@ func YamlToSchema(
 
 			keyNodeSchema, description, err := GetSchemaFromComment(comment)
 			if err != nil {
-				return nil, fmt.Errorf("error parsing comment of key %s: %w", keyNode.Value, err)
+				return nil, &LineError{Line: keyNode.Line, Err: fmt.Errorf("error parsing comment of key %s: %w", keyNode.Value, err)}
 			}
 
 			if helmDocsCompatibilityMode {
@@ -1725,21 +1960,20 @@ func YamlToSchema(
 				description = helmDocsPrefixRemover.ReplaceAllString(description, "")
 			}
 
-			if keyNodeSchema.Ref != "" || len(keyNodeSchema.PatternProperties) > 0 {
-				// Handle $ref in main schema and pattern properties
-				if err := handleSchemaRefs(&keyNodeSchema, valuesPath); err != nil {
-					return nil, fmt.Errorf("error resolving $ref for key %s: %w", keyNode.Value, err)
-				}
+			// Handle $ref in the main schema and all its subschemas
+			referenced, err := handleSchemaRefs(&keyNodeSchema, refs)
+			if err != nil {
+				return nil, &LineError{Line: keyNode.Line, Err: fmt.Errorf("error resolving $ref for key %s: %w", keyNode.Value, err)}
 			}
 
 			if keyNodeSchema.ConstFromValue {
 				if keyNodeSchema.constWasSet {
-					return nil, fmt.Errorf("error validating schema of key %s: const and const-from-value cannot be used together", keyNode.Value)
+					return nil, &LineError{Line: keyNode.Line, Err: fmt.Errorf("error validating schema of key %s: const and const-from-value cannot be used together", keyNode.Value)}
 				}
 
 				decodedValue, err := decodeNodeValue(valueNode)
 				if err != nil {
-					return nil, fmt.Errorf("error decoding value for const-from-value on key %s: %w", keyNode.Value, err)
+					return nil, &LineError{Line: keyNode.Line, Err: fmt.Errorf("error decoding value for const-from-value on key %s: %w", keyNode.Value, err)}
 				}
 
 				keyNodeSchema.Const = decodedValue
@@ -1748,12 +1982,12 @@ func YamlToSchema(
 
 			if keyNodeSchema.HasData {
 				if err := keyNodeSchema.Validate(); err != nil {
-					return nil, fmt.Errorf("error validating schema of key %s: %w", keyNode.Value, err)
+					return nil, &LineError{Line: keyNode.Line, Err: fmt.Errorf("error validating schema of key %s: %w", keyNode.Value, err)}
 				}
 			} else if !skipAutoGeneration.Type {
 				nodeType, err := typeFromTag(valueNode.Tag)
 				if err != nil {
-					return nil, fmt.Errorf("error inferring type for key %s: %w", keyNode.Value, err)
+					return nil, &LineError{Line: keyNode.Line, Err: fmt.Errorf("error inferring type for key %s: %w", keyNode.Value, err)}
 				}
 				keyNodeSchema.Type = nodeType
 			}
@@ -1768,11 +2002,11 @@ func YamlToSchema(
 				}
 			}
 
-			// only validate or default if $ref is not set
-			if keyNodeSchema.Ref == "" {
+			// only validate or default if $ref is not set or was inlined
+			if !referenced {
 
 				if !skipAutoGeneration.AdditionalProperties && valueNode.Kind == yaml.MappingNode &&
-					(!keyNodeSchema.HasData || keyNodeSchema.AdditionalProperties == nil) {
+					(!keyNodeSchema.HasData || keyNodeSchema.AdditionalProperties == nil) && keyNodeSchema.UnevaluatedProperties == nil {
 					keyNodeSchema.AdditionalProperties = new(bool)
 				}
 
@@ -1795,8 +2029,8 @@ func YamlToSchema(
 				if valueNode.Kind == yaml.MappingNode && keyNodeSchema.Properties == nil {
 					keyNodeSchema.Properties = make(map[string]*Schema)
 
-					generatedSchema, err := YamlToSchema(
-						valuesPath,
+					generatedSchema, err := yamlToSchema(
+						refs,
 						valueNode,
 						keepFullComment,
 						helmDocsCompatibilityMode,
@@ -1819,7 +2053,7 @@ func YamlToSchema(
 						for pattern := range keyNodeSchema.PatternProperties {
 							matched, err := regexp.MatchString(pattern, propKeyNode.Value)
 							if err != nil {
-								return nil, fmt.Errorf("invalid pattern '%s' in patternProperties: %w", pattern, err)
+								return nil, &LineError{Line: propKeyNode.Line, Err: fmt.Errorf("invalid pattern '%s' in patternProperties: %w", pattern, err)}
 							}
 							if matched {
 								skipProperty = true
@@ -1834,7 +2068,7 @@ func YamlToSchema(
 							}
 						}
 					}
-				} else if valueNode.Kind == yaml.SequenceNode && keyNodeSchema.Items == nil {
+				} else if valueNode.Kind == yaml.SequenceNode && keyNodeSchema.Items == nil && len(keyNodeSchema.PrefixItems) == 0 {
 					// If the value is a sequence, but no items are predefined
 					seqSchema := NewSchema("")
 
@@ -1842,19 +2076,19 @@ func YamlToSchema(
 						if itemNode.Kind == yaml.ScalarNode {
 							itemNodeType, err := typeFromTag(itemNode.Tag)
 							if err != nil {
-								return nil, fmt.Errorf("error inferring type for array item: %w", err)
+								return nil, &LineError{Line: itemNode.Line, Err: fmt.Errorf("error inferring type for array item: %w", err)}
 							}
 							seqSchema.AnyOf = append(seqSchema.AnyOf, NewSchema(itemNodeType[0]))
 						} else {
 							itemRequiredProperties := []string{}
-							itemSchema, err := YamlToSchema(valuesPath, itemNode, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, dontAddGlobal, skipAutoGeneration, &itemRequiredProperties)
+							itemSchema, err := yamlToSchema(refs, itemNode, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, dontAddGlobal, skipAutoGeneration, &itemRequiredProperties)
 							if err != nil {
 								return nil, err
 							}
 
 							itemSchema.Required.Strings = append(itemSchema.Required.Strings, itemRequiredProperties...)
 
-							if !skipAutoGeneration.AdditionalProperties && itemNode.Kind == yaml.MappingNode && (!itemSchema.HasData || itemSchema.AdditionalProperties == nil) {
+							if !skipAutoGeneration.AdditionalProperties && itemNode.Kind == yaml.MappingNode && (!itemSchema.HasData || itemSchema.AdditionalProperties == nil) && itemSchema.UnevaluatedProperties == nil {
 								itemSchema.AdditionalProperties = new(bool)
 							}
 
@@ -1972,85 +2206,3 @@ func decodeNodeValue(node *yaml.Node) (interface{}, error) {
 
 	return value, nil
 }
-
-// handleSchemaRefs processes and resolves JSON Schema references ($ref) within a schema.
-// It handles both direct schema references and references within patternProperties.
-// For each reference:
-// - If it's a relative file path, it attempts to load and parse the referenced schema
-// - If it includes a JSON pointer (#/path/to/schema), it extracts the specific schema section
-// - The resolved schema replaces the original reference
-//
-// Parameters:
-//   - schema: Pointer to the Schema object containing the references to resolve
-//   - valuesPath: Path to the current values file, used for resolving relative paths
-//
-// Returns:
-//   - An error if the reference cannot be resolved, or nil on success
-func handleSchemaRefs(schema *Schema, valuesPath string) error {
-	// Handle main schema $ref
-	if schema.Ref != "" {
-		fileRef, jsonPointer, hasJSONPointer := strings.Cut(schema.Ref, "#")
-		if fileRef == "" {
-			return nil
-		}
-
-		relFilePath, err := util.IsRelativeFile(valuesPath, fileRef)
-		if err != nil {
-			// Not a relative file path, may be handled elsewhere
-			log.Debug(err)
-			return nil
-		}
-
-		var relSchema Schema
-		file, err := os.Open(relFilePath)
-		if err != nil {
-			return fmt.Errorf("failed to open referenced schema file %s: %w", relFilePath, err)
-		}
-		defer file.Close()
-
-		byteValue, err := io.ReadAll(file)
-		if err != nil {
-			return fmt.Errorf("failed to read referenced schema file %s: %w", relFilePath, err)
-		}
-
-		if hasJSONPointer && jsonPointer != "" {
-			// Found json-pointer
-			var obj interface{}
-			if err := json.Unmarshal(byteValue, &obj); err != nil {
-				return fmt.Errorf("failed to unmarshal JSON from %s: %w", relFilePath, err)
-			}
-			jsonPointerResultRaw, err := jsonpointer.Get(obj, jsonPointer)
-			if err != nil {
-				return fmt.Errorf("failed to resolve JSON pointer %s in %s: %w", jsonPointer, relFilePath, err)
-			}
-			jsonPointerResultMarshaled, err := json.Marshal(jsonPointerResultRaw)
-			if err != nil {
-				return fmt.Errorf("failed to marshal JSON pointer result from %s: %w", relFilePath, err)
-			}
-			if err := json.Unmarshal(jsonPointerResultMarshaled, &relSchema); err != nil {
-				return fmt.Errorf("failed to unmarshal JSON pointer result from %s: %w", relFilePath, err)
-			}
-		} else {
-			// No json-pointer
-			if err := json.Unmarshal(byteValue, &relSchema); err != nil {
-				return fmt.Errorf("failed to unmarshal schema from %s: %w", relFilePath, err)
-			}
-		}
-		*schema = relSchema
-		schema.HasData = true
-	}
-
-	// Handle $ref in pattern properties
-	if schema.PatternProperties != nil {
-		for pattern, subSchema := range schema.PatternProperties {
-			if subSchema.Ref != "" {
-				if err := handleSchemaRefs(subSchema, valuesPath); err != nil {
-					return fmt.Errorf("failed to resolve $ref in patternProperties[%s]: %w", pattern, err)
-				}
-				schema.PatternProperties[pattern] = subSchema // Update the original schema in the map
-			}
-		}
-	}
-
-	return nil
-}
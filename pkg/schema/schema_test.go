@@ -1421,6 +1421,35 @@ func TestHoistDefinitions(t *testing.T) {
 	}
 }
 
+func TestNamespaced(t *testing.T) {
+	s := &Schema{
+		Properties: map[string]*Schema{
+			"image":  {Ref: "#/definitions/image.json"},
+			"tag":    {Ref: "#/definitions/image.json/properties/tag"},
+			"labels": {Items: &Schema{Ref: "#/definitions/a~1b"}},
+			"other":  {Ref: "#/definitions/missing"},
+		},
+		Definitions: map[string]*Schema{
+			"image.json": {Properties: map[string]*Schema{"tag": {Type: StringOrArrayOfString{"string"}}}},
+			"a/b":        {AllOf: []*Schema{{Ref: "#/definitions/image.json"}}},
+		},
+	}
+
+	namespaced := s.Namespaced("child", map[string]*Schema{"child.x": {}, "child_2": {}})
+	assert.Equal(t, []string{"child_2.a/b", "child_2.image.json"}, sortedKeys(namespaced.Definitions))
+	assert.Equal(t, "#/definitions/child_2.image.json", namespaced.Properties["image"].Ref)
+	assert.Equal(t, "#/definitions/child_2.image.json/properties/tag", namespaced.Properties["tag"].Ref)
+	assert.Equal(t, "#/definitions/child_2.a~1b", namespaced.Properties["labels"].Items.Ref)
+	assert.Equal(t, "#/definitions/missing", namespaced.Properties["other"].Ref)
+	assert.Equal(t, "#/definitions/child_2.image.json", namespaced.Definitions["child_2.a/b"].AllOf[0].Ref)
+
+	// The schema itself is unchanged
+	assert.Equal(t, "#/definitions/image.json", s.Properties["image"].Ref)
+	assert.Equal(t, "#/definitions/image.json", s.Definitions["a/b"].AllOf[0].Ref)
+	namespaced.Properties["image"].Title = "changed"
+	assert.Equal(t, s.Properties["image"].Title, "")
+}
+
 // Fix 3: UnmarshalJSON must accept arrays/bools/null and reject other JSON.
 func TestBoolOrArrayOfStringUnmarshalJSON(t *testing.T) {
 	tests := []struct {
@@ -1474,3 +1503,15 @@ func TestYamlToSchemaNilSkipConfig(t *testing.T) {
 		t.Fatal("expected non-nil schema")
 	}
 }
+
+func TestAnnotationKeywords(t *testing.T) {
+	keywords := AnnotationKeywords()
+
+	for _, keyword := range []string{"type", "$ref", "const-from-value", "additionalProperties", "deprecated"} {
+		assert.Equal(t, slices.Contains(keywords, keyword), true, keyword)
+	}
+	for _, keyword := range []string{"-", "", "HasData", "CustomAnnotations"} {
+		assert.Equal(t, slices.Contains(keywords, keyword), false, keyword)
+	}
+	assert.Equal(t, slices.IsSorted(keywords), true)
+}
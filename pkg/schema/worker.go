@@ -13,8 +13,10 @@ import (
 )
 
 type Result struct {
-	ChartPath         string
-	ValuesPath        string
+	ChartPath  string
+	ValuesPath string
+	// OutFile is the path of the schema file relative to the chart directory
+	OutFile           string
 	Chart             *chart.ChartFile
 	Schema            Schema
 	Errors            []error
@@ -22,15 +24,18 @@ type Result struct {
 }
 
 func Worker(
-	dryRun, uncomment, addSchemaReference, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, dontAddGlobal, annotate bool,
+	dryRun, uncomment, addSchemaReference, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, dontAddGlobal, annotate, migrateHelmDocs bool,
 	valueFileNames []string,
 	skipAutoGenerationConfig *SkipAutoGenerationConfig,
+	annotateConfig *AnnotateConfig,
+	k8sCatalog *K8sCatalog,
+	remoteRefs *RemoteRefs,
 	outFile string,
 	queue <-chan string,
 	results chan<- Result,
 ) {
 	for chartPath := range queue {
-		result := Result{ChartPath: chartPath}
+		result := Result{ChartPath: chartPath, OutFile: outFile}
 
 		chartBasePath := filepath.Dir(chartPath)
 		file, err := os.Open(chartPath)
@@ -74,9 +79,19 @@ func Worker(
 		valuesPath = valuesPaths[0]
 		result.ValuesPath = valuesPath
 
-		// Annotate mode: write @schema annotations into values.yaml and skip schema generation
-		if annotate {
-			if err := AnnotateValuesFile(valuesPath, dryRun); err != nil {
+		// Annotate and migrate modes: write @schema annotations into values.yaml and skip schema generation
+		if annotate || migrateHelmDocs {
+			var rewrites []func([]byte) ([]byte, error)
+			if migrateHelmDocs {
+				rewrites = append(rewrites, MigrateHelmDocsContent)
+			}
+			if annotate {
+				if annotateConfig == nil {
+					annotateConfig = MinimalAnnotateConfig
+				}
+				rewrites = append(rewrites, AnnotateRewrite(valuesPath, annotateConfig))
+			}
+			if err := RewriteValuesFile(valuesPath, dryRun, rewrites...); err != nil {
 				result.Errors = append(result.Errors, err)
 			}
 			results <- result
@@ -158,6 +173,16 @@ func Worker(
 			results <- result
 			continue
 		}
+		if err := k8sCatalog.ResolveRefs(schema, chart.KubeVersion); err != nil {
+			result.Errors = append(result.Errors, err)
+			results <- result
+			continue
+		}
+		if err := remoteRefs.ResolveRefs(schema); err != nil {
+			result.Errors = append(result.Errors, err)
+			results <- result
+			continue
+		}
 		result.Schema = *schema
 
 		results <- result
@@ -131,8 +131,12 @@ key1: value1
 				tt.dontRemoveHelmDocsPrefix,
 				tt.dontAddGlobal,
 				false, // annotate
+				false, // migrateHelmDocs
 				tt.valueFileNames,
 				tt.skipAutoGenerationConfig,
+				nil, // annotateConfig
+				nil, // k8sCatalog
+				nil, // remoteRefs
 				tt.outFile,
 				queue,
 				results,
@@ -178,8 +182,12 @@ func TestWorker_DryRunDoesNotWriteSchemaReference(t *testing.T) {
 		false, // dontRemoveHelmDocsPrefix
 		false, // dontAddGlobal
 		false, // annotate
+		false, // migrateHelmDocs
 		[]string{"values.yaml"},
 		&SkipAutoGenerationConfig{},
+		nil, // annotateConfig
+		nil, // k8sCatalog
+		nil, // remoteRefs
 		"values.schema.json",
 		queue,
 		results,
@@ -239,8 +247,12 @@ replicas: "two"
 		false, // dontRemoveHelmDocsPrefix
 		false, // dontAddGlobal
 		false, // annotate
+		false, // migrateHelmDocs
 		[]string{"values.base.yaml", "values.prod.yaml"},
 		&SkipAutoGenerationConfig{},
+		nil, // annotateConfig
+		nil, // k8sCatalog
+		nil, // remoteRefs
 		"values.schema.json",
 		queue,
 		results,
@@ -273,3 +285,52 @@ replicas: "two"
 		}
 	}
 }
+
+func TestWorker_ResolvesK8sRefsByKubeVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	catalog, err := NewK8sCatalog(writeK8sCatalog(t), "")
+	assert.NoError(t, err)
+
+	chartPath := filepath.Join(tmpDir, "Chart.yaml")
+	err = os.WriteFile(chartPath, []byte("apiVersion: v2\nname: test-chart\nversion: 1.0.0\nkubeVersion: \"~1.28.0\"\n"), 0o644)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(tmpDir, "values.yaml"), []byte(`
+# @schema
+# $ref: k8s://io.k8s.api.core.v1.Toleration
+# @schema
+toleration: {}
+`), 0o644)
+	assert.NoError(t, err)
+
+	queue := make(chan string, 1)
+	results := make(chan Result, 1)
+	queue <- chartPath
+	close(queue)
+
+	Worker(
+		true,  // dryRun
+		false, // uncomment
+		false, // addSchemaReference
+		false, // keepFullComment
+		false, // helmDocsCompatibilityMode
+		false, // dontRemoveHelmDocsPrefix
+		false, // dontAddGlobal
+		false, // annotate
+		false, // migrateHelmDocs
+		[]string{"values.yaml"},
+		&SkipAutoGenerationConfig{},
+		nil, // annotateConfig
+		catalog,
+		nil, // remoteRefs
+		"values.schema.json",
+		queue,
+		results,
+	)
+
+	result := <-results
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, "#/definitions/io.k8s.api.core.v1.Toleration", result.Schema.Properties["toleration"].Ref)
+	if assert.Contains(t, result.Schema.Definitions, "io.k8s.api.core.v1.Toleration") {
+		assert.Equal(t, "1.28", result.Schema.Definitions["io.k8s.api.core.v1.Toleration"].Description)
+	}
+}
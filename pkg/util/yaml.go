@@ -0,0 +1,151 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YamlNodeToValue converts a yaml node into plain go values, the same way helm
+// sees them after converting the values to json: mappings become
+// map[string]any, sequences []any and timestamps stay strings.
+func YamlNodeToValue(node *yaml.Node) (any, error) {
+	if node == nil {
+		return nil, nil
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return YamlNodeToValue(node.Content[0])
+	case yaml.AliasNode:
+		return YamlNodeToValue(node.Alias)
+	case yaml.MappingNode:
+		result := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+
+			// Merge keys (<<: *anchor) inline the referenced mapping(s)
+			if keyNode.Tag == "!!merge" {
+				if err := mergeYamlValue(result, valueNode); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			value, err := YamlNodeToValue(valueNode)
+			if err != nil {
+				return nil, err
+			}
+			result[keyNode.Value] = value
+		}
+		return result, nil
+	case yaml.SequenceNode:
+		result := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := YamlNodeToValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+		return result, nil
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!timestamp" {
+			return node.Value, nil
+		}
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return nil, fmt.Errorf("failed to decode value at line %d: %w", node.Line, err)
+		}
+		return value, nil
+	}
+
+	return nil, fmt.Errorf("unsupported yaml node kind %d at line %d", node.Kind, node.Line)
+}
+
+func mergeYamlValue(target map[string]any, node *yaml.Node) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	var sources []*yaml.Node
+	if node.Kind == yaml.SequenceNode {
+		sources = node.Content
+	} else {
+		sources = []*yaml.Node{node}
+	}
+
+	for _, source := range sources {
+		value, err := YamlNodeToValue(source)
+		if err != nil {
+			return err
+		}
+		mapping, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("merge key at line %d does not reference a mapping", node.Line)
+		}
+		for k, v := range mapping {
+			// Keys defined explicitly in the mapping take precedence
+			if _, exists := target[k]; !exists {
+				target[k] = v
+			}
+		}
+	}
+	return nil
+}
+
+// FindYamlNode follows the given path (e.g. the tokens of a json pointer)
+// through the yaml node tree. For mapping entries the key node is returned,
+// so the reported position points to the key and not to its value. If the
+// path can't be followed completely, the deepest node found is returned.
+func FindYamlNode(root *yaml.Node, path []string) *yaml.Node {
+	if root == nil {
+		return nil
+	}
+
+	current := root
+	if current.Kind == yaml.DocumentNode {
+		if len(current.Content) == 0 {
+			return current
+		}
+		current = current.Content[0]
+	}
+	found := current
+
+	for _, token := range path {
+		if current.Kind == yaml.AliasNode && current.Alias != nil {
+			current = current.Alias
+		}
+
+		var next, position *yaml.Node
+		switch current.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(current.Content); i += 2 {
+				if current.Content[i].Value == token {
+					position = current.Content[i]
+					next = current.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(token)
+			if err == nil && index >= 0 && index < len(current.Content) {
+				position = current.Content[index]
+				next = current.Content[index]
+			}
+		}
+
+		if next == nil {
+			return found
+		}
+		found = position
+		current = next
+	}
+
+	return found
+}
@@ -0,0 +1,77 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYamlNodeToValue(t *testing.T) {
+	input := `
+base: &base
+  a: 1
+  b: two
+merged:
+  <<: *base
+  b: three
+date: 2024-01-02
+list: [1, 2.5, true, null]
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(input), &node); err != nil {
+		t.Fatalf("failed to parse yaml: %v", err)
+	}
+
+	value, err := YamlNodeToValue(&node)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	expected := map[string]any{
+		"base":   map[string]any{"a": 1, "b": "two"},
+		"merged": map[string]any{"a": 1, "b": "three"},
+		"date":   "2024-01-02",
+		"list":   []any{1, 2.5, true, nil},
+	}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("Was expecting %#v, but got %#v", expected, value)
+	}
+}
+
+func TestFindYamlNode(t *testing.T) {
+	input := `foo:
+  bar:
+    - name: a
+    - name: b
+baz: 1
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(input), &node); err != nil {
+		t.Fatalf("failed to parse yaml: %v", err)
+	}
+
+	tests := []struct {
+		path   []string
+		line   int
+		column int
+	}{
+		{path: nil, line: 1, column: 1},
+		{path: []string{"baz"}, line: 5, column: 1},
+		{path: []string{"foo", "bar"}, line: 2, column: 3},
+		{path: []string{"foo", "bar", "1", "name"}, line: 4, column: 7},
+		// missing keys resolve to the deepest existing node
+		{path: []string{"foo", "missing"}, line: 1, column: 1},
+		{path: []string{"foo", "bar", "5"}, line: 2, column: 3},
+	}
+	for _, test := range tests {
+		found := FindYamlNode(&node, test.path)
+		if found == nil {
+			t.Errorf("Was expecting a node for %v, but got nil", test.path)
+			continue
+		}
+		if found.Line != test.line || found.Column != test.column {
+			t.Errorf("Was expecting %v at %d:%d, but got %d:%d", test.path, test.line, test.column, found.Line, found.Column)
+		}
+	}
+}